@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/syou6162/git-sequential-stage/internal/executor"
+	"github.com/syou6162/git-sequential-stage/internal/stager"
+)
+
+// runDryRunStage stages the hunks into a temporary copy of the index (GIT_INDEX_FILE)
+// and returns the resulting staged diff. The real index and the working tree are left untouched.
+func runDryRunStage(ctx context.Context, opts stager.Options, selection stager.Selection, patchFile string) (*stager.StageResult, string, error) {
+	realExec := executor.NewRealCommandExecutor()
+
+	tmpDir, err := os.MkdirTemp("", "git-sequential-stage-dry-run-*")
+	if err != nil {
+		return nil, "", stager.NewIOError("create temporary directory", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	tmpIndex := filepath.Join(tmpDir, "index")
+	if err := copyIndex(ctx, realExec, tmpIndex); err != nil {
+		return nil, "", err
+	}
+
+	exec := realExec.WithEnv("GIT_INDEX_FILE=" + tmpIndex)
+	opts.DryRun = true
+	result, err := stageWithExecutor(ctx, exec, opts, selection, patchFile)
+	if err != nil {
+		return result, "", err
+	}
+
+	stagedDiff, err := exec.Execute(ctx, "git", "diff", "--cached")
+	if err != nil {
+		return result, "", stager.NewGitCommandError("git diff --cached", err)
+	}
+
+	return result, string(stagedDiff), nil
+}
+
+// copyIndex copies the current index file to dst.
+// If the repository has no index yet, dst is not created and git treats it as empty.
+func copyIndex(ctx context.Context, exec executor.CommandExecutor, dst string) error {
+	output, err := exec.Execute(ctx, "git", "rev-parse", "--git-path", "index")
+	if err != nil {
+		return stager.NewGitCommandError("git rev-parse --git-path index", err)
+	}
+
+	content, err := os.ReadFile(strings.TrimSpace(string(output)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return stager.NewIOError("read index", err)
+	}
+
+	if err := os.WriteFile(dst, content, 0644); err != nil {
+		return stager.NewIOError("write temporary index", err)
+	}
+	return nil
+}
+
+// printDryRunResult prints the staged diff that a dry run produced
+func printDryRunResult(stagedDiff string) {
+	if stagedDiff == "" {
+		fmt.Printf("Dry run: nothing would be staged\n")
+		return
+	}
+	fmt.Printf("Dry run: the staging area would contain the following changes (index not modified)\n\n")
+	fmt.Print(stagedDiff)
+}
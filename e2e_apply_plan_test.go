@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/testutils"
+)
+
+// TestE2E_ApplyPlan_YAML tests that a YAML plan creates one commit per group with the planned hunks
+func TestE2E_ApplyPlan_YAML(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "apply-plan-yaml-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	initial := "line1\nline2\nline3\nline4\nline5\nline6\nline7\nline8\nline9\nline10\nline11\nline12\n"
+	testRepo.CreateFile("file.txt", initial)
+	testRepo.CreateFile("other.txt", "other\n")
+	testRepo.CommitChanges("Initial commit")
+
+	modified := "line1\nLINE2\nline3\nline4\nline5\nline6\nline7\nline8\nline9\nline10\nline11\nLINE12\n"
+	testRepo.ModifyFile("file.txt", modified)
+	testRepo.ModifyFile("other.txt", "other\nmore\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	plan := `patch: changes.patch
+commits:
+  - message: "fix: Update line 12"
+    hunks: ["file.txt:2"]
+  - message: "fix: Update line 2 and other"
+    hunks: ["file.txt:1", "other.txt:*"]
+`
+	if err := testRepo.WriteFile("plan.yaml", plan); err != nil {
+		t.Fatalf("Failed to write plan: %v", err)
+	}
+
+	initialCount := testRepo.GetCommitCount()
+	output, err := testutils.CaptureStdout(t, func() error {
+		return runApplyPlanCommand(context.Background(), []string{"-plan", "plan.yaml"})
+	})
+	if err != nil {
+		t.Fatalf("apply-plan failed: %v", err)
+	}
+
+	if got := testRepo.GetCommitCount() - initialCount; got != 2 {
+		t.Fatalf("Expected 2 new commits, got %d", got)
+	}
+	if !strings.Contains(output, "[1/2] Committed: fix: Update line 12") {
+		t.Errorf("Expected progress for first commit, got:\n%s", output)
+	}
+
+	firstCommit := testRepo.RunCommandOrFail("git", "show", "HEAD~1")
+	testutils.AssertDiffContains(t, firstCommit, "fix: Update line 12", "+LINE12")
+	testutils.AssertDiffNotContains(t, firstCommit, "+LINE2\n", "+more")
+
+	secondCommit := testRepo.RunCommandOrFail("git", "show", "HEAD")
+	testutils.AssertDiffContains(t, secondCommit, "fix: Update line 2 and other", "+LINE2", "+more")
+	testutils.AssertDiffNotContains(t, secondCommit, "+LINE12")
+
+	// Everything in the patch has been committed
+	if diff := testRepo.RunCommandOrFail("git", "diff", "HEAD", "--", "file.txt", "other.txt"); diff != "" {
+		t.Errorf("Expected no remaining changes, got:\n%s", diff)
+	}
+}
+
+// TestE2E_ApplyPlan_JSON tests a JSON plan with the patch file given by flag
+func TestE2E_ApplyPlan_JSON(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "apply-plan-json-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateFile("a.txt", "a\n")
+	testRepo.CreateFile("b.txt", "b\n")
+	testRepo.CommitChanges("Initial commit")
+
+	testRepo.ModifyFile("a.txt", "a\nA\n")
+	testRepo.ModifyFile("b.txt", "b\nB\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	plan := `{"commits": [
+  {"message": "feat: Update b", "hunks": ["b.txt:1"]},
+  {"message": "feat: Update a", "hunks": ["a.txt:1"]}
+]}`
+	if err := testRepo.WriteFile("plan.json", plan); err != nil {
+		t.Fatalf("Failed to write plan: %v", err)
+	}
+
+	initialCount := testRepo.GetCommitCount()
+	_, err := testutils.CaptureStdout(t, func() error {
+		return runApplyPlanCommand(context.Background(), []string{"-plan", "plan.json", "-patch", "changes.patch"})
+	})
+	if err != nil {
+		t.Fatalf("apply-plan failed: %v", err)
+	}
+
+	if got := testRepo.GetCommitCount() - initialCount; got != 2 {
+		t.Fatalf("Expected 2 new commits, got %d", got)
+	}
+
+	files := testRepo.RunCommandOrFail("git", "show", "--name-only", "--format=%s", "HEAD~1")
+	if !strings.Contains(files, "feat: Update b") || !strings.Contains(files, "b.txt") || strings.Contains(files, "a.txt") {
+		t.Errorf("Unexpected first commit:\n%s", files)
+	}
+}
+
+// TestE2E_ApplyPlan_InvalidPlanCreatesNoCommits tests that an invalid later group is rejected before any commit is made
+func TestE2E_ApplyPlan_InvalidPlanCreatesNoCommits(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "apply-plan-invalid-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateFile("a.txt", "a\n")
+	testRepo.CommitChanges("Initial commit")
+	testRepo.ModifyFile("a.txt", "a\nA\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	plan := &commitPlan{
+		Patch: "changes.patch",
+		Commits: []plannedCommit{
+			{Message: "first", Hunks: []string{"a.txt:1"}},
+			{Message: "second", Hunks: []string{"a.txt:1,*"}},
+		},
+	}
+
+	initialCount := testRepo.GetCommitCount()
+	err := runApplyPlan(context.Background(), plan)
+	if err == nil {
+		t.Fatal("Expected error for invalid hunk specification")
+	}
+	if !strings.Contains(err.Error(), "commit 2") {
+		t.Errorf("Expected error to name the failing commit, got: %v", err)
+	}
+	if got := testRepo.GetCommitCount(); got != initialCount {
+		t.Errorf("Expected no new commits, got %d", got-initialCount)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/internal/stager"
+	"github.com/syou6162/git-sequential-stage/testutils"
+)
+
+// TestE2E_Base_StageAgainstBase tests that hunk numbers of a patch against --base are honored
+func TestE2E_Base_StageAgainstBase(t *testing.T) {
+	testRepo, lines := testutils.NewMultiHunkRepo(t, "base-stage-*", 40, map[int]string{1: "FIXUP\n"}, nil)
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CommitChanges("Fixup base")
+	lines[29] = "WORK\n"
+	testRepo.ModifyFile("file.txt", lines.String())
+	basePatch := filepath.Join(t.TempDir(), "base.patch")
+	if err := os.WriteFile(basePatch, []byte(testRepo.RunCommandOrFail("git", "diff", "HEAD~1")), 0o644); err != nil {
+		t.Fatalf("Failed to write patch: %v", err)
+	}
+
+	// Hunk 2 of "git diff HEAD~1" is hunk 1 of "git diff HEAD"
+	opts := stager.Options{Base: "HEAD~1"}
+	if _, err := runStageSelectionWithOptions(context.Background(), opts, stager.Selection{HunkSpecs: []string{"file.txt:2"}}, basePatch); err != nil {
+		t.Fatalf("Failed to stage against the base: %v", err)
+	}
+
+	staged := testRepo.RunCommandOrFail("git", "diff", "--cached")
+	testutils.AssertDiffContains(t, staged, "+WORK")
+}
+
+// TestE2E_Base_HunkCommittedSinceBase tests that a hunk of "git diff <base>" that was
+// committed between the base and HEAD is rejected with a clear error, since the staging area
+// starts at HEAD, and that the other hunk is staged and verified against HEAD
+func TestE2E_Base_HunkCommittedSinceBase(t *testing.T) {
+	testRepo, lines := testutils.NewMultiHunkRepo(t, "base-committed-*", 40, map[int]string{1: "FIXUP\n"}, nil)
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CommitChanges("Fixup base")
+	lines[29] = "WORK\n"
+	testRepo.ModifyFile("file.txt", lines.String())
+	basePatch := filepath.Join(t.TempDir(), "base.patch")
+	if err := os.WriteFile(basePatch, []byte(testRepo.RunCommandOrFail("git", "diff", "HEAD~1")), 0o644); err != nil {
+		t.Fatalf("Failed to write patch: %v", err)
+	}
+
+	// Hunk 1 of "git diff HEAD~1" is the FIXUP line committed in HEAD
+	opts := stager.Options{Base: "HEAD~1", Verify: true}
+	_, err := runStageSelectionWithOptions(context.Background(), opts, stager.Selection{HunkSpecs: []string{"file.txt:1,2"}}, basePatch)
+	if !errors.Is(err, &stager.StagerError{Type: stager.ErrorTypeBaseMismatch}) {
+		t.Fatalf("Expected a BaseMismatch error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "committed between base HEAD~1 and HEAD") || !strings.HasSuffix(err.Error(), ": file.txt:1") {
+		t.Errorf("Expected the error to name file.txt:1 only, got %v", err)
+	}
+	if staged := testRepo.RunCommandOrFail("git", "diff", "--cached"); staged != "" {
+		t.Errorf("Expected nothing to be staged, got:\n%s", staged)
+	}
+
+	if _, err := runStageSelectionWithOptions(context.Background(), opts, stager.Selection{HunkSpecs: []string{"file.txt:2"}}, basePatch); err != nil {
+		t.Fatalf("Failed to stage and verify the uncommitted hunk: %v", err)
+	}
+	staged := testRepo.RunCommandOrFail("git", "diff", "--cached")
+	testutils.AssertDiffContains(t, staged, "+WORK")
+	testutils.AssertDiffNotContains(t, staged, "+FIXUP")
+}
+
+// TestE2E_Base_PatchMismatch tests that a patch not generated against --base is rejected
+func TestE2E_Base_PatchMismatch(t *testing.T) {
+	testRepo, lines := testutils.NewMultiHunkRepo(t, "base-mismatch-*", 40, map[int]string{1: "FIXUP\n"}, nil)
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CommitChanges("Fixup base")
+	lines[29] = "WORK\n"
+	testRepo.ModifyFile("file.txt", lines.String())
+	headPatch := filepath.Join(t.TempDir(), "head.patch")
+	if err := os.WriteFile(headPatch, []byte(testRepo.RunCommandOrFail("git", "diff", "HEAD")), 0o644); err != nil {
+		t.Fatalf("Failed to write patch: %v", err)
+	}
+
+	opts := stager.Options{Base: "HEAD~1"}
+	_, err := runStageSelectionWithOptions(context.Background(), opts, stager.Selection{HunkSpecs: []string{"file.txt:1"}}, headPatch)
+	if !errors.Is(err, &stager.StagerError{Type: stager.ErrorTypeBaseMismatch}) {
+		t.Fatalf("Expected a BaseMismatch error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "file.txt") {
+		t.Errorf("Expected the error to name file.txt, got %v", err)
+	}
+	if staged := testRepo.RunCommandOrFail("git", "diff", "--cached"); staged != "" {
+		t.Errorf("Expected nothing to be staged, got:\n%s", staged)
+	}
+}
+
+// TestE2E_Base_InvalidRevision tests that an unknown base revision is rejected
+func TestE2E_Base_InvalidRevision(t *testing.T) {
+	testRepo, lines := testutils.NewMultiHunkRepo(t, "base-invalid-*", 40, map[int]string{1: "FIXUP\n"}, nil)
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CommitChanges("Fixup base")
+	lines[29] = "WORK\n"
+	testRepo.ModifyFile("file.txt", lines.String())
+	basePatch := filepath.Join(t.TempDir(), "base.patch")
+	if err := os.WriteFile(basePatch, []byte(testRepo.RunCommandOrFail("git", "diff", "HEAD~1")), 0o644); err != nil {
+		t.Fatalf("Failed to write patch: %v", err)
+	}
+
+	for _, base := range []string{"no-such-branch", "--output=x"} {
+		opts := stager.Options{Base: base}
+		_, err := runStageSelectionWithOptions(context.Background(), opts, stager.Selection{HunkSpecs: []string{"file.txt:2"}}, basePatch)
+		if !errors.Is(err, &stager.StagerError{Type: stager.ErrorTypeInvalidArgument}) {
+			t.Errorf("%s: expected an InvalidArgument error, got %v", base, err)
+		}
+	}
+}
+
+// TestE2E_Base_CountHunks tests that count-hunks --base counts against the given revision
+func TestE2E_Base_CountHunks(t *testing.T) {
+	testRepo, lines := testutils.NewMultiHunkRepo(t, "base-count-hunks-*", 40, map[int]string{1: "FIXUP\n"}, nil)
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CommitChanges("Fixup base")
+	lines[29] = "WORK\n"
+	testRepo.ModifyFile("file.txt", lines.String())
+
+	output, err := testutils.CaptureStdout(t, func() error {
+		return runCountHunksCommand(context.Background(), []string{"--base=HEAD~1"})
+	})
+	if err != nil {
+		t.Fatalf("count-hunks failed: %v", err)
+	}
+	if strings.TrimSpace(output) != "file.txt: 2" {
+		t.Errorf("Expected 2 hunks against HEAD~1, got %q", output)
+	}
+
+	output, err = testutils.CaptureStdout(t, func() error {
+		return runCountHunksCommand(context.Background(), []string{})
+	})
+	if err != nil {
+		t.Fatalf("count-hunks failed: %v", err)
+	}
+	if strings.TrimSpace(output) != "file.txt: 1" {
+		t.Errorf("Expected 1 hunk against HEAD, got %q", output)
+	}
+}
+
+// TestE2E_Base_RejectedBeforeGitCalls tests that a base looking like an option never reaches git
+func TestE2E_Base_RejectedBeforeGitCalls(t *testing.T) {
+	testRepo, lines := testutils.NewMultiHunkRepo(t, "base-injection-*", 40, map[int]string{1: "FIXUP\n"}, nil)
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CommitChanges("Fixup base")
+	lines[29] = "WORK\n"
+	testRepo.ModifyFile("file.txt", lines.String())
+
+	injected := filepath.Join(t.TempDir(), "injected.txt")
+	commands := map[string]func() error{
+		"stage": func() error {
+			return runStageCommand(context.Background(), []string{"--from-worktree", "--base=--output=" + injected, "-hunk=file.txt:1"})
+		},
+		"count-hunks": func() error {
+			return runCountHunksCommand(context.Background(), []string{"--base=--output=" + injected})
+		},
+	}
+
+	for name, run := range commands {
+		err := run()
+		if !errors.Is(err, &stager.StagerError{Type: stager.ErrorTypeInvalidArgument}) {
+			t.Errorf("%s: expected an InvalidArgument error, got %v", name, err)
+		}
+		if _, statErr := os.Stat(injected); !os.IsNotExist(statErr) {
+			t.Errorf("%s: expected git not to write %s, got %v", name, injected, statErr)
+		}
+	}
+}
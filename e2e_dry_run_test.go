@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/testutils"
+)
+
+// TestE2E_Stage_DryRun tests that --dry-run prints the resulting staged diff without touching the index
+func TestE2E_Stage_DryRun(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "stage-dry-run-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	initial := "line1\nline2\nline3\nline4\nline5\nline6\nline7\nline8\nline9\nline10\nline11\nline12\n"
+	testRepo.CreateFile("file.txt", initial)
+	testRepo.CreateFile("other.txt", "other\n")
+	testRepo.CommitChanges("Initial commit")
+
+	testRepo.ModifyFile("file.txt", "line1\nLINE2\nline3\nline4\nline5\nline6\nline7\nline8\nline9\nline10\nline11\nLINE12\n")
+	testRepo.ModifyFile("other.txt", "changed\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	output, err := testutils.CaptureStdout(t, func() error {
+		return runStageCommand(context.Background(), []string{
+			"-patch", "changes.patch", "-hunk", "file.txt:2", "-hunk", "other.txt:*", "--dry-run",
+		})
+	})
+	if err != nil {
+		t.Fatalf("stage --dry-run failed: %v", err)
+	}
+
+	if !strings.HasPrefix(output, "Dry run:") {
+		t.Errorf("Expected dry-run header, got:\n%s", output)
+	}
+	testutils.AssertDiffContains(t, output, "+LINE12", "+changed")
+	testutils.AssertDiffNotContains(t, output, "+LINE2\n")
+
+	// The real index is untouched
+	if staged := testRepo.RunCommandOrFail("git", "diff", "--cached"); staged != "" {
+		t.Errorf("Expected empty staging area after dry run, got:\n%s", staged)
+	}
+}
+
+// TestE2E_Stage_DryRunJSON tests that --dry-run with --format=json includes the staged diff
+func TestE2E_Stage_DryRunJSON(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "stage-dry-run-json-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateFile("file.txt", "a\n")
+	testRepo.CommitChanges("Initial commit")
+	testRepo.ModifyFile("file.txt", "a\nb\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	output, err := testutils.CaptureStdout(t, func() error {
+		return runStageCommand(context.Background(), []string{
+			"-patch", "changes.patch", "-hunk", "file.txt:1", "--dry-run", "--format=json",
+		})
+	})
+	if err != nil {
+		t.Fatalf("stage --dry-run failed: %v", err)
+	}
+
+	var result stageOutput
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, output)
+	}
+	if !result.Success || !result.DryRun {
+		t.Errorf("Expected successful dry run, got %+v", result)
+	}
+	if len(result.Hunks) != 1 || result.Hunks[0].Status != "applied" {
+		t.Errorf("Expected hunk to be reported as applied, got %+v", result.Hunks)
+	}
+	testutils.AssertDiffContains(t, result.StagedDiff, "+b")
+
+	if staged := testRepo.RunCommandOrFail("git", "diff", "--cached"); staged != "" {
+		t.Errorf("Expected empty staging area after dry run, got:\n%s", staged)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/internal/stager"
+	"github.com/syou6162/git-sequential-stage/testutils"
+)
+
+// TestE2E_Stage_NegatedSpec tests that "file:!N" stages every hunk of the file except hunk N
+func TestE2E_Stage_NegatedSpec(t *testing.T) {
+	testRepo, _ := testutils.NewMultiHunkRepo(t, "stage-negated-*", 30, map[int]string{1: "FIRST\n", 14: "DEBUG\n", 28: "THIRD\n"},
+		map[string]string{"image.png": string(testutils.TestData.MinimalPNGTransparent), "other.txt": "other\n"})
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateBinaryFile("image.png", testutils.TestData.MinimalPNGRed)
+	testRepo.ModifyFile("other.txt", "changed\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	if err := runGitSequentialStage(context.Background(), []string{"file.txt:!2"}, "changes.patch"); err != nil {
+		t.Fatalf("stage file.txt:!2 failed: %v", err)
+	}
+
+	staged := testRepo.RunCommandOrFail("git", "diff", "--cached")
+	testutils.AssertDiffContains(t, staged, "+FIRST", "+THIRD")
+	testutils.AssertDiffNotContains(t, staged, "+DEBUG", "image.png", "other.txt")
+}
+
+// TestE2E_Stage_ExcludeEverythingElse tests that -exclude without -hunk stages everything else
+// in the patch, with binary files staged as a whole
+func TestE2E_Stage_ExcludeEverythingElse(t *testing.T) {
+	testRepo, _ := testutils.NewMultiHunkRepo(t, "stage-exclude-*", 30, map[int]string{1: "FIRST\n", 14: "DEBUG\n", 28: "THIRD\n"},
+		map[string]string{"image.png": string(testutils.TestData.MinimalPNGTransparent), "other.txt": "other\n"})
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateBinaryFile("image.png", testutils.TestData.MinimalPNGRed)
+	testRepo.ModifyFile("other.txt", "changed\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	_, err := runStageSelection(context.Background(), stager.Selection{Excludes: []string{"file.txt:2"}}, "changes.patch")
+	if err != nil {
+		t.Fatalf("stage -exclude failed: %v", err)
+	}
+
+	staged := testRepo.RunCommandOrFail("git", "diff", "--cached", "--stat")
+	for _, want := range []string{"file.txt", "image.png", "other.txt"} {
+		if !strings.Contains(staged, want) {
+			t.Errorf("Expected %s to be staged, got:\n%s", want, staged)
+		}
+	}
+	if unstaged := testRepo.RunCommandOrFail("git", "diff"); !strings.Contains(unstaged, "+DEBUG") || strings.Contains(unstaged, "+FIRST") {
+		t.Errorf("Expected only the excluded hunk to remain unstaged, got:\n%s", unstaged)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/testutils"
+)
+
+// TestE2E_Stage_GlobWildcard tests that a glob wildcard stages every matching file in the patch,
+// including deleted and renamed files
+func TestE2E_Stage_GlobWildcard(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "stage-glob-wildcard-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateFile("src/a.go", "package src\n")
+	testRepo.CreateFile("src/gone.go", "package src\n\nfunc gone() {}\n")
+	testRepo.CreateFile("src/old.go", "package src\n\nfunc moved() {}\n")
+	testRepo.CreateFile("README.md", "# title\n")
+	testRepo.CommitChanges("Initial commit")
+
+	testRepo.ModifyFile("src/a.go", "package src\n\nfunc a() {}\n")
+	testRepo.RunCommandOrFail("git", "rm", "-q", "src/gone.go")
+	testRepo.RunCommandOrFail("git", "mv", "src/old.go", "src/new.go")
+	testRepo.RunCommandOrFail("git", "reset", "-q")
+	testRepo.RunCommandOrFail("git", "add", "-N", "src/new.go")
+	testRepo.ModifyFile("README.md", "# title\n\ntext\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	if err := runGitSequentialStage(context.Background(), []string{"src/*.go:*"}, "changes.patch"); err != nil {
+		t.Fatalf("stage src/*.go:* failed: %v", err)
+	}
+
+	status := testRepo.RunCommandOrFail("git", "status", "--porcelain")
+	for _, want := range []string{"M  src/a.go", "D  src/gone.go", "R  src/old.go -> src/new.go", " M README.md"} {
+		if !strings.Contains(status, want) {
+			t.Errorf("Expected %q in status, got:\n%s", want, status)
+		}
+	}
+}
+
+// TestE2E_Stage_DirectoryHunk tests that "dir/:N" stages hunk N of every file below the directory
+func TestE2E_Stage_DirectoryHunk(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "stage-directory-hunk-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	lines := "line1\nline2\nline3\nline4\nline5\nline6\nline7\nline8\nline9\nline10\n"
+	changed := "LINE1\nline2\nline3\nline4\nline5\nline6\nline7\nline8\nline9\nLINE10\n"
+	testRepo.CreateFile("src/a.txt", lines)
+	testRepo.CreateFile("src/pkg/b.txt", lines)
+	testRepo.CreateFile("other.txt", lines)
+	testRepo.CommitChanges("Initial commit")
+
+	testRepo.ModifyFile("src/a.txt", changed)
+	testRepo.ModifyFile("src/pkg/b.txt", changed)
+	testRepo.ModifyFile("other.txt", changed)
+	testRepo.GeneratePatch("changes.patch")
+
+	if err := runGitSequentialStage(context.Background(), []string{"src/:1"}, "changes.patch"); err != nil {
+		t.Fatalf("stage src/:1 failed: %v", err)
+	}
+
+	staged := testRepo.RunCommandOrFail("git", "diff", "--cached")
+	testutils.AssertDiffContains(t, staged, "src/a.txt", "src/pkg/b.txt", "+LINE1")
+	testutils.AssertDiffNotContains(t, staged, "other.txt", "+LINE10")
+}
+
+// TestE2E_Stage_PatternWithoutMatch tests that a pattern matching no file in the patch is an error
+func TestE2E_Stage_PatternWithoutMatch(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "stage-pattern-no-match-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateFile("file.txt", "a\n")
+	testRepo.CommitChanges("Initial commit")
+	testRepo.ModifyFile("file.txt", "a\nb\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	err := runGitSequentialStage(context.Background(), []string{"docs/**:*"}, "changes.patch")
+	if err == nil || !strings.Contains(err.Error(), "files matching docs/**") {
+		t.Fatalf("Expected pattern error, got %v", err)
+	}
+}
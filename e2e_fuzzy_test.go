@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/internal/stager"
+	"github.com/syou6162/git-sequential-stage/testutils"
+)
+
+// TestE2E_Stage_Fuzzy tests that --fuzzy stages the current version of an edited hunk
+// and reports the match with its confidence
+func TestE2E_Stage_Fuzzy(t *testing.T) {
+	testRepo, lines := testutils.NewMultiHunkRepo(t, "stage-fuzzy-*", 30, map[int]string{1: "FIRST\n", 20: "SECOND\nSECOND continued\nSECOND more\n"}, nil)
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.GeneratePatch("changes.patch")
+	lines[20] = "SECOND\nSECOND continued\nSECOND more\nSECOND edited later\n"
+	testRepo.ModifyFile("file.txt", lines.String())
+
+	if err := runGitSequentialStage(context.Background(), []string{"file.txt:2"}, "changes.patch"); err == nil {
+		t.Fatal("Expected the edited hunk not to be found without --fuzzy")
+	}
+
+	opts := stager.Options{Fuzzy: true}
+	result, err := runStageSelectionWithOptions(context.Background(), opts, stager.Selection{HunkSpecs: []string{"file.txt:1,2"}}, "changes.patch")
+	if err != nil {
+		t.Fatalf("stage --fuzzy failed: %v", err)
+	}
+
+	if result.Hunks[0].MatchedPatchID != "" {
+		t.Errorf("Expected the unchanged hunk to match by patch ID, got %+v", result.Hunks[0])
+	}
+	fuzzyHunk := result.Hunks[1]
+	if fuzzyHunk.MatchedPatchID == "" || fuzzyHunk.Confidence < 0.6 || fuzzyHunk.Confidence >= 1 {
+		t.Errorf("Expected a fuzzy match for the edited hunk, got %+v", fuzzyHunk)
+	}
+
+	staged := testRepo.RunCommandOrFail("git", "diff", "--cached")
+	testutils.AssertDiffContains(t, staged, "+FIRST", "+SECOND edited later")
+	if unstaged := testRepo.RunCommandOrFail("git", "diff"); unstaged != "" {
+		t.Errorf("Expected everything to be staged, got:\n%s", unstaged)
+	}
+}
+
+// TestE2E_Stage_FuzzyRejectsUnrelatedHunk tests that --fuzzy does not stage a hunk
+// that was rewritten completely
+func TestE2E_Stage_FuzzyRejectsUnrelatedHunk(t *testing.T) {
+	testRepo, lines := testutils.NewMultiHunkRepo(t, "stage-fuzzy-unrelated-*", 30, map[int]string{1: "FIRST\n", 20: "SECOND\nSECOND continued\nSECOND more\n"}, nil)
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.GeneratePatch("changes.patch")
+	lines[20] = "SECOND\nSECOND continued\nSECOND more\nSECOND edited later\n"
+	testRepo.ModifyFile("file.txt", lines.String())
+
+	content := strings.Replace(testRepo.RunCommandOrFail("cat", "file.txt"), "SECOND\nSECOND continued\nSECOND more\nSECOND edited later\n", "something else\n", 1)
+	testRepo.ModifyFile("file.txt", content)
+
+	opts := stager.Options{Fuzzy: true}
+	if _, err := runStageSelectionWithOptions(context.Background(), opts, stager.Selection{HunkSpecs: []string{"file.txt:2"}}, "changes.patch"); err == nil {
+		t.Fatal("Expected a rewritten hunk not to be matched")
+	}
+	if staged := testRepo.RunCommandOrFail("git", "diff", "--cached"); staged != "" {
+		t.Errorf("Expected nothing to be staged, got:\n%s", staged)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/internal/stager"
+	"github.com/syou6162/git-sequential-stage/testutils"
+)
+
+// TestE2E_Stage_HunkRanges tests range and last-hunk tokens in hunk specifications
+func TestE2E_Stage_HunkRanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		staged   []string
+		unstaged []string
+	}{
+		{"closed range", "file.txt:2-3", []string{"+H", "+O"}, []string{"+A", "+V"}},
+		{"open-ended range", "file.txt:3-", []string{"+O", "+V"}, []string{"+A", "+H"}},
+		{"last hunk", "file.txt:1,last", []string{"+A", "+V"}, []string{"+H", "+O"}},
+		{"minus one", "file.txt:-1", []string{"+V"}, []string{"+A", "+H", "+O"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo, _ := testutils.NewMultiHunkRepo(t, "stage-hunk-range-*", 40, map[int]string{0: "A\n", 10: "H\n", 20: "O\n", 30: "V\n"}, nil)
+			defer testRepo.Cleanup()
+			defer testRepo.Chdir()()
+
+			testRepo.GeneratePatch("changes.patch")
+
+			if err := runGitSequentialStage(context.Background(), []string{tt.spec}, "changes.patch"); err != nil {
+				t.Fatalf("stage %s failed: %v", tt.spec, err)
+			}
+
+			staged := testRepo.RunCommandOrFail("git", "diff", "--cached")
+			testutils.AssertDiffContains(t, staged, tt.staged...)
+			testutils.AssertDiffNotContains(t, staged, tt.unstaged...)
+		})
+	}
+}
+
+// TestE2E_Stage_HunkRangeExceeded tests that a range beyond the last hunk reports the actual hunk count
+func TestE2E_Stage_HunkRangeExceeded(t *testing.T) {
+	testRepo, _ := testutils.NewMultiHunkRepo(t, "stage-hunk-range-exceeded-*", 40, map[int]string{0: "A\n", 10: "H\n", 20: "O\n", 30: "V\n"}, nil)
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.GeneratePatch("changes.patch")
+
+	err := runGitSequentialStage(context.Background(), []string{"file.txt:3-6"}, "changes.patch")
+	var stagerErr *stager.StagerError
+	if !errors.As(err, &stagerErr) || stagerErr.Type != stager.ErrorTypeHunkCountExceeded {
+		t.Fatalf("Expected hunk count exceeded error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "file.txt has 4 hunks (valid: 1-4) but requested [6]") {
+		t.Errorf("Unexpected error message: %v", err)
+	}
+}
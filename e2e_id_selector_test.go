@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/testutils"
+)
+
+// TestE2E_Stage_IDSelector tests that hunks listed by list-hunks can be staged by patch ID,
+// independently of their position
+func TestE2E_Stage_IDSelector(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "stage-id-selector-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	initial := "line1\nline2\nline3\nline4\nline5\nline6\nline7\nline8\nline9\nline10\nline11\nline12\n"
+	testRepo.CreateFile("file.txt", initial)
+	testRepo.CommitChanges("Initial commit")
+
+	testRepo.ModifyFile("file.txt", "line1\nLINE2\nline3\nline4\nline5\nline6\nline7\nline8\nline9\nline10\nline11\nLINE12\n")
+
+	output, err := testutils.CaptureStdout(t, func() error {
+		return runListHunksCommand(context.Background(), []string{})
+	})
+	if err != nil {
+		t.Fatalf("list-hunks failed: %v", err)
+	}
+
+	match := regexp.MustCompile(`file\.txt:2 \(#2\) \+1 -1 @@ -9,4 \+9,4 @@ (id:[0-9a-f]{8})`).FindStringSubmatch(output)
+	if match == nil {
+		t.Fatalf("Expected patch ID of the second hunk in list-hunks output, got:\n%s", output)
+	}
+	secondHunk := match[1]
+
+	// Stage the first hunk so that positions in a fresh diff shift
+	testRepo.GeneratePatch("changes.patch")
+	if err := runGitSequentialStage(context.Background(), []string{"file.txt:1"}, "changes.patch"); err != nil {
+		t.Fatalf("stage file.txt:1 failed: %v", err)
+	}
+	testRepo.RunCommandOrFail("git", "commit", "-m", "First hunk")
+
+	// The second hunk is now hunk 1 of the new diff, but its ID is unchanged
+	testRepo.GeneratePatch("changes.patch")
+	if err := runGitSequentialStage(context.Background(), []string{secondHunk}, "changes.patch"); err != nil {
+		t.Fatalf("stage %s failed: %v", secondHunk, err)
+	}
+
+	staged := testRepo.RunCommandOrFail("git", "diff", "--cached")
+	testutils.AssertDiffContains(t, staged, "+LINE12")
+}
+
+// TestE2E_Stage_IDSelectorNotFound tests the error for a patch ID that is not in the patch
+func TestE2E_Stage_IDSelectorNotFound(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "stage-id-selector-not-found-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateFile("file.txt", "a\n")
+	testRepo.CommitChanges("Initial commit")
+	testRepo.ModifyFile("file.txt", "a\nb\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	err := runGitSequentialStage(context.Background(), []string{"id:00000000"}, "changes.patch")
+	if err == nil {
+		t.Fatal("Expected an error for an unknown patch ID")
+	}
+	if !strings.Contains(err.Error(), "patch ID 00000000") {
+		t.Errorf("Expected error to mention the patch ID, got %v", err)
+	}
+
+	if staged := testRepo.RunCommandOrFail("git", "diff", "--cached"); staged != "" {
+		t.Errorf("Expected nothing staged, got:\n%s", staged)
+	}
+}
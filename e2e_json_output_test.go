@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/testutils"
+)
+
+// TestE2E_CountHunks_JSONFormat tests count-hunks --format=json
+func TestE2E_CountHunks_JSONFormat(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "count-hunks-json-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateFile("main.go", "package main\n\nfunc main() {\n}\n")
+	testRepo.CreateBinaryFile("image.png", testutils.TestData.MinimalPNGTransparent)
+	testRepo.CommitChanges("Initial commit")
+
+	testRepo.ModifyFile("main.go", "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n")
+	testRepo.CreateBinaryFile("image.png", testutils.TestData.MinimalPNGRed)
+
+	output, err := testutils.CaptureStdout(t, func() error {
+		return runCountHunksCommand(context.Background(), []string{"--format=json"})
+	})
+	if err != nil {
+		t.Fatalf("count-hunks failed: %v", err)
+	}
+
+	var result countHunksOutput
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, output)
+	}
+
+	expected := []fileHunkCountOutput{
+		{Path: "image.png", Hunks: 1, Binary: true},
+		{Path: "main.go", Hunks: 1, Binary: false},
+	}
+	if len(result.Files) != len(expected) {
+		t.Fatalf("Expected %d files, got %d: %+v", len(expected), len(result.Files), result.Files)
+	}
+	for i, want := range expected {
+		if result.Files[i] != want {
+			t.Errorf("files[%d] = %+v, want %+v", i, result.Files[i], want)
+		}
+	}
+}
+
+// TestE2E_Stage_JSONFormat tests that stage --format=json reports per-hunk results
+func TestE2E_Stage_JSONFormat(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "stage-json-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	initial := "line1\nline2\nline3\nline4\nline5\nline6\nline7\nline8\nline9\nline10\nline11\nline12\n"
+	testRepo.CreateFile("file.txt", initial)
+	testRepo.CreateFile("other.txt", "other\n")
+	testRepo.CommitChanges("Initial commit")
+
+	testRepo.ModifyFile("file.txt", "line1\nLINE2\nline3\nline4\nline5\nline6\nline7\nline8\nline9\nline10\nline11\nLINE12\n")
+	testRepo.ModifyFile("other.txt", "changed\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	output, err := testutils.CaptureStdout(t, func() error {
+		return runStageCommand(context.Background(), []string{
+			"-patch", "changes.patch", "-hunk", "file.txt:2,1", "-hunk", "other.txt:*", "--format=json",
+		})
+	})
+	if err != nil {
+		t.Fatalf("stage failed: %v", err)
+	}
+
+	var result stageOutput
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, output)
+	}
+
+	if !result.Success || result.Error != nil {
+		t.Fatalf("Expected success, got %+v", result)
+	}
+
+	// Hunks are reported in request order
+	if len(result.Hunks) != 2 {
+		t.Fatalf("Expected 2 hunk results, got %+v", result.Hunks)
+	}
+	for i, wantHunk := range []int{2, 1} {
+		hunk := result.Hunks[i]
+		if hunk.File != "file.txt" || hunk.Hunk != wantHunk {
+			t.Errorf("hunks[%d] = %s:%d, want file.txt:%d", i, hunk.File, hunk.Hunk, wantHunk)
+		}
+		if hunk.Status != "applied" || hunk.Strategy != "cached" {
+			t.Errorf("hunks[%d] status/strategy = %s/%s, want applied/cached", i, hunk.Status, hunk.Strategy)
+		}
+		if hunk.PatchID == "" {
+			t.Errorf("hunks[%d] has empty patch ID", i)
+		}
+	}
+
+	if len(result.Files) != 1 || result.Files[0] != "other.txt" {
+		t.Errorf("Expected other.txt staged as a whole, got %v", result.Files)
+	}
+}
+
+// TestE2E_Stage_JSONErrorTypes tests that JSON errors carry the stager and safety error type names
+func TestE2E_Stage_JSONErrorTypes(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "stage-json-errors-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateFile("file.txt", "line1\nline2\n")
+	testRepo.CommitChanges("Initial commit")
+	testRepo.ModifyFile("file.txt", "line1\nLINE2\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	t.Run("hunk count exceeded", func(t *testing.T) {
+		result, err := runGitSequentialStageWithResult(context.Background(), []string{"file.txt:5"}, "changes.patch")
+		output := newStageOutput(result, err)
+
+		if output.Success || output.Error == nil {
+			t.Fatalf("Expected failure, got %+v", output)
+		}
+		if output.Error.Category != "stager" || output.Error.Type != "HunkCountExceeded" {
+			t.Errorf("Expected stager/HunkCountExceeded, got %s/%s", output.Error.Category, output.Error.Type)
+		}
+	})
+
+	t.Run("staging area not clean", func(t *testing.T) {
+		testRepo.CreateFile("staged.txt", "staged\n")
+		testRepo.RunCommandOrFail("git", "add", "staged.txt")
+		defer testRepo.RunCommandOrFail("git", "reset", "HEAD", "staged.txt")
+
+		result, err := runGitSequentialStageWithResult(context.Background(), []string{"file.txt:1"}, "changes.patch")
+		output := newStageOutput(result, err)
+
+		if output.Success || output.Error == nil {
+			t.Fatalf("Expected failure, got %+v", output)
+		}
+		if output.Error.Category != "safety" || output.Error.Type != "StagingAreaNotClean" {
+			t.Errorf("Expected safety/StagingAreaNotClean, got %s/%s", output.Error.Category, output.Error.Type)
+		}
+		if output.Error.Advice == "" {
+			t.Error("Expected advice for safety error")
+		}
+	})
+}
+
+// TestE2E_Stage_JSONEarlyErrors tests that errors found before staging starts are reported
+// as JSON too. The stage subcommand exits on failure, so it runs in a child process.
+func TestE2E_Stage_JSONEarlyErrors(t *testing.T) {
+	if args := os.Getenv("STAGE_JSON_EARLY_ERROR_ARGS"); args != "" {
+		if err := runStageCommand(context.Background(), strings.Split(args, " ")); err != nil {
+			os.Exit(2) // Plain error instead of JSON
+		}
+		os.Exit(0)
+	}
+
+	testRepo := testutils.NewTestRepo(t, "stage-json-early-errors-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateFile("file.txt", "line1\n")
+	testRepo.CommitChanges("Initial commit")
+	testRepo.ModifyFile("file.txt", "LINE1\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	tests := []struct {
+		name      string
+		args      string
+		errorType string
+	}{
+		{"invalid base", "-patch=changes.patch -hunk=file.txt:1 --base=no-such-rev", "InvalidArgument"},
+		{"missing patch file", "-patch=missing.patch -hunk=file.txt:1", "FileNotFound"},
+		{"invalid safety policy", "-patch=changes.patch -hunk=file.txt:1 --safety=lax", "InvalidArgument"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(os.Args[0], "-test.run=^TestE2E_Stage_JSONEarlyErrors$")
+			cmd.Env = append(os.Environ(), "STAGE_JSON_EARLY_ERROR_ARGS=--format=json "+tt.args)
+			stdout, err := cmd.Output()
+			if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+				t.Fatalf("Expected exit status 1, got %v", err)
+			}
+
+			var output stageOutput
+			if err := json.Unmarshal(stdout, &output); err != nil {
+				t.Fatalf("Expected JSON output, got %q: %v", stdout, err)
+			}
+			if output.Success || output.Error == nil || output.Error.Type != tt.errorType {
+				t.Errorf("Expected a %s error, got %+v", tt.errorType, output.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/testutils"
+)
+
+// TestE2E_Stage_HunkLineSelection tests staging only some lines of a hunk with file:N[a-b]
+func TestE2E_Stage_HunkLineSelection(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "stage-line-selection-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateFile("file.txt", "line1\nline2\nline3\nline4\nline5\nline6\n")
+	testRepo.CommitChanges("Initial commit")
+
+	// A single hunk that mixes two unrelated changes
+	testRepo.ModifyFile("file.txt", "line1\nLINE2\nline3\nline4\nLINE5\nline6\nline7\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	// Body lines 2-3 are "-line2" and "+LINE2"
+	if err := runGitSequentialStage(context.Background(), []string{"file.txt:1[2-3]"}, "changes.patch"); err != nil {
+		t.Fatalf("stage failed: %v", err)
+	}
+
+	staged := testRepo.RunCommandOrFail("git", "show", ":file.txt")
+	if staged != "line1\nLINE2\nline3\nline4\nline5\nline6\n" {
+		t.Errorf("Unexpected staged content:\n%s", staged)
+	}
+}
+
+// TestE2E_Stage_FileLineRange tests staging changed lines by file line number with file:La-Lb
+func TestE2E_Stage_FileLineRange(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "stage-file-line-range-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateFile("file.txt", "line1\nline2\nline3\nline4\nline5\nline6\n")
+	testRepo.CommitChanges("Initial commit")
+
+	testRepo.ModifyFile("file.txt", "line1\nLINE2\nline3\nline4\nLINE5\nline6\nline7\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	if err := runGitSequentialStage(context.Background(), []string{"file.txt:L5-L7"}, "changes.patch"); err != nil {
+		t.Fatalf("stage failed: %v", err)
+	}
+
+	staged := testRepo.RunCommandOrFail("git", "show", ":file.txt")
+	if staged != "line1\nline2\nline3\nline4\nLINE5\nline6\nline7\n" {
+		t.Errorf("Unexpected staged content:\n%s", staged)
+	}
+
+	// The unselected change stays in the working tree only
+	unstaged := testRepo.RunCommandOrFail("git", "diff")
+	testutils.AssertDiffContains(t, unstaged, "+LINE2")
+}
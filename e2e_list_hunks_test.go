@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/testutils"
+)
+
+// TestE2E_ListHunks_MatchesStageNumbering tests that list-hunks numbers can be passed to stage as-is
+func TestE2E_ListHunks_MatchesStageNumbering(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "list-hunks-numbering-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	initial := "line1\nline2\nline3\nline4\nline5\nline6\nline7\nline8\nline9\nline10\nline11\nline12\n"
+	testRepo.CreateFile("file.txt", initial)
+	testRepo.CommitChanges("Initial commit")
+
+	modified := "line1\nLINE2\nline3\nline4\nline5\nline6\nline7\nline8\nline9\nline10\nline11\nLINE12\n"
+	testRepo.ModifyFile("file.txt", modified)
+	testRepo.GeneratePatch("changes.patch")
+
+	output, err := testutils.CaptureStdout(t, func() error {
+		return runListHunksCommand(context.Background(), []string{"-patch", "changes.patch", "-body"})
+	})
+	if err != nil {
+		t.Fatalf("list-hunks failed: %v", err)
+	}
+
+	if !strings.Contains(output, "file.txt:1 (#1) +1 -1 @@ -1,5 +1,5 @@") {
+		t.Errorf("Expected summary of first hunk, got:\n%s", output)
+	}
+	if !strings.Contains(output, "file.txt:2 (#2) +1 -1 @@ -9,4 +9,4 @@") {
+		t.Errorf("Expected summary of second hunk, got:\n%s", output)
+	}
+	if !strings.Contains(output, "    +LINE12") {
+		t.Errorf("Expected indented body of second hunk, got:\n%s", output)
+	}
+
+	// The second listed hunk must be the one staged by file.txt:2
+	if err := runGitSequentialStage(context.Background(), []string{"file.txt:2"}, "changes.patch"); err != nil {
+		t.Fatalf("stage failed: %v", err)
+	}
+
+	stagedDiff := testRepo.RunCommandOrFail("git", "diff", "--cached")
+	testutils.AssertDiffContains(t, stagedDiff, "+LINE12")
+	testutils.AssertDiffNotContains(t, stagedDiff, "+LINE2")
+}
+
+// TestE2E_ListHunks_WorkingTree tests list-hunks without a patch file
+func TestE2E_ListHunks_WorkingTree(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "list-hunks-worktree-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateFile("main.go", "package main\n\nfunc main() {\n}\n")
+	testRepo.CreateBinaryFile("image.png", testutils.TestData.MinimalPNGTransparent)
+	testRepo.CommitChanges("Initial commit")
+
+	testRepo.ModifyFile("main.go", "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n")
+	testRepo.CreateBinaryFile("image.png", testutils.TestData.MinimalPNGRed)
+
+	output, err := testutils.CaptureStdout(t, func() error {
+		return runListHunksCommand(context.Background(), []string{})
+	})
+	if err != nil {
+		t.Fatalf("list-hunks failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines of output, got %d:\n%s", len(lines), output)
+	}
+	if lines[0] != "image.png:1 (#1) binary (stage with image.png:*)" {
+		t.Errorf("Unexpected binary summary: %s", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "main.go:1 (#2) +1 -0 @@ -1,4 +1,5 @@") {
+		t.Errorf("Unexpected text summary: %s", lines[1])
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/internal/stager"
+	"github.com/syou6162/git-sequential-stage/testutils"
+)
+
+// TestE2E_Stage_Match tests that -match stages every hunk whose changed lines match,
+// together with hunks selected by number
+func TestE2E_Stage_Match(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "stage-match-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateFile("logger.go", "package main\n\ntype Logger struct{}\n\nfunc a() {}\n\nfunc b() {}\n\nfunc c() {}\n\nfunc d() {}\n")
+	testRepo.CreateFile("main.go", "package main\n\nfunc main() {\n}\n")
+	testRepo.CommitChanges("Initial commit")
+
+	testRepo.ModifyFile("logger.go", "package main\n\ntype Logger struct{}\n\nfunc NewLogger() *Logger { return &Logger{} }\n\nfunc a() {}\n\nfunc b() {}\n\nfunc c() {}\n\nfunc d() {}\n\nfunc e() {}\n")
+	testRepo.ModifyFile("main.go", "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	_, err := testutils.CaptureStdout(t, func() error {
+		return runStageCommand(context.Background(), []string{
+			"-patch", "changes.patch", "-match", "*.go:func NewLogger", "-hunk", "main.go:1",
+		})
+	})
+	if err != nil {
+		t.Fatalf("stage -match failed: %v", err)
+	}
+
+	staged := testRepo.RunCommandOrFail("git", "diff", "--cached")
+	testutils.AssertDiffContains(t, staged, "+func NewLogger", "+\tprintln(\"hi\")")
+	testutils.AssertDiffNotContains(t, staged, "+func e()")
+}
+
+// TestE2E_Stage_MatchNothing tests that a -match selecting no hunk fails without staging anything
+func TestE2E_Stage_MatchNothing(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "stage-match-nothing-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateFile("file.txt", "a\n")
+	testRepo.CommitChanges("Initial commit")
+	testRepo.ModifyFile("file.txt", "a\nb\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	_, err := runStageSelection(context.Background(), stager.Selection{Matches: []string{"*.txt:^zzz$"}}, "changes.patch")
+	if err == nil {
+		t.Fatal("Expected an error for a match selecting no hunk")
+	}
+
+	if staged := testRepo.RunCommandOrFail("git", "diff", "--cached"); staged != "" {
+		t.Errorf("Expected nothing staged, got:\n%s", staged)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/testutils"
+)
+
+// TestE2E_Stage_PatchFromStdin tests that -patch=- reads the patch from stdin
+func TestE2E_Stage_PatchFromStdin(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "stage-patch-stdin-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateFile("file.txt", "line1\nline2\nline3\nline4\nline5\nline6\nline7\nline8\nline9\nline10\n")
+	testRepo.CommitChanges("Initial commit")
+	testRepo.ModifyFile("file.txt", "LINE1\nline2\nline3\nline4\nline5\nline6\nline7\nline8\nline9\nLINE10\n")
+
+	// Feed "git diff HEAD" through stdin
+	stdinFile, err := os.CreateTemp(t.TempDir(), "stdin-*.patch")
+	if err != nil {
+		t.Fatalf("Failed to create stdin file: %v", err)
+	}
+	if _, err := stdinFile.WriteString(testRepo.RunCommandOrFail("git", "diff", "HEAD")); err != nil {
+		t.Fatalf("Failed to write stdin file: %v", err)
+	}
+	if _, err := stdinFile.Seek(0, 0); err != nil {
+		t.Fatalf("Failed to rewind stdin file: %v", err)
+	}
+	defer func() { _ = stdinFile.Close() }()
+
+	originalStdin := os.Stdin
+	os.Stdin = stdinFile
+	defer func() { os.Stdin = originalStdin }()
+
+	_, err = testutils.CaptureStdout(t, func() error {
+		return runStageCommand(context.Background(), []string{"-patch", "-", "-hunk", "file.txt:2"})
+	})
+	if err != nil {
+		t.Fatalf("stage -patch=- failed: %v", err)
+	}
+
+	staged := testRepo.RunCommandOrFail("git", "diff", "--cached")
+	testutils.AssertDiffContains(t, staged, "+LINE10")
+	testutils.AssertDiffNotContains(t, staged, "+LINE1\n")
+}
+
+// TestE2E_Stage_FromWorktree tests that --from-worktree uses "git diff HEAD" as the patch
+func TestE2E_Stage_FromWorktree(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "stage-from-worktree-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateFile("file.txt", "line1\nline2\nline3\nline4\nline5\nline6\nline7\nline8\nline9\nline10\n")
+	testRepo.CreateFile("other.txt", "other\n")
+	testRepo.CommitChanges("Initial commit")
+	testRepo.ModifyFile("file.txt", "LINE1\nline2\nline3\nline4\nline5\nline6\nline7\nline8\nline9\nLINE10\n")
+	testRepo.ModifyFile("other.txt", "changed\n")
+
+	_, err := testutils.CaptureStdout(t, func() error {
+		return runStageCommand(context.Background(), []string{
+			"--from-worktree", "-hunk", "file.txt:1", "-hunk", "other.txt:*",
+		})
+	})
+	if err != nil {
+		t.Fatalf("stage --from-worktree failed: %v", err)
+	}
+
+	staged := testRepo.RunCommandOrFail("git", "diff", "--cached")
+	testutils.AssertDiffContains(t, staged, "+LINE1", "+changed")
+	testutils.AssertDiffNotContains(t, staged, "+LINE10")
+}
+
+// TestE2E_Stage_PatchAndFromWorktreeConflict tests that -patch and --from-worktree are mutually exclusive
+func TestE2E_Stage_PatchAndFromWorktreeConflict(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "stage-patch-source-conflict-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	err := runStageCommand(context.Background(), []string{
+		"-patch", "changes.patch", "--from-worktree", "-hunk", "file.txt:1",
+	})
+	var usageErr *usageShownError
+	if !errors.As(err, &usageErr) {
+		t.Fatalf("Expected usage error, got %v", err)
+	}
+}
@@ -2,12 +2,16 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/syou6162/git-sequential-stage/internal/executor"
+	"github.com/syou6162/git-sequential-stage/internal/stager"
 	"github.com/syou6162/git-sequential-stage/testutils"
 )
 
@@ -99,3 +103,92 @@ func TestE2E_PerformanceWithSafetyChecks(t *testing.T) {
 		}
 	})
 }
+
+// countingExecutor runs commands with the real executor and counts them by git subcommand
+type countingExecutor struct {
+	executor.CommandExecutor
+	calls map[string]int
+}
+
+func (e *countingExecutor) Execute(ctx context.Context, name string, args ...string) ([]byte, error) {
+	e.count(args)
+	return e.CommandExecutor.Execute(ctx, name, args...)
+}
+
+func (e *countingExecutor) ExecuteWithStdin(ctx context.Context, name string, stdin io.Reader, args ...string) ([]byte, error) {
+	e.count(args)
+	return e.CommandExecutor.ExecuteWithStdin(ctx, name, stdin, args...)
+}
+
+func (e *countingExecutor) count(args []string) {
+	if len(args) > 0 {
+		e.calls[args[0]]++
+	}
+}
+
+// total returns the number of commands run
+func (e *countingExecutor) total() int {
+	total := 0
+	for _, n := range e.calls {
+		total += n
+	}
+	return total
+}
+
+// TestE2E_PerformanceBatchedPatchIDs tests that staging every hunk of a large patch runs
+// the same number of git commands for any hunk count, with a single "git patch-id" process
+// per diff, so the work grows linearly with the number of hunks
+func TestE2E_PerformanceBatchedPatchIDs(t *testing.T) {
+	const lineDistance = 8 // Far enough apart for the context lines not to merge hunks
+
+	type run struct {
+		calls    map[string]int
+		total    int
+		duration time.Duration
+	}
+	stageAll := func(t *testing.T, hunkCount int) run {
+		edits := make(map[int]string, hunkCount)
+		for i := 0; i < hunkCount; i++ {
+			edits[i*lineDistance] = fmt.Sprintf("CHANGED %d\n", i)
+		}
+		testRepo, _ := testutils.NewMultiHunkRepo(t, "performance-batched-patch-id-*", hunkCount*lineDistance, edits, nil)
+		defer testRepo.Cleanup()
+		defer testRepo.Chdir()()
+
+		testRepo.GeneratePatch("changes.patch")
+
+		exec := &countingExecutor{CommandExecutor: executor.NewRealCommandExecutor(), calls: make(map[string]int)}
+		opts := stager.Options{GitPatchID: true}
+		selection := stager.Selection{HunkSpecs: []string{fmt.Sprintf("file.txt:1-%d", hunkCount)}}
+
+		start := time.Now()
+		result, err := stageWithExecutor(context.Background(), exec, opts, selection, "changes.patch")
+		duration := time.Since(start)
+		if err != nil {
+			t.Fatalf("Staging %d hunks failed: %v", hunkCount, err)
+		}
+		if len(result.Hunks) != hunkCount {
+			t.Fatalf("Expected %d staged hunks, got %d", hunkCount, len(result.Hunks))
+		}
+		t.Logf("%d hunks: %v, git commands: %v", hunkCount, duration, exec.calls)
+		return run{calls: exec.calls, total: exec.total(), duration: duration}
+	}
+
+	small := stageAll(t, 200)
+	large := stageAll(t, 800)
+
+	// At most one process for the patch and one for the current diff, whatever the hunk count
+	if small.calls["patch-id"] > 2 || large.calls["patch-id"] != small.calls["patch-id"] {
+		t.Errorf("Expected the same one or two git patch-id processes for both sizes, got %d and %d",
+			small.calls["patch-id"], large.calls["patch-id"])
+	}
+	if large.total != small.total {
+		t.Errorf("Expected the same number of git commands for 200 and 800 hunks, got %d and %d", small.total, large.total)
+	}
+
+	// Four times the hunks must not take anywhere near sixteen times as long; the generous
+	// bound keeps the check meaningful for quadratic work without being flaky
+	if large.duration > 10*small.duration+time.Second {
+		t.Errorf("Staging 800 hunks took %v, more than ten times the %v for 200 hunks", large.duration, small.duration)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/testutils"
+)
+
+// TestE2E_Remaining_AfterCommits tests that remaining lists the uncommitted hunks of the
+// original patch with their numbers in the current diff
+func TestE2E_Remaining_AfterCommits(t *testing.T) {
+	testRepo, _ := testutils.NewMultiHunkRepo(t, "remaining-*", 40, map[int]string{1: "FIRST\n", 20: "SECOND\n", 38: "THIRD\n"}, map[string]string{"other.txt": "other\n"})
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.ModifyFile("other.txt", "changed\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	// Commit the first hunk of file.txt and all of other.txt
+	if err := runGitSequentialStage(context.Background(), []string{"file.txt:1", "other.txt:*"}, "changes.patch"); err != nil {
+		t.Fatalf("Failed to stage: %v", err)
+	}
+	testRepo.RunCommandOrFail("git", "commit", "-m", "First")
+
+	remaining, total, err := runRemaining(context.Background(), "changes.patch")
+	if err != nil {
+		t.Fatalf("remaining failed: %v", err)
+	}
+	if total != 4 {
+		t.Errorf("Expected 4 hunks in the patch, got %d", total)
+	}
+	if len(remaining) != 2 {
+		t.Fatalf("Expected 2 remaining hunks, got %+v", remaining)
+	}
+	for i, expected := range []struct{ original, current int }{{2, 1}, {3, 2}} {
+		hunk := remaining[i]
+		if hunk.FilePath != "file.txt" || hunk.IndexInFile != expected.original || hunk.CurrentIndexInFile != expected.current {
+			t.Errorf("Expected file.txt:%d to be file.txt:%d now, got %+v", expected.original, expected.current, hunk)
+		}
+	}
+
+	// The current number selects the same hunk in a fresh diff
+	testRepo.GeneratePatch("fresh.patch")
+	if err := runGitSequentialStage(context.Background(), []string{"file.txt:2"}, "fresh.patch"); err != nil {
+		t.Fatalf("Failed to stage from the fresh patch: %v", err)
+	}
+	staged := testRepo.RunCommandOrFail("git", "diff", "--cached")
+	testutils.AssertDiffContains(t, staged, "+THIRD")
+	testutils.AssertDiffNotContains(t, staged, "+SECOND")
+
+	// Staged hunks are not committed yet, so they still remain
+	remaining, _, err = runRemaining(context.Background(), "changes.patch")
+	if err != nil {
+		t.Fatalf("remaining failed: %v", err)
+	}
+	if len(remaining) != 2 {
+		t.Errorf("Expected staged hunks to remain until committed, got %+v", remaining)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/internal/executor"
+	"github.com/syou6162/git-sequential-stage/internal/stager"
+	"github.com/syou6162/git-sequential-stage/testutils"
+)
+
+// TestE2E_Stage_RollbackOnFailure tests that a hunk staged earlier in the same call
+// is removed from the index again when a later hunk cannot be staged
+func TestE2E_Stage_RollbackOnFailure(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "stage-rollback-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	initial := "line1\nline2\nline3\nline4\nline5\nline6\nline7\nline8\nline9\nline10\nline11\nline12\n"
+	testRepo.CreateFile("file.txt", initial)
+	testRepo.CommitChanges("Initial commit")
+
+	testRepo.ModifyFile("file.txt", "line1\nLINE2\nline3\nline4\nline5\nline6\nline7\nline8\nline9\nline10\nline11\nLINE12\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	// Revert the second change after generating the patch so that hunk 2 can no longer be found
+	testRepo.ModifyFile("file.txt", "line1\nLINE2\nline3\nline4\nline5\nline6\nline7\nline8\nline9\nline10\nline11\nline12\n")
+
+	s := stager.NewStager(executor.NewRealCommandExecutor())
+	result, err := s.StageHunksWithResult(context.Background(), []string{"file.txt:1,2"}, "changes.patch")
+	if err == nil {
+		t.Fatal("Expected staging to fail for the missing hunk")
+	}
+
+	if result == nil || !result.RolledBack {
+		t.Fatalf("Expected result to report a rollback, got %+v", result)
+	}
+	if result.Hunks[0].Status != stager.HunkStatusRolledBack {
+		t.Errorf("Expected hunk 1 to be rolled back, got %s", result.Hunks[0].Status)
+	}
+	if result.Hunks[1].Status != stager.HunkStatusSkipped {
+		t.Errorf("Expected hunk 2 to be skipped, got %s", result.Hunks[1].Status)
+	}
+
+	if staged := testRepo.RunCommandOrFail("git", "diff", "--cached"); staged != "" {
+		t.Errorf("Expected empty staging area after rollback, got:\n%s", staged)
+	}
+}
+
+// TestE2E_Stage_RollbackKeepsIntentToAdd tests that rollback restores intent-to-add entries
+func TestE2E_Stage_RollbackKeepsIntentToAdd(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "stage-rollback-ita-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateFile("file.txt", "a\n")
+	testRepo.CommitChanges("Initial commit")
+
+	testRepo.CreateFile("new.txt", "new\n")
+	testRepo.RunCommandOrFail("git", "add", "-N", "new.txt")
+	testRepo.ModifyFile("file.txt", "a\nb\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	// Make the file.txt hunk unavailable so that staging fails after new.txt is applied
+	testRepo.ModifyFile("file.txt", "a\n")
+
+	err := runGitSequentialStage(context.Background(), []string{"new.txt:1", "file.txt:1"}, "changes.patch")
+	if err == nil {
+		t.Fatal("Expected staging to fail for the missing hunk")
+	}
+
+	status := testRepo.RunCommandOrFail("git", "status", "--porcelain")
+	if status != " A new.txt\n?? changes.patch\n" {
+		t.Errorf("Expected new.txt to be intent-to-add again, got:\n%s", status)
+	}
+}
+
+// TestE2E_Stage_RollbackOnWildcardFailure tests that hunks and whole files staged earlier
+// in the same call are removed from the index again when a wildcard file cannot be staged
+func TestE2E_Stage_RollbackOnWildcardFailure(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "stage-rollback-wildcard-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateFile("file.txt", "line1\nline2\nline3\n")
+	testRepo.CreateFile("f.txt", "f\n")
+	testRepo.CreateFile("g.txt", "g\n")
+	testRepo.CommitChanges("Initial commit")
+
+	testRepo.ModifyFile("file.txt", "line1\nLINE2\nline3\n")
+	testRepo.ModifyFile("f.txt", "F\n")
+	testRepo.ModifyFile("g.txt", "G\n")
+	testRepo.CreateFile("h.txt", "h\n")
+	testRepo.RunCommandOrFail("git", "add", "-N", "h.txt")
+	testRepo.GeneratePatch("changes.patch")
+
+	// Remove the untracked file after generating the patch so that it cannot be staged
+	testRepo.RunCommandOrFail("git", "rm", "--cached", "-q", "h.txt")
+	testRepo.RunCommandOrFail("rm", "h.txt")
+
+	result, err := stageWithExecutor(context.Background(), executor.NewRealCommandExecutor(), stager.Options{},
+		stager.Selection{HunkSpecs: []string{"file.txt:1", "f.txt:*", "g.txt:*", "h.txt:*"}}, "changes.patch")
+	if err == nil {
+		t.Fatal("Expected staging to fail for the missing wildcard file")
+	}
+
+	if result == nil || !result.RolledBack {
+		t.Fatalf("Expected result to report a rollback, got %+v", result)
+	}
+	if len(result.Hunks) != 1 || result.Hunks[0].Status != stager.HunkStatusRolledBack {
+		t.Errorf("Expected hunk file.txt:1 to be rolled back, got %+v", result.Hunks)
+	}
+
+	if staged := testRepo.RunCommandOrFail("git", "diff", "--cached"); staged != "" {
+		t.Errorf("Expected empty staging area after rollback, got:\n%s", staged)
+	}
+}
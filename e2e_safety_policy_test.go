@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/internal/stager"
+	"github.com/syou6162/git-sequential-stage/testutils"
+)
+
+// TestE2E_Stage_SafetyPolicies tests how each policy treats a file that was staged outside the targets
+func TestE2E_Stage_SafetyPolicies(t *testing.T) {
+	tests := []struct {
+		policy  stager.SafetyPolicy
+		allowed bool
+	}{
+		{stager.SafetyPolicyStrict, false},
+		{stager.SafetyPolicyTargetsOnly, false},
+		{stager.SafetyPolicyAllowPreexisting, true},
+		{stager.SafetyPolicyOff, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			testRepo, _ := testutils.NewMultiHunkRepo(t, "safety-policy-*", 30, map[int]string{1: "FIRST\n", 25: "SECOND\n"}, map[string]string{"other.txt": "other\n"})
+			defer testRepo.Cleanup()
+			defer testRepo.Chdir()()
+
+			testRepo.ModifyFile("other.txt", "staged by hand\n")
+			testRepo.GeneratePatch("changes.patch")
+			testRepo.RunCommandOrFail("git", "add", "other.txt")
+
+			opts := stager.Options{SafetyPolicy: tt.policy}
+			_, err := runStageSelectionWithOptions(context.Background(), opts, stager.Selection{HunkSpecs: []string{"file.txt:1"}}, "changes.patch")
+			if !tt.allowed {
+				if !errors.Is(err, &stager.SafetyError{Type: stager.StagingAreaNotClean}) {
+					t.Fatalf("Expected StagingAreaNotClean, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("stage failed: %v", err)
+			}
+
+			// The hunk is staged on top of the change staged beforehand
+			staged := testRepo.RunCommandOrFail("git", "diff", "--cached")
+			testutils.AssertDiffContains(t, staged, "+FIRST", "+staged by hand")
+			testutils.AssertDiffNotContains(t, staged, "+SECOND")
+		})
+	}
+}
+
+// TestE2E_Stage_StrictPolicyRejectsStagedTarget tests that only the strict policy refuses
+// to stage more hunks of a file that already has staged changes
+func TestE2E_Stage_StrictPolicyRejectsStagedTarget(t *testing.T) {
+	testRepo, _ := testutils.NewMultiHunkRepo(t, "safety-policy-strict-*", 30, map[int]string{1: "FIRST\n", 25: "SECOND\n"}, map[string]string{"other.txt": "other\n"})
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.ModifyFile("other.txt", "staged by hand\n")
+	testRepo.GeneratePatch("changes.patch")
+	testRepo.RunCommandOrFail("git", "add", "other.txt")
+	testRepo.RunCommandOrFail("git", "reset", "-q", "other.txt")
+
+	if err := runGitSequentialStage(context.Background(), []string{"file.txt:2"}, "changes.patch"); err != nil {
+		t.Fatalf("stage file.txt:2 failed: %v", err)
+	}
+
+	strict := stager.Options{SafetyPolicy: stager.SafetyPolicyStrict}
+	selection := stager.Selection{HunkSpecs: []string{"file.txt:1"}}
+	if _, err := runStageSelectionWithOptions(context.Background(), strict, selection, "changes.patch"); !errors.Is(err, &stager.SafetyError{Type: stager.StagingAreaNotClean}) {
+		t.Fatalf("Expected StagingAreaNotClean under the strict policy, got %v", err)
+	}
+
+	if _, err := runStageSelectionWithOptions(context.Background(), stager.Options{}, selection, "changes.patch"); err != nil {
+		t.Fatalf("Expected the default policy to allow staging on top of the target file, got %v", err)
+	}
+	testutils.AssertDiffContains(t, testRepo.RunCommandOrFail("git", "diff", "--cached"), "+FIRST", "+SECOND")
+}
+
+// TestE2E_ResolveSafetyPolicy tests that the policy is read from the flag first, then from
+// the environment variable and from git config otherwise
+func TestE2E_ResolveSafetyPolicy(t *testing.T) {
+	testRepo, _ := testutils.NewMultiHunkRepo(t, "safety-policy-config-*", 30, map[int]string{1: "FIRST\n", 25: "SECOND\n"}, map[string]string{"other.txt": "other\n"})
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.ModifyFile("other.txt", "staged by hand\n")
+	testRepo.GeneratePatch("changes.patch")
+	testRepo.RunCommandOrFail("git", "add", "other.txt")
+	ctx := context.Background()
+
+	t.Setenv("GIT_SEQUENTIAL_STAGE_SAFETY", "")
+	policy, err := resolveSafetyPolicy(ctx, "")
+	if err != nil || policy != stager.SafetyPolicyTargetsOnly {
+		t.Errorf("Expected the default policy, got %v (err: %v)", policy, err)
+	}
+
+	testRepo.RunCommandOrFail("git", "config", "sequential-stage.safety", "allow-preexisting")
+	policy, err = resolveSafetyPolicy(ctx, "")
+	if err != nil || policy != stager.SafetyPolicyAllowPreexisting {
+		t.Errorf("Expected the policy from git config, got %v (err: %v)", policy, err)
+	}
+	if err := runGitSequentialStage(ctx, []string{"file.txt:1"}, "changes.patch"); err != nil {
+		t.Errorf("Expected git config to allow the staged file, got %v", err)
+	}
+
+	t.Setenv("GIT_SEQUENTIAL_STAGE_SAFETY", "strict")
+	policy, err = resolveSafetyPolicy(ctx, "")
+	if err != nil || policy != stager.SafetyPolicyStrict {
+		t.Errorf("Expected the environment variable to override git config, got %v (err: %v)", policy, err)
+	}
+
+	policy, err = resolveSafetyPolicy(ctx, "off")
+	if err != nil || policy != stager.SafetyPolicyOff {
+		t.Errorf("Expected the flag to override the environment variable, got %v (err: %v)", policy, err)
+	}
+
+	t.Setenv("GIT_SEQUENTIAL_STAGE_SAFETY", "lenient")
+	if _, err := resolveSafetyPolicy(ctx, ""); err == nil {
+		t.Error("Expected an error for an unknown policy")
+	}
+}
+
+// TestE2E_SafetyFlagOverridesInvalidConfig tests that an invalid policy in git config neither
+// stops --safety from taking effect nor breaks commands that never run the safety checks
+func TestE2E_SafetyFlagOverridesInvalidConfig(t *testing.T) {
+	testRepo, _ := testutils.NewMultiHunkRepo(t, "safety-policy-invalid-config-*", 30, map[int]string{1: "FIRST\n", 25: "SECOND\n"}, map[string]string{"other.txt": "other\n"})
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.ModifyFile("other.txt", "staged by hand\n")
+	testRepo.GeneratePatch("changes.patch")
+	testRepo.RunCommandOrFail("git", "add", "other.txt")
+	testRepo.RunCommandOrFail("git", "config", "sequential-stage.safety", "bogus")
+	t.Setenv("GIT_SEQUENTIAL_STAGE_SAFETY", "")
+	ctx := context.Background()
+
+	if _, err := testutils.CaptureStdout(t, func() error {
+		return runListHunksCommand(ctx, []string{})
+	}); err != nil {
+		t.Errorf("Expected list-hunks to ignore the safety policy, got %v", err)
+	}
+	if _, _, err := runRemaining(ctx, "changes.patch"); err != nil {
+		t.Errorf("Expected remaining to ignore the safety policy, got %v", err)
+	}
+
+	if _, err := runStageSelection(ctx, stager.Selection{HunkSpecs: []string{"file.txt:1"}}, "changes.patch"); !errors.Is(err, &stager.StagerError{Type: stager.ErrorTypeInvalidArgument}) {
+		t.Errorf("Expected an InvalidArgument error without --safety, got %v", err)
+	}
+
+	if _, err := testutils.CaptureStdout(t, func() error {
+		return runStageCommand(ctx, []string{"-patch=changes.patch", "-hunk=file.txt:1", "--safety=off"})
+	}); err != nil {
+		t.Fatalf("Expected --safety to override git config, got %v", err)
+	}
+	staged := testRepo.RunCommandOrFail("git", "diff", "--cached")
+	testutils.AssertDiffContains(t, staged, "+FIRST", "+staged by hand")
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/internal/stager"
+	"github.com/syou6162/git-sequential-stage/testutils"
+)
+
+// TestE2E_Session_StatusAcrossCommits tests that stage --session records staged hunks
+// and that status follows them through a commit
+func TestE2E_Session_StatusAcrossCommits(t *testing.T) {
+	testRepo, _ := testutils.NewMultiHunkRepo(t, "session-status-*", 40, map[int]string{1: "FIRST\n", 20: "SECOND\n", 38: "THIRD\n"}, map[string]string{"other.txt": "other\n"})
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.ModifyFile("other.txt", "changed\n")
+	patchFile := filepath.Join(t.TempDir(), "changes.patch")
+	if err := os.WriteFile(patchFile, []byte(testRepo.RunCommandOrFail("git", "diff", "HEAD")), 0o644); err != nil {
+		t.Fatalf("Failed to write patch: %v", err)
+	}
+
+	if _, _, err := runStatus(context.Background()); !errors.Is(err, errNoSession) {
+		t.Fatalf("Expected no session before staging, got %v", err)
+	}
+
+	opts := stager.Options{Session: true}
+	if _, err := runStageSelectionWithOptions(context.Background(), opts, stager.Selection{HunkSpecs: []string{"file.txt:1", "other.txt:*"}}, patchFile); err != nil {
+		t.Fatalf("Failed to stage: %v", err)
+	}
+	assertSessionStates(t, map[string]string{"file.txt:1": "staged", "file.txt:2": "unstaged", "file.txt:3": "unstaged", "other.txt:1": "staged"})
+
+	testRepo.RunCommandOrFail("git", "commit", "-m", "First")
+
+	// Hunk numbers still refer to the original patch
+	if _, err := runStageSelectionWithOptions(context.Background(), opts, stager.Selection{HunkSpecs: []string{"file.txt:3"}}, patchFile); err != nil {
+		t.Fatalf("Failed to stage: %v", err)
+	}
+	assertSessionStates(t, map[string]string{"file.txt:1": "committed", "file.txt:2": "unstaged", "file.txt:3": "staged", "other.txt:1": "committed"})
+
+	if _, err := os.Stat(filepath.Join(".git", "sequential-stage", "session.json")); err != nil {
+		t.Errorf("Expected the session manifest in .git/sequential-stage: %v", err)
+	}
+}
+
+// TestE2E_Session_PartialAndUnstagedBeforeCommit tests that a line selection is reported
+// as partial and that a hunk unstaged again before a commit is not reported as committed
+func TestE2E_Session_PartialAndUnstagedBeforeCommit(t *testing.T) {
+	testRepo, _ := testutils.NewMultiHunkRepo(t, "session-partial-*", 40, map[int]string{1: "FIRST\n", 20: "SECOND\n", 38: "THIRD\n"}, nil)
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	patchFile := filepath.Join(t.TempDir(), "changes.patch")
+	if err := os.WriteFile(patchFile, []byte(testRepo.RunCommandOrFail("git", "diff", "HEAD")), 0o644); err != nil {
+		t.Fatalf("Failed to write patch: %v", err)
+	}
+
+	// Line 5 of the body of hunk 2 is "+SECOND"
+	opts := stager.Options{Session: true}
+	if _, err := runStageSelectionWithOptions(context.Background(), opts, stager.Selection{HunkSpecs: []string{"file.txt:1", "file.txt:2[5]"}}, patchFile); err != nil {
+		t.Fatalf("Failed to stage: %v", err)
+	}
+	assertSessionStates(t, map[string]string{"file.txt:1": "staged", "file.txt:2": "partial", "file.txt:3": "unstaged"})
+
+	if _, err := runUnstage(context.Background(), stager.Options{}, []string{"file.txt:1"}); err != nil {
+		t.Fatalf("Failed to unstage: %v", err)
+	}
+	testRepo.RunCommandOrFail("git", "commit", "-m", "Second")
+
+	staged := testRepo.RunCommandOrFail("git", "show", "HEAD")
+	testutils.AssertDiffContains(t, staged, "+SECOND")
+	testutils.AssertDiffNotContains(t, staged, "+FIRST")
+	assertSessionStates(t, map[string]string{"file.txt:1": "unstaged", "file.txt:2": "partial", "file.txt:3": "unstaged"})
+}
+
+// TestE2E_Session_DryRunDoesNotRecord tests that a dry run leaves the session untouched
+func TestE2E_Session_DryRunDoesNotRecord(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "session-dry-run-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateFile("file.txt", "line1\n")
+	testRepo.CommitChanges("Initial commit")
+	testRepo.ModifyFile("file.txt", "LINE1\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	opts := stager.Options{Session: true}
+	if _, _, err := runDryRunStage(context.Background(), opts, stager.Selection{HunkSpecs: []string{"file.txt:1"}}, "changes.patch"); err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if _, _, err := runStatus(context.Background()); !errors.Is(err, errNoSession) {
+		t.Errorf("Expected no session after a dry run, got %v", err)
+	}
+}
+
+// assertSessionStates checks the state that status reports for every hunk
+func assertSessionStates(t *testing.T, expected map[string]string) {
+	t.Helper()
+
+	_, statuses, err := runStatus(context.Background())
+	if err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	if len(statuses) != len(expected) {
+		t.Fatalf("Expected %d hunks, got %d", len(expected), len(statuses))
+	}
+	for _, status := range statuses {
+		key := fmt.Sprintf("%s:%d", status.FilePath, status.IndexInFile)
+		if status.State.String() != expected[key] {
+			t.Errorf("%s: expected %s, got %s", key, expected[key], status.State)
+		}
+	}
+}
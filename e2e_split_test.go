@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/testutils"
+)
+
+// TestE2E_Split tests split against a three-hunk change, a new file, a deleted file,
+// a renamed and edited file and an executable bit change with an edit
+func TestE2E_Split(t *testing.T) {
+	testRepo, _ := testutils.NewMultiHunkRepo(t, "split-*", 40, map[int]string{1: "FIRST\nADDED\n", 20: "", 37: "THIRD\n"},
+		map[string]string{"deleted.txt": "gone\n", "old.txt": "one\ntwo\nthree\nfour\nfive\nsix\n", "script.sh": "echo hello\n"})
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateFile("new.txt", "brand new\n")
+	testRepo.RunCommandOrFail("git", "rm", "-q", "deleted.txt")
+	testRepo.RunCommandOrFail("git", "mv", "old.txt", "renamed.txt")
+	testRepo.ModifyFile("renamed.txt", "one\ntwo\nthree\nfour\nfive\nSIX\n")
+	testRepo.ModifyFile("script.sh", "echo hello world\n")
+	testRepo.RunCommandOrFail("chmod", "+x", "script.sh")
+	testRepo.RunCommandOrFail("git", "add", "-A")
+	if err := testRepo.WriteFile("changes.patch", testRepo.RunCommandOrFail("git", "diff", "--cached", "-M")); err != nil {
+		t.Fatalf("Failed to write patch: %v", err)
+	}
+
+	// Go back to the original tree so that the split patches can be checked against it
+	testRepo.RunCommandOrFail("git", "reset", "-q", "--hard")
+	testRepo.RunCommandOrFail("git", "clean", "-fdq", "-e", "changes.patch")
+
+	// Every written patch passes "git apply --check" against the original tree,
+	// and the index is left untouched
+	t.Run("EachPatchAppliesOnItsOwn", func(t *testing.T) {
+		hunks := []string{"file.txt:3", "file.txt:1", "new.txt:*", "deleted.txt:*", "renamed.txt:*", "script.sh:*"}
+		written, err := runSplit(context.Background(), hunks, "changes.patch", "out-apply", false)
+		if err != nil {
+			t.Fatalf("split failed: %v", err)
+		}
+		if len(written) != len(hunks) {
+			t.Fatalf("Expected %d patch files, got %v", len(hunks), written)
+		}
+		if filepath.Base(written[0]) != "001-file.txt.patch" {
+			t.Errorf("Expected the first patch to be named 001-file.txt.patch, got %s", written[0])
+		}
+
+		for _, path := range written {
+			testRepo.RunCommandOrFail("git", "apply", "--check", path)
+		}
+
+		// The third hunk alone must be renumbered as if the first hunk had not been applied
+		third, err := os.ReadFile(written[0])
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", written[0], err)
+		}
+		if !strings.Contains(string(third), "@@ -35,6 +35,6 @@") {
+			t.Errorf("Expected the third hunk to keep its old position, got:\n%s", third)
+		}
+
+		if staged := testRepo.RunCommandOrFail("git", "diff", "--cached", "--stat"); staged != "" {
+			t.Errorf("Expected nothing to be staged, got:\n%s", staged)
+		}
+	})
+
+	// -per-file writes every file of a group separately
+	t.Run("PerFile", func(t *testing.T) {
+		written, err := runSplit(context.Background(), []string{"*.txt:*"}, "changes.patch", "out-per-file", true)
+		if err != nil {
+			t.Fatalf("split failed: %v", err)
+		}
+
+		var names []string
+		for _, path := range written {
+			names = append(names, filepath.Base(path))
+			testRepo.RunCommandOrFail("git", "apply", "--check", path)
+		}
+		want := []string{"001-deleted.txt.patch", "002-file.txt.patch", "003-new.txt.patch", "004-renamed.txt.patch"}
+		if strings.Join(names, ",") != strings.Join(want, ",") {
+			t.Errorf("Expected %v, got %v", want, names)
+		}
+	})
+
+	// The hunks of one -hunk flag are written to a single patch that leaves out the other hunks
+	t.Run("GroupWithSeveralHunks", func(t *testing.T) {
+		written, err := runSplit(context.Background(), []string{"file.txt:1,3"}, "changes.patch", "out-group", false)
+		if err != nil {
+			t.Fatalf("split failed: %v", err)
+		}
+		if len(written) != 1 {
+			t.Fatalf("Expected one patch file, got %v", written)
+		}
+
+		testRepo.RunCommandOrFail("git", "apply", "--cached", written[0])
+		staged := testRepo.RunCommandOrFail("git", "diff", "--cached")
+		testutils.AssertDiffContains(t, staged, "+FIRST", "+THIRD")
+		if strings.Contains(staged, "-lineu") {
+			t.Errorf("Expected the second hunk to be left out, got:\n%s", staged)
+		}
+	})
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/internal/executor"
+	"github.com/syou6162/git-sequential-stage/internal/stager"
+	"github.com/syou6162/git-sequential-stage/testutils"
+)
+
+// TestE2E_StalePatch_UpToDate tests that a patch generated from the working tree is kept
+func TestE2E_StalePatch_UpToDate(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "stale-patch-fresh-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateFile("file.txt", "line1\n")
+	testRepo.CommitChanges("Initial commit")
+	testRepo.ModifyFile("file.txt", "LINE1\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	patchFile, cleanup, err := checkStalePatch(context.Background(), executor.NewRealCommandExecutor(), stalePatchRefuse, "changes.patch", "")
+	defer cleanup()
+	if err != nil {
+		t.Fatalf("Expected an up-to-date patch, got %v", err)
+	}
+	if patchFile != "changes.patch" {
+		t.Errorf("Expected the patch file to be kept, got %s", patchFile)
+	}
+}
+
+// TestE2E_StalePatch_Symlink tests that a freshly generated patch changing a symbolic link
+// is up to date, and becomes stale once the link is pointed elsewhere
+func TestE2E_StalePatch_Symlink(t *testing.T) {
+	testRepo := testutils.NewTestRepo(t, "stale-patch-symlink-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateFile("a.txt", "a\n")
+	testRepo.CreateFile("b.txt", "b\n")
+	testRepo.RunCommandOrFail("ln", "-s", "a.txt", "link")
+	testRepo.CommitChanges("Initial commit")
+
+	testRepo.RunCommandOrFail("ln", "-sfn", "b.txt", "link")
+	testRepo.GeneratePatch("changes.patch")
+
+	_, cleanup, err := checkStalePatch(context.Background(), executor.NewRealCommandExecutor(), stalePatchRefuse, "changes.patch", "")
+	cleanup()
+	if err != nil {
+		t.Fatalf("Expected an up-to-date patch, got %v", err)
+	}
+
+	testRepo.RunCommandOrFail("ln", "-sfn", "a.txt", "link")
+	_, cleanup, err = checkStalePatch(context.Background(), executor.NewRealCommandExecutor(), stalePatchRefuse, "changes.patch", "")
+	cleanup()
+	if err == nil || !strings.HasSuffix(err.Error(), ": link") {
+		t.Errorf("Expected the link to be reported as stale, got %v", err)
+	}
+}
+
+// TestE2E_StalePatch_Refuse tests that --stale-patch=refuse names the files that drifted
+func TestE2E_StalePatch_Refuse(t *testing.T) {
+	testRepo, lines := testutils.NewMultiHunkRepo(t, "stale-patch-refuse-*", 30, map[int]string{1: "FIRST\n"}, map[string]string{"other.txt": "other\n"})
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.ModifyFile("other.txt", "changed\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	// Edited after the patch was generated
+	lines[25] = "LATER\n"
+	testRepo.ModifyFile("file.txt", lines.String())
+
+	_, cleanup, err := checkStalePatch(context.Background(), executor.NewRealCommandExecutor(), stalePatchRefuse, "changes.patch", "")
+	defer cleanup()
+
+	var stagerErr *stager.StagerError
+	if !errors.As(err, &stagerErr) || stagerErr.Type != stager.ErrorTypeStalePatch {
+		t.Fatalf("Expected a StalePatch error, got %v", err)
+	}
+	if !strings.HasSuffix(err.Error(), ": file.txt") {
+		t.Errorf("Expected only file.txt to be reported, got %q", err.Error())
+	}
+}
+
+// TestE2E_StalePatch_Warn tests that the default mode keeps the stale patch
+func TestE2E_StalePatch_Warn(t *testing.T) {
+	testRepo, lines := testutils.NewMultiHunkRepo(t, "stale-patch-warn-*", 30, map[int]string{1: "FIRST\n"}, map[string]string{"other.txt": "other\n"})
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.ModifyFile("other.txt", "changed\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	// Edited after the patch was generated
+	lines[25] = "LATER\n"
+	testRepo.ModifyFile("file.txt", lines.String())
+
+	patchFile, cleanup, err := checkStalePatch(context.Background(), executor.NewRealCommandExecutor(), stalePatchWarn, "changes.patch", "")
+	defer cleanup()
+	if err != nil {
+		t.Fatalf("Expected a warning only, got %v", err)
+	}
+	if patchFile != "changes.patch" {
+		t.Errorf("Expected the stale patch file to be kept, got %s", patchFile)
+	}
+}
+
+// TestE2E_StalePatch_Regenerate tests that --stale-patch=regenerate stages from a fresh "git diff HEAD"
+func TestE2E_StalePatch_Regenerate(t *testing.T) {
+	testRepo, lines := testutils.NewMultiHunkRepo(t, "stale-patch-regenerate-*", 30, map[int]string{1: "FIRST\n"}, map[string]string{"other.txt": "other\n"})
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.ModifyFile("other.txt", "changed\n")
+	testRepo.GeneratePatch("changes.patch")
+
+	// Edited after the patch was generated
+	lines[25] = "LATER\n"
+	testRepo.ModifyFile("file.txt", lines.String())
+
+	patchFile, cleanup, err := checkStalePatch(context.Background(), executor.NewRealCommandExecutor(), stalePatchRegenerate, "changes.patch", "")
+	if err != nil {
+		cleanup()
+		t.Fatalf("Failed to regenerate the patch: %v", err)
+	}
+	if patchFile == "changes.patch" {
+		t.Fatalf("Expected a regenerated patch file")
+	}
+
+	// Hunk 2 of file.txt only exists in the regenerated patch
+	err = runGitSequentialStage(context.Background(), []string{"file.txt:2"}, patchFile)
+	cleanup()
+	if err != nil {
+		t.Fatalf("Failed to stage from the regenerated patch: %v", err)
+	}
+	if _, statErr := os.Stat(patchFile); !os.IsNotExist(statErr) {
+		t.Errorf("Expected the regenerated patch to be removed, got %v", statErr)
+	}
+
+	staged := testRepo.RunCommandOrFail("git", "diff", "--cached")
+	testutils.AssertDiffContains(t, staged, "+LATER")
+	testutils.AssertDiffNotContains(t, staged, "+FIRST")
+}
+
+// TestE2E_StalePatch_RegenerateRemapsNumbers tests that --stale-patch=regenerate keeps a
+// hunk number of the stale patch selecting the same change although the numbering shifted
+func TestE2E_StalePatch_RegenerateRemapsNumbers(t *testing.T) {
+	testRepo, lines := testutils.NewMultiHunkRepo(t, "stale-patch-remap-*", 30, map[int]string{25: "PATCHED\n"}, nil)
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.GeneratePatch("changes.patch")
+
+	// Edited after the patch was generated, ahead of the patched hunk
+	lines[1] = "EARLIER\n"
+	testRepo.ModifyFile("file.txt", lines.String())
+
+	_, err := testutils.CaptureStdout(t, func() error {
+		return runStageCommand(context.Background(), []string{
+			"-patch", "changes.patch", "-hunk", "file.txt:1", "--stale-patch=regenerate",
+		})
+	})
+	if err != nil {
+		t.Fatalf("stage --stale-patch=regenerate failed: %v", err)
+	}
+
+	staged := testRepo.RunCommandOrFail("git", "diff", "--cached")
+	testutils.AssertDiffContains(t, staged, "+PATCHED")
+	testutils.AssertDiffNotContains(t, staged, "+EARLIER")
+}
+
+// TestE2E_StalePatch_RegenerateChangedHunk tests that a selected hunk that was edited since
+// the patch was generated is reported instead of staging whatever took its number
+func TestE2E_StalePatch_RegenerateChangedHunk(t *testing.T) {
+	testRepo, lines := testutils.NewMultiHunkRepo(t, "stale-patch-remap-changed-*", 30, map[int]string{25: "PATCHED\n"}, nil)
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.GeneratePatch("changes.patch")
+
+	// Edited after the patch was generated, ahead of the patched hunk
+	lines[1] = "EARLIER\n"
+	testRepo.ModifyFile("file.txt", lines.String())
+
+	lines[25] = "EDITED\n"
+	testRepo.ModifyFile("file.txt", lines.String())
+
+	exec := executor.NewRealCommandExecutor()
+	patchFile, cleanup, err := checkStalePatch(context.Background(), exec, stalePatchRegenerate, "changes.patch", "")
+	defer cleanup()
+	if err != nil {
+		t.Fatalf("Failed to regenerate the patch: %v", err)
+	}
+
+	_, err = remapSelection(context.Background(), exec, stager.Options{}, stager.Selection{HunkSpecs: []string{"file.txt:1"}}, "changes.patch", patchFile)
+	var stagerErr *stager.StagerError
+	if !errors.As(err, &stagerErr) || stagerErr.Type != stager.ErrorTypeHunkNotFound {
+		t.Fatalf("Expected a HunkNotFound error, got %v", err)
+	}
+}
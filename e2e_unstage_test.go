@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/internal/stager"
+	"github.com/syou6162/git-sequential-stage/testutils"
+)
+
+// TestE2E_Unstage_SingleHunk tests that unstaging one hunk keeps the other staged hunks
+// and leaves the working tree untouched
+func TestE2E_Unstage_SingleHunk(t *testing.T) {
+	testRepo, _ := testutils.NewMultiHunkRepo(t, "unstage-hunk-*", 30, map[int]string{1: "FIRST\n", 14: "SECOND\n", 28: "THIRD\n"}, map[string]string{"other.txt": "other\n"})
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.ModifyFile("other.txt", "changed\n")
+	testRepo.RunCommandOrFail("git", "add", "file.txt", "other.txt")
+
+	result, err := runUnstage(context.Background(), stager.Options{}, []string{"file.txt:2"})
+	if err != nil {
+		t.Fatalf("unstage file.txt:2 failed: %v", err)
+	}
+	if len(result.Hunks) != 1 || result.Hunks[0].FilePath != "file.txt" || result.Hunks[0].IndexInFile != 2 {
+		t.Errorf("Expected the result to report file.txt:2, got %+v", result.Hunks)
+	}
+
+	staged := testRepo.RunCommandOrFail("git", "diff", "--cached")
+	testutils.AssertDiffContains(t, staged, "+FIRST", "+THIRD", "other.txt")
+	testutils.AssertDiffNotContains(t, staged, "+SECOND")
+
+	unstaged := testRepo.RunCommandOrFail("git", "diff")
+	testutils.AssertDiffContains(t, unstaged, "+SECOND")
+	testutils.AssertDiffNotContains(t, unstaged, "+FIRST", "+THIRD")
+}
+
+// TestE2E_Unstage_MultipleHunks tests that hunk numbers refer to the staged diff before unstaging starts
+func TestE2E_Unstage_MultipleHunks(t *testing.T) {
+	testRepo, _ := testutils.NewMultiHunkRepo(t, "unstage-multiple-*", 30, map[int]string{1: "FIRST\n", 14: "SECOND\n", 28: "THIRD\n"}, map[string]string{"other.txt": "other\n"})
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.ModifyFile("other.txt", "changed\n")
+	testRepo.RunCommandOrFail("git", "add", "file.txt", "other.txt")
+
+	if _, err := runUnstage(context.Background(), stager.Options{}, []string{"file.txt:1,3"}); err != nil {
+		t.Fatalf("unstage file.txt:1,3 failed: %v", err)
+	}
+
+	staged := testRepo.RunCommandOrFail("git", "diff", "--cached")
+	testutils.AssertDiffContains(t, staged, "+SECOND")
+	testutils.AssertDiffNotContains(t, staged, "+FIRST", "+THIRD")
+}
+
+// TestE2E_Unstage_WholeFile tests that "file:*" removes every staged change of the file
+func TestE2E_Unstage_WholeFile(t *testing.T) {
+	testRepo, _ := testutils.NewMultiHunkRepo(t, "unstage-wildcard-*", 30, map[int]string{1: "FIRST\n", 14: "SECOND\n", 28: "THIRD\n"}, map[string]string{"other.txt": "other\n"})
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.ModifyFile("other.txt", "changed\n")
+	testRepo.RunCommandOrFail("git", "add", "file.txt", "other.txt")
+
+	result, err := runUnstage(context.Background(), stager.Options{}, []string{"other.txt:*"})
+	if err != nil {
+		t.Fatalf("unstage other.txt:* failed: %v", err)
+	}
+	if len(result.Files) != 1 || result.Files[0] != "other.txt" {
+		t.Errorf("Expected the result to report other.txt, got %v", result.Files)
+	}
+
+	staged := testRepo.RunCommandOrFail("git", "diff", "--cached", "--stat")
+	if strings.Contains(staged, "other.txt") || !strings.Contains(staged, "file.txt") {
+		t.Errorf("Expected only file.txt to remain staged, got:\n%s", staged)
+	}
+}
+
+// TestE2E_Unstage_NonexistentHunk tests that unstaging a hunk that is not staged fails without touching the index
+func TestE2E_Unstage_NonexistentHunk(t *testing.T) {
+	testRepo, _ := testutils.NewMultiHunkRepo(t, "unstage-missing-*", 30, map[int]string{1: "FIRST\n", 14: "SECOND\n", 28: "THIRD\n"}, map[string]string{"other.txt": "other\n"})
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.ModifyFile("other.txt", "changed\n")
+	testRepo.RunCommandOrFail("git", "add", "file.txt", "other.txt")
+
+	before := testRepo.RunCommandOrFail("git", "diff", "--cached")
+	if _, err := runUnstage(context.Background(), stager.Options{}, []string{"file.txt:4"}); err == nil {
+		t.Fatal("Expected an error for a hunk that is not staged")
+	}
+	if after := testRepo.RunCommandOrFail("git", "diff", "--cached"); after != before {
+		t.Errorf("Expected the staging area to be unchanged, got:\n%s", after)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/internal/stager"
+	"github.com/syou6162/git-sequential-stage/testutils"
+)
+
+// TestE2E_Stage_Verify tests that --verify accepts a staging run whose staged diff contains
+// exactly the requested hunks, including new files, and that unstaging is verified as well
+func TestE2E_Stage_Verify(t *testing.T) {
+	testRepo, _ := testutils.NewMultiHunkRepo(t, "stage-verify-*", 30, map[int]string{1: "FIRST\n", 14: "SECOND\n", 28: "THIRD\n"}, nil)
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	testRepo.CreateFile("new.txt", "brand new\n")
+	testRepo.RunCommandOrFail("git", "add", "-N", "new.txt")
+	testRepo.GeneratePatch("changes.patch")
+
+	opts := stager.Options{Verify: true}
+	selection := stager.Selection{HunkSpecs: []string{"file.txt:1,3", "new.txt:1"}}
+	if _, err := runStageSelectionWithOptions(context.Background(), opts, selection, "changes.patch"); err != nil {
+		t.Fatalf("stage --verify failed: %v", err)
+	}
+
+	staged := testRepo.RunCommandOrFail("git", "diff", "--cached")
+	testutils.AssertDiffContains(t, staged, "+FIRST", "+THIRD", "+brand new")
+	testutils.AssertDiffNotContains(t, staged, "+SECOND")
+
+	// Unstaging verifies that exactly the requested hunk left the staged diff
+	if _, err := runUnstage(context.Background(), opts, []string{"file.txt:2"}); err != nil {
+		t.Fatalf("unstage --verify failed: %v", err)
+	}
+	staged = testRepo.RunCommandOrFail("git", "diff", "--cached")
+	testutils.AssertDiffContains(t, staged, "+FIRST", "+brand new")
+	testutils.AssertDiffNotContains(t, staged, "+THIRD")
+}
@@ -5,6 +5,7 @@ go 1.24.2
 require (
 	github.com/bluekeyes/go-gitdiff v0.8.1
 	github.com/go-git/go-git/v5 v5.16.5
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
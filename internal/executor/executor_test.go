@@ -487,6 +487,36 @@ func TestRealCommandExecutorStderrCapture(t *testing.T) {
 	}
 }
 
+func TestRealCommandExecutorWithEnv(t *testing.T) {
+	base := NewRealCommandExecutor()
+	withEnv := base.WithEnv("GSS_EXECUTOR_TEST=from-env")
+
+	output, err := withEnv.Execute(context.Background(), "sh", "-c", "echo $GSS_EXECUTOR_TEST")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if strings.TrimSpace(string(output)) != "from-env" {
+		t.Errorf("Execute() output = %q, want %q", output, "from-env")
+	}
+
+	output, err = withEnv.ExecuteWithStdin(context.Background(), "sh", strings.NewReader(""), "-c", "echo $GSS_EXECUTOR_TEST")
+	if err != nil {
+		t.Fatalf("ExecuteWithStdin() error = %v", err)
+	}
+	if strings.TrimSpace(string(output)) != "from-env" {
+		t.Errorf("ExecuteWithStdin() output = %q, want %q", output, "from-env")
+	}
+
+	// The original executor is not modified
+	output, err = base.Execute(context.Background(), "sh", "-c", "echo $GSS_EXECUTOR_TEST")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if strings.TrimSpace(string(output)) != "" {
+		t.Errorf("Expected base executor without extra env, got %q", output)
+	}
+}
+
 func TestWrapGitError(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"strings"
 
@@ -14,6 +15,7 @@ import (
 // RealCommandExecutor is the real implementation of CommandExecutor
 type RealCommandExecutor struct {
 	logger *logger.Logger
+	env    []string // Extra environment variables ("KEY=value") for every command
 }
 
 // NewRealCommandExecutor creates a new real executor
@@ -23,9 +25,28 @@ func NewRealCommandExecutor() *RealCommandExecutor {
 	}
 }
 
+// WithEnv returns a copy of the executor that runs every command with the given
+// environment variables ("KEY=value") added to the current process environment.
+// This is used e.g. to point git at a temporary index with GIT_INDEX_FILE.
+func (r *RealCommandExecutor) WithEnv(env ...string) *RealCommandExecutor {
+	return &RealCommandExecutor{
+		logger: r.logger,
+		env:    append(append([]string{}, r.env...), env...),
+	}
+}
+
+// command creates an exec.Cmd with the executor's environment
+func (r *RealCommandExecutor) command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	if len(r.env) > 0 {
+		cmd.Env = append(os.Environ(), r.env...)
+	}
+	return cmd
+}
+
 // Execute implements CommandExecutor.Execute
 func (r *RealCommandExecutor) Execute(ctx context.Context, name string, args ...string) ([]byte, error) {
-	cmd := exec.CommandContext(ctx, name, args...)
+	cmd := r.command(ctx, name, args...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -48,7 +69,7 @@ func (r *RealCommandExecutor) Execute(ctx context.Context, name string, args ...
 
 // ExecuteWithStdin implements CommandExecutor.ExecuteWithStdin
 func (r *RealCommandExecutor) ExecuteWithStdin(ctx context.Context, name string, stdin io.Reader, args ...string) ([]byte, error) {
-	cmd := exec.CommandContext(ctx, name, args...)
+	cmd := r.command(ctx, name, args...)
 	cmd.Stdin = stdin
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
@@ -0,0 +1,192 @@
+package stager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	"github.com/syou6162/git-sequential-stage/internal/executor"
+)
+
+// defaultBase is the revision the current diff is taken against unless Options.Base is set
+const defaultBase = "HEAD"
+
+// base returns the revision the current diff is taken against
+func (s *Stager) base() string {
+	if s.options.Base == "" {
+		return defaultBase
+	}
+	return s.options.Base
+}
+
+// VerifyBaseRevision checks that base names a commit. Revisions starting with a dash are
+// rejected since git would take them as options. Callers must run it before base is put on
+// any git command line, and pass it after "--end-of-options" there.
+func VerifyBaseRevision(ctx context.Context, exec executor.CommandExecutor, base string) error {
+	if strings.HasPrefix(base, "-") {
+		return NewInvalidArgumentError(fmt.Sprintf("invalid base revision: %s", base), nil)
+	}
+	if _, err := exec.Execute(ctx, "git", "rev-parse", "--verify", "--quiet", "--end-of-options", base+"^{commit}"); err != nil {
+		return NewInvalidArgumentError(fmt.Sprintf("invalid base revision: %s", base), err)
+	}
+	return nil
+}
+
+// validatePatchBase checks that the patch was generated against Options.Base: the old blob
+// of every changed file must be the one at the base, and new files must not exist there.
+// Files without an index line cannot be checked. Nothing is checked unless Options.Base is set.
+func (s *Stager) validatePatchBase(ctx context.Context, patchContent string) error {
+	base := s.options.Base
+	if base == "" {
+		return nil
+	}
+
+	if err := VerifyBaseRevision(ctx, s.executor, base); err != nil {
+		return err
+	}
+
+	files, _, err := gitdiff.Parse(strings.NewReader(patchContent))
+	if err != nil {
+		return NewParsingError("patch file", err)
+	}
+
+	var checked []*gitdiff.File
+	args := []string{"ls-tree", "-r", "-z", "--full-tree", "--end-of-options", base, "--"}
+	for _, file := range files {
+		if file.OldOIDPrefix == "" {
+			continue
+		}
+		checked = append(checked, file)
+		if file.IsNew {
+			args = append(args, file.NewName)
+		} else {
+			args = append(args, file.OldName)
+		}
+	}
+	if len(checked) == 0 {
+		return nil
+	}
+
+	output, err := s.executor.Execute(ctx, "git", args...)
+	if err != nil {
+		return NewGitCommandError("git ls-tree", err)
+	}
+	blobs := parseLsTree(output)
+
+	var mismatched []string
+	for _, file := range checked {
+		if file.IsNew {
+			if _, exists := blobs[file.NewName]; exists {
+				mismatched = append(mismatched, file.NewName)
+			}
+			continue
+		}
+		if oid, exists := blobs[file.OldName]; !exists || !strings.HasPrefix(oid, file.OldOIDPrefix) {
+			s.logger.Debug("Patch expects %s at %s in %s, found %q", file.OldOIDPrefix, file.OldName, base, oid)
+			mismatched = append(mismatched, file.OldName)
+		}
+	}
+	if len(mismatched) > 0 {
+		return NewBaseMismatchError(base, mismatched)
+	}
+	return nil
+}
+
+// checkCommittedSinceBase rejects targets that touch lines changed between Options.Base and
+// HEAD. Hunks are taken from "git diff <base>", but the staging area starts out at HEAD, so a
+// hunk whose lines (context included) were changed by those commits cannot be applied to it.
+// Files added or deleted since the base conflict as a whole.
+func (s *Stager) checkCommittedSinceBase(ctx context.Context, targets []hunkTarget) error {
+	base := s.options.Base
+	if base == "" || s.unstaging {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	args := []string{"diff", "-U0", "--no-renames", "--end-of-options", base, "HEAD", "--"}
+	for _, target := range targets {
+		path := basePath(target.Hunk)
+		if !seen[path] {
+			seen[path] = true
+			args = append(args, path)
+		}
+	}
+
+	output, err := s.executor.Execute(ctx, "git", args...)
+	if err != nil {
+		return NewGitCommandError(fmt.Sprintf("git diff %s HEAD", base), err)
+	}
+	if len(output) == 0 {
+		return nil
+	}
+	files, _, err := gitdiff.Parse(bytes.NewReader(output))
+	if err != nil {
+		return NewParsingError(fmt.Sprintf("git diff %s HEAD", base), err)
+	}
+
+	committed := make(map[string]*gitdiff.File, len(files))
+	for _, file := range files {
+		if file.IsNew {
+			committed[file.NewName] = file
+		} else {
+			committed[file.OldName] = file
+		}
+	}
+
+	var conflicting []string
+	for _, target := range targets {
+		file, changed := committed[basePath(target.Hunk)]
+		if changed && (file.IsNew || file.IsDelete || target.Hunk.Fragment == nil || overlapsFragments(target.Hunk.Fragment, file.TextFragments)) {
+			conflicting = append(conflicting, hunkKey(target.Hunk.FilePath, target.Hunk.IndexInFile))
+		}
+	}
+	if len(conflicting) > 0 {
+		return NewCommittedSinceBaseError(base, conflicting)
+	}
+	return nil
+}
+
+// basePath returns the path of the hunk's file at the base revision
+func basePath(hunk HunkInfo) string {
+	if hunk.OldFilePath != "" {
+		return hunk.OldFilePath
+	}
+	return hunk.FilePath
+}
+
+// overlapsFragments reports whether any of the zero-context fragments changes old lines
+// covered by fragment, or inserts lines between two of them
+func overlapsFragments(fragment *gitdiff.TextFragment, changes []*gitdiff.TextFragment) bool {
+	first, last := fragment.OldPosition, fragment.OldPosition+fragment.OldLines-1
+	for _, change := range changes {
+		if change.OldLines == 0 {
+			// Lines inserted after line OldPosition
+			if first <= change.OldPosition && change.OldPosition < last {
+				return true
+			}
+			continue
+		}
+		if change.OldPosition <= last && change.OldPosition+change.OldLines-1 >= first {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLsTree maps the paths listed by "git ls-tree -z" to their object IDs
+func parseLsTree(output []byte) map[string]string {
+	blobs := make(map[string]string)
+	for _, entry := range bytes.Split(output, []byte{0}) {
+		// <mode> SP <type> SP <object> TAB <path>
+		meta, path, found := strings.Cut(string(entry), "\t")
+		if !found {
+			continue
+		}
+		if fields := strings.Fields(meta); len(fields) == 3 {
+			blobs[path] = fields[2]
+		}
+	}
+	return blobs
+}
@@ -0,0 +1,122 @@
+package stager
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	"github.com/syou6162/git-sequential-stage/internal/executor"
+	"github.com/syou6162/git-sequential-stage/internal/logger"
+)
+
+const basePatch = `diff --git a/file.txt b/file.txt
+index 1111111..2222222 100644
+--- a/file.txt
++++ b/file.txt
+@@ -1 +1 @@
+-a
++A
+diff --git a/new.txt b/new.txt
+new file mode 100644
+index 0000000..3333333
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1 @@
++new
+`
+
+func newBaseMock(lsTree string) *executor.MockCommandExecutor {
+	mock := executor.NewMockCommandExecutor()
+	mock.Commands["git [rev-parse --verify --quiet --end-of-options fixup^{commit}]"] = executor.MockResponse{Output: []byte("4444444444444444444444444444444444444444\n")}
+	mock.Commands["git [ls-tree -r -z --full-tree --end-of-options fixup -- file.txt new.txt]"] = executor.MockResponse{Output: []byte(lsTree)}
+	return mock
+}
+
+func TestGetCurrentDiff_UsesBase(t *testing.T) {
+	mock := executor.NewMockCommandExecutor()
+	mock.Commands["git [diff --end-of-options fixup -- file.txt]"] = executor.MockResponse{Output: []byte("diff")}
+	s := &Stager{executor: mock, logger: logger.NewFromEnv(), options: Options{Base: "fixup"}}
+
+	output, err := s.getCurrentDiff(context.Background(), map[string]bool{"file.txt": true})
+	if err != nil {
+		t.Fatalf("getCurrentDiff failed: %v", err)
+	}
+	if string(output) != "diff" {
+		t.Errorf("Expected the diff against the base, got %q", output)
+	}
+}
+
+func TestValidatePatchBase(t *testing.T) {
+	tests := []struct {
+		name       string
+		lsTree     string
+		mismatched string
+	}{
+		{"matches", "100644 blob 1111111aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa\tfile.txt\x00", ""},
+		{"changed file", "100644 blob 5555555aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa\tfile.txt\x00", "file.txt"},
+		{"missing file", "", "file.txt"},
+		{"new file exists", "100644 blob 1111111aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa\tfile.txt\x00100644 blob 6666666aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa\tnew.txt\x00", "new.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Stager{executor: newBaseMock(tt.lsTree), logger: logger.NewFromEnv(), options: Options{Base: "fixup"}}
+
+			err := s.validatePatchBase(context.Background(), basePatch)
+			if tt.mismatched == "" {
+				if err != nil {
+					t.Errorf("Expected the patch to match the base, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, &StagerError{Type: ErrorTypeBaseMismatch}) {
+				t.Fatalf("Expected a BaseMismatch error, got %v", err)
+			}
+			if !strings.HasSuffix(err.Error(), ": "+tt.mismatched) {
+				t.Errorf("Expected %s to be reported, got %v", tt.mismatched, err)
+			}
+		})
+	}
+}
+
+func TestValidatePatchBase_NotSet(t *testing.T) {
+	mock := executor.NewMockCommandExecutor()
+	s := &Stager{executor: mock, logger: logger.NewFromEnv()}
+
+	if err := s.validatePatchBase(context.Background(), basePatch); err != nil {
+		t.Errorf("Expected no validation without a base, got %v", err)
+	}
+	if len(mock.ExecutedCommands) != 0 {
+		t.Errorf("Expected no git commands, got %v", mock.ExecutedCommands)
+	}
+}
+
+func TestOverlapsFragments(t *testing.T) {
+	// The hunk covers old lines 10 to 16
+	hunk := &gitdiff.TextFragment{OldPosition: 10, OldLines: 7}
+
+	tests := []struct {
+		name     string
+		change   gitdiff.TextFragment
+		expected bool
+	}{
+		{"changed before", gitdiff.TextFragment{OldPosition: 5, OldLines: 2}, false},
+		{"changed first line", gitdiff.TextFragment{OldPosition: 8, OldLines: 3}, true},
+		{"changed inside", gitdiff.TextFragment{OldPosition: 13, OldLines: 1}, true},
+		{"changed last line", gitdiff.TextFragment{OldPosition: 16, OldLines: 1}, true},
+		{"changed after", gitdiff.TextFragment{OldPosition: 17, OldLines: 1}, false},
+		{"inserted before", gitdiff.TextFragment{OldPosition: 9}, false},
+		{"inserted inside", gitdiff.TextFragment{OldPosition: 12}, true},
+		{"inserted after", gitdiff.TextFragment{OldPosition: 16}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := overlapsFragments(hunk, []*gitdiff.TextFragment{&tt.change}); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -2,17 +2,22 @@ package stager
 
 import (
 	"fmt"
-	"strconv"
+	"sort"
 )
 
-// CountHunksInDiff counts the number of hunks per file in the given diff output.
-// It parses the diff using ParsePatchFileWithGitDiff and returns a map of file paths to hunk counts.
-// For binary files, returns "*" to indicate wildcard staging is required.
-// For text files, returns the hunk count as a string.
-// Returns an empty map if diffOutput is empty.
-func CountHunksInDiff(diffOutput string) (map[string]string, error) {
+// FileHunkCount holds the number of hunks for a single file
+type FileHunkCount struct {
+	FilePath string
+	Hunks    int  // Number of hunks (1 for binary files)
+	IsBinary bool // Binary files must be staged with a wildcard
+}
+
+// CountHunksPerFile counts the number of hunks per file in the given diff output.
+// The result is sorted alphabetically by file path.
+// Returns an empty slice if diffOutput is empty.
+func CountHunksPerFile(diffOutput string) ([]FileHunkCount, error) {
 	if len(diffOutput) == 0 {
-		return make(map[string]string), nil
+		return []FileHunkCount{}, nil
 	}
 
 	// Parse the diff using existing parser
@@ -22,25 +27,26 @@ func CountHunksInDiff(diffOutput string) (map[string]string, error) {
 	}
 
 	// Count hunks per file, tracking binary status
-	hunkCounts := make(map[string]int)
-	binaryFiles := make(map[string]bool)
-
+	countsByFile := make(map[string]*FileHunkCount)
 	for _, hunk := range hunks {
+		count, exists := countsByFile[hunk.FilePath]
+		if !exists {
+			count = &FileHunkCount{FilePath: hunk.FilePath}
+			countsByFile[hunk.FilePath] = count
+		}
+		count.Hunks++
 		if hunk.IsBinary {
-			binaryFiles[hunk.FilePath] = true
-		} else {
-			hunkCounts[hunk.FilePath]++
+			count.IsBinary = true
 		}
 	}
 
-	// Convert to string format
-	result := make(map[string]string)
-	for file := range binaryFiles {
-		result[file] = "*"
-	}
-	for file, count := range hunkCounts {
-		result[file] = strconv.Itoa(count)
+	result := make([]FileHunkCount, 0, len(countsByFile))
+	for _, count := range countsByFile {
+		result = append(result, *count)
 	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].FilePath < result[j].FilePath
+	})
 
 	return result, nil
 }
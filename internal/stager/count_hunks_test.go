@@ -5,20 +5,20 @@ import (
 	"testing"
 )
 
-// TestCountHunksInDiff_NoChanges tests counting hunks when diff is empty
-func TestCountHunksInDiff_NoChanges(t *testing.T) {
-	result, err := CountHunksInDiff("")
+// TestCountHunksPerFile_NoChanges tests counting hunks when diff is empty
+func TestCountHunksPerFile_NoChanges(t *testing.T) {
+	result, err := CountHunksPerFile("")
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
 
 	if len(result) != 0 {
-		t.Errorf("Expected empty map for empty diff, got %v", result)
+		t.Errorf("Expected no files for empty diff, got %v", result)
 	}
 }
 
-// TestCountHunksInDiff_SingleFileMultipleHunks tests counting multiple hunks in one file
-func TestCountHunksInDiff_SingleFileMultipleHunks(t *testing.T) {
+// TestCountHunksPerFile_SingleFileMultipleHunks tests counting multiple hunks in one file
+func TestCountHunksPerFile_SingleFileMultipleHunks(t *testing.T) {
 	diffOutput := `diff --git a/calculator.go b/calculator.go
 index 1234567..abcdefg 100644
 --- a/calculator.go
@@ -34,18 +34,18 @@ index 1234567..abcdefg 100644
  }
 `
 
-	result, err := CountHunksInDiff(diffOutput)
+	result, err := CountHunksPerFile(diffOutput)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
 
-	if result["calculator.go"] != "2" {
-		t.Errorf("Expected calculator.go to have 2 hunks, got %s", result["calculator.go"])
+	if len(result) != 1 || result[0] != (FileHunkCount{FilePath: "calculator.go", Hunks: 2}) {
+		t.Errorf("Expected calculator.go to have 2 hunks, got %v", result)
 	}
 }
 
-// TestCountHunksInDiff_MultipleFiles tests counting hunks across multiple files
-func TestCountHunksInDiff_MultipleFiles(t *testing.T) {
+// TestCountHunksPerFile_MultipleFiles tests counting hunks across multiple files
+func TestCountHunksPerFile_MultipleFiles(t *testing.T) {
 	diffOutput := `diff --git a/file1.go b/file1.go
 index 1234567..abcdefg 100644
 --- a/file1.go
@@ -64,31 +64,31 @@ index 2234567..bbcdefg 100644
  }
 `
 
-	result, err := CountHunksInDiff(diffOutput)
+	result, err := CountHunksPerFile(diffOutput)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
 
-	expected := map[string]string{
-		"file1.go": "1",
-		"file2.go": "1",
+	expected := []FileHunkCount{
+		{FilePath: "file1.go", Hunks: 1},
+		{FilePath: "file2.go", Hunks: 1},
 	}
 
 	if len(result) != len(expected) {
-		t.Errorf("Expected %d files, got %d", len(expected), len(result))
+		t.Fatalf("Expected %d files, got %d: %v", len(expected), len(result), result)
 	}
 
-	for file, expectedCount := range expected {
-		if result[file] != expectedCount {
-			t.Errorf("Expected %s to have %s hunks, got %s", file, expectedCount, result[file])
+	for i, want := range expected {
+		if result[i] != want {
+			t.Errorf("result[%d] = %+v, want %+v", i, result[i], want)
 		}
 	}
 }
 
-// TestCountHunksInDiff_ParseError tests error handling when diff parsing fails
+// TestCountHunksPerFile_ParseError tests error handling when diff parsing fails
 // Note: ParsePatchFileWithGitDiff has fallback mechanism, so it rarely returns errors.
 // This test uses completely invalid input to trigger a parse error.
-func TestCountHunksInDiff_ParseError(t *testing.T) {
+func TestCountHunksPerFile_ParseError(t *testing.T) {
 	diffOutput := `diff --git a/file.go b/file.go
 --- a/file.go
 +++ b/file.go
@@ -96,7 +96,7 @@ func TestCountHunksInDiff_ParseError(t *testing.T) {
 corrupted content
 `
 
-	result, err := CountHunksInDiff(diffOutput)
+	result, err := CountHunksPerFile(diffOutput)
 
 	// If parser has robust fallback, it might succeed with 0 hunks
 	// Either error or empty result is acceptable
@@ -110,26 +110,26 @@ corrupted content
 	}
 }
 
-// TestCountHunksInDiff_BinaryFile tests that binary files are counted as "*"
-func TestCountHunksInDiff_BinaryFile(t *testing.T) {
+// TestCountHunksPerFile_BinaryFile tests that binary files are flagged as binary
+func TestCountHunksPerFile_BinaryFile(t *testing.T) {
 	diffOutput := `diff --git a/image.png b/image.png
 new file mode 100644
 index 0000000..abc123
 Binary files /dev/null and b/image.png differ
 `
 
-	result, err := CountHunksInDiff(diffOutput)
+	result, err := CountHunksPerFile(diffOutput)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
 
-	if result["image.png"] != "*" {
-		t.Errorf("Expected image.png to have '*' (wildcard), got %s", result["image.png"])
+	if len(result) != 1 || result[0] != (FileHunkCount{FilePath: "image.png", Hunks: 1, IsBinary: true}) {
+		t.Errorf("Expected image.png to be a binary file, got %v", result)
 	}
 }
 
-// TestCountHunksInDiff_MixedBinaryAndText tests counting hunks with both binary and text files
-func TestCountHunksInDiff_MixedBinaryAndText(t *testing.T) {
+// TestCountHunksPerFile_MixedBinaryAndText tests counting hunks with both binary and text files
+func TestCountHunksPerFile_MixedBinaryAndText(t *testing.T) {
 	diffOutput := `diff --git a/image.png b/image.png
 new file mode 100644
 index 0000000..abc123
@@ -152,24 +152,65 @@ index def456..ghi789 100644
 Binary files a/logo.jpg and b/logo.jpg differ
 `
 
-	result, err := CountHunksInDiff(diffOutput)
+	result, err := CountHunksPerFile(diffOutput)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
 
-	expected := map[string]string{
-		"image.png": "*",
-		"main.go":   "2",
-		"logo.jpg":  "*",
+	expected := []FileHunkCount{
+		{FilePath: "image.png", Hunks: 1, IsBinary: true},
+		{FilePath: "logo.jpg", Hunks: 1, IsBinary: true},
+		{FilePath: "main.go", Hunks: 2},
 	}
 
 	if len(result) != len(expected) {
-		t.Errorf("Expected %d files, got %d", len(expected), len(result))
+		t.Fatalf("Expected %d files, got %d: %v", len(expected), len(result), result)
 	}
 
-	for file, expectedCount := range expected {
-		if result[file] != expectedCount {
-			t.Errorf("Expected %s to have %s, got %s", file, expectedCount, result[file])
+	for i, want := range expected {
+		if result[i] != want {
+			t.Errorf("result[%d] = %+v, want %+v", i, result[i], want)
+		}
+	}
+}
+
+// TestCountHunksPerFile_SortedWithBinaryFlag tests that per-file counts are sorted and flag binary files
+func TestCountHunksPerFile_SortedWithBinaryFlag(t *testing.T) {
+	diffOutput := `diff --git a/main.go b/main.go
+index 1234567..abcdefg 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,4 @@
+ package main
++import "fmt"
+
+ func main() {
+@@ -10,2 +11,3 @@ func test() {
+ 	return 0
++	// comment
+ }
+diff --git a/image.png b/image.png
+index def456..ghi789 100644
+Binary files a/image.png and b/image.png differ
+`
+
+	result, err := CountHunksPerFile(diffOutput)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []FileHunkCount{
+		{FilePath: "image.png", Hunks: 1, IsBinary: true},
+		{FilePath: "main.go", Hunks: 2, IsBinary: false},
+	}
+
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d files, got %d: %v", len(expected), len(result), result)
+	}
+
+	for i, want := range expected {
+		if result[i] != want {
+			t.Errorf("result[%d] = %+v, want %+v", i, result[i], want)
 		}
 	}
 }
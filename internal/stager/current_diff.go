@@ -0,0 +1,94 @@
+package stager
+
+import (
+	"context"
+	"errors"
+)
+
+// currentDiff is the diff between the base revision (HEAD by default) and the working tree for the target files,
+// with the single-hunk patch and patch ID of every hunk.
+// It is loaded once per staging run: "git apply --cached" only changes the index,
+// so the diff stays valid while hunks are staged. Only a working-directory apply
+// changes it, and then just the affected file is reloaded.
+type currentDiff struct {
+	hunks    []HunkInfo
+	contents [][]byte // Single-hunk patch of every hunk (nil if it could not be generated)
+	patchIDs []string // Patch ID of every hunk (empty if it could not be calculated)
+	used     []bool   // Whether the hunk has already been matched to a target
+}
+
+// loadCurrentDiff gets, parses and hashes the current diff of the given files
+func (s *Stager) loadCurrentDiff(ctx context.Context, files map[string]bool) (*currentDiff, error) {
+	diffOutput, err := s.getCurrentDiff(ctx, files)
+	if err != nil {
+		return nil, err
+	}
+
+	hunks, err := ParsePatchFileWithGitDiff(string(diffOutput))
+	if err != nil {
+		s.logger.Error("Failed to parse current diff: %v", err)
+		return nil, NewParsingError("current diff", err)
+	}
+
+	contents := make([][]byte, len(hunks))
+	for i := range hunks {
+		hunkContent, err := s.extractHunkContent(&hunks[i])
+		if err != nil {
+			continue
+		}
+		contents[i] = hunkContent
+	}
+
+	patchIDs, err := s.calculatePatchIDs(ctx, contents)
+	if err != nil {
+		// Check if context was canceled or timed out
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, ctx.Err()
+		}
+		return nil, NewGitCommandError("patch-id calculation", err)
+	}
+
+	return &currentDiff{
+		hunks:    hunks,
+		contents: contents,
+		patchIDs: patchIDs,
+		used:     make([]bool, len(hunks)),
+	}, nil
+}
+
+// find returns the position of the first hunk with the given patch ID that is
+// neither used nor claimed, or -1 if there is none
+func (d *currentDiff) find(patchID string, claimed map[int]bool) int {
+	for i, id := range d.patchIDs {
+		if id != "" && id == patchID && !d.used[i] && !claimed[i] {
+			return i
+		}
+	}
+	return -1
+}
+
+// reloadFile replaces the hunks of filePath with those of a fresh diff of that file
+func (s *Stager) reloadFile(ctx context.Context, d *currentDiff, filePath string) error {
+	fresh, err := s.loadCurrentDiff(ctx, map[string]bool{filePath: true})
+	if err != nil {
+		return err
+	}
+
+	reloaded := &currentDiff{}
+	for i := range d.hunks {
+		if d.hunks[i].FilePath == filePath {
+			continue
+		}
+		reloaded.hunks = append(reloaded.hunks, d.hunks[i])
+		reloaded.contents = append(reloaded.contents, d.contents[i])
+		reloaded.patchIDs = append(reloaded.patchIDs, d.patchIDs[i])
+		reloaded.used = append(reloaded.used, d.used[i])
+	}
+	reloaded.hunks = append(reloaded.hunks, fresh.hunks...)
+	reloaded.contents = append(reloaded.contents, fresh.contents...)
+	reloaded.patchIDs = append(reloaded.patchIDs, fresh.patchIDs...)
+	reloaded.used = append(reloaded.used, fresh.used...)
+
+	*d = *reloaded
+	return nil
+}
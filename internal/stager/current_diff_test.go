@@ -0,0 +1,91 @@
+package stager
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/internal/executor"
+	"github.com/syou6162/git-sequential-stage/internal/logger"
+)
+
+const threeHunkDiff = `diff --git a/file.txt b/file.txt
+index 1111111..2222222 100644
+--- a/file.txt
++++ b/file.txt
+@@ -1,3 +1,3 @@
+ line1
+-line2
++line2 changed
+ line3
+@@ -10,3 +10,3 @@
+ line10
+-line11
++line11 changed
+ line12
+@@ -20,3 +20,3 @@
+ line20
+-line21
++line21 changed
+ line22
+`
+
+func TestStageTargetsSequentially_DiffsOnceAndAppliesFileAtOnce(t *testing.T) {
+	mock := executor.NewMockCommandExecutor()
+	mock.Commands["git [diff --end-of-options HEAD -- file.txt]"] = executor.MockResponse{Output: []byte(threeHunkDiff)}
+	mock.Commands["git [apply --cached]"] = executor.MockResponse{}
+	s := &Stager{executor: mock, logger: logger.NewFromEnv()}
+	ctx := context.Background()
+
+	allHunks, err := ParsePatchFileWithGitDiff(threeHunkDiff)
+	if err != nil {
+		t.Fatalf("Failed to parse diff: %v", err)
+	}
+	if err := s.calculatePatchIDsForHunks(ctx, allHunks); err != nil {
+		t.Fatalf("Failed to calculate patch IDs: %v", err)
+	}
+	targets, err := buildTargets([]string{"file.txt:3,1"}, allHunks)
+	if err != nil {
+		t.Fatalf("Failed to build targets: %v", err)
+	}
+
+	result := newStageResult(targets)
+	if err := s.stageTargetsSequentially(ctx, targets, map[string]bool{"file.txt": true}, result); err != nil {
+		t.Fatalf("stageTargetsSequentially failed: %v", err)
+	}
+
+	var diffs, applies []executor.ExecutedCommand
+	for _, cmd := range mock.ExecutedCommands {
+		switch {
+		case len(cmd.Args) > 0 && cmd.Args[0] == "diff":
+			diffs = append(diffs, cmd)
+		case len(cmd.Args) > 0 && cmd.Args[0] == "apply":
+			applies = append(applies, cmd)
+		}
+	}
+	if len(diffs) != 1 {
+		t.Errorf("Expected the current diff to be loaded once, got %d git diff calls", len(diffs))
+	}
+	if len(applies) != 1 {
+		t.Fatalf("Expected a single git apply --cached for the file, got %d", len(applies))
+	}
+
+	patch := string(applies[0].Stdin)
+	if strings.Count(patch, "diff --git") != 1 {
+		t.Errorf("Expected one file header in the combined patch:\n%s", patch)
+	}
+	for _, want := range []string{"+line2 changed", "+line21 changed"} {
+		if !strings.Contains(patch, want) {
+			t.Errorf("Combined patch is missing %q:\n%s", want, patch)
+		}
+	}
+	if strings.Contains(patch, "+line11 changed") {
+		t.Errorf("Combined patch contains an unselected hunk:\n%s", patch)
+	}
+
+	for _, hunk := range result.Hunks {
+		if hunk.Status != HunkStatusApplied || hunk.Strategy != ApplyStrategyCached {
+			t.Errorf("Hunk %s:%d = %s/%s, want applied/cached", hunk.FilePath, hunk.IndexInFile, hunk.Status, hunk.Strategy)
+		}
+	}
+}
@@ -75,3 +75,90 @@ func TestEnumTypeSafety(t *testing.T) {
 		t.Errorf("Expected 'commit', got %s", action.Category.String())
 	}
 }
+
+func TestErrorType_String(t *testing.T) {
+	tests := []struct {
+		errorType ErrorType
+		expected  string
+	}{
+		{ErrorTypeUnknown, "Unknown"},
+		{ErrorTypeFileNotFound, "FileNotFound"},
+		{ErrorTypeParsing, "Parsing"},
+		{ErrorTypeGitCommand, "GitCommand"},
+		{ErrorTypeHunkNotFound, "HunkNotFound"},
+		{ErrorTypeInvalidArgument, "InvalidArgument"},
+		{ErrorTypeDependencyMissing, "DependencyMissing"},
+		{ErrorTypeIO, "IO"},
+		{ErrorTypePatchApplication, "PatchApplication"},
+		{ErrorTypeHunkCountExceeded, "HunkCountExceeded"},
+		{ErrorTypeStalePatch, "StalePatch"},
+		{ErrorTypeBaseMismatch, "BaseMismatch"},
+		{ErrorType(999), "Unknown"}, // Test unknown type
+	}
+
+	for _, test := range tests {
+		if test.errorType.String() != test.expected {
+			t.Errorf("ErrorType(%d).String() = %s, expected %s",
+				test.errorType, test.errorType.String(), test.expected)
+		}
+	}
+}
+
+func TestHunkStatus_String(t *testing.T) {
+	tests := []struct {
+		status   HunkStatus
+		expected string
+	}{
+		{HunkStatusSkipped, "skipped"},
+		{HunkStatusApplied, "applied"},
+		{HunkStatusRolledBack, "rolled-back"},
+		{HunkStatus(999), "unknown"}, // Test unknown status
+	}
+
+	for _, test := range tests {
+		if test.status.String() != test.expected {
+			t.Errorf("HunkStatus(%d).String() = %s, expected %s",
+				test.status, test.status.String(), test.expected)
+		}
+	}
+}
+
+func TestSessionHunkState_String(t *testing.T) {
+	tests := []struct {
+		state    SessionHunkState
+		expected string
+	}{
+		{SessionHunkUnstaged, "unstaged"},
+		{SessionHunkStaged, "staged"},
+		{SessionHunkCommitted, "committed"},
+		{SessionHunkPartial, "partial"},
+		{SessionHunkState(999), "unknown"}, // Test unknown state
+	}
+
+	for _, test := range tests {
+		if test.state.String() != test.expected {
+			t.Errorf("SessionHunkState(%d).String() = %s, expected %s",
+				test.state, test.state.String(), test.expected)
+		}
+	}
+}
+
+func TestApplyStrategy_String(t *testing.T) {
+	tests := []struct {
+		strategy ApplyStrategy
+		expected string
+	}{
+		{ApplyStrategyNone, "none"},
+		{ApplyStrategyCached, "cached"},
+		{ApplyStrategyResetApply, "reset-apply"},
+		{ApplyStrategyWorkingDirectory, "working-directory"},
+		{ApplyStrategy(999), "unknown"}, // Test unknown strategy
+	}
+
+	for _, test := range tests {
+		if test.strategy.String() != test.expected {
+			t.Errorf("ApplyStrategy(%d).String() = %s, expected %s",
+				test.strategy, test.strategy.String(), test.expected)
+		}
+	}
+}
@@ -30,8 +30,44 @@ const (
 	ErrorTypePatchApplication
 	// ErrorTypeHunkCountExceeded is when requested hunk numbers exceed available hunks
 	ErrorTypeHunkCountExceeded
+	// ErrorTypeStalePatch is when the patch file no longer matches the working tree
+	ErrorTypeStalePatch
+	// ErrorTypeBaseMismatch is when the patch was not generated against the base revision
+	ErrorTypeBaseMismatch
 )
 
+// String returns a string representation of the error type
+func (t ErrorType) String() string {
+	switch t {
+	case ErrorTypeUnknown:
+		return "Unknown"
+	case ErrorTypeFileNotFound:
+		return "FileNotFound"
+	case ErrorTypeParsing:
+		return "Parsing"
+	case ErrorTypeGitCommand:
+		return "GitCommand"
+	case ErrorTypeHunkNotFound:
+		return "HunkNotFound"
+	case ErrorTypeInvalidArgument:
+		return "InvalidArgument"
+	case ErrorTypeDependencyMissing:
+		return "DependencyMissing"
+	case ErrorTypeIO:
+		return "IO"
+	case ErrorTypePatchApplication:
+		return "PatchApplication"
+	case ErrorTypeHunkCountExceeded:
+		return "HunkCountExceeded"
+	case ErrorTypeStalePatch:
+		return "StalePatch"
+	case ErrorTypeBaseMismatch:
+		return "BaseMismatch"
+	default:
+		return "Unknown"
+	}
+}
+
 // StagerError represents a custom error with type classification
 type StagerError struct {
 	Type    ErrorType
@@ -140,6 +176,25 @@ func NewHunkCountExceededError(filePath string, maxHunks int, invalidHunks []int
 	return NewStagerError(ErrorTypeHunkCountExceeded, message, nil)
 }
 
+// NewStalePatchError creates an error for a patch file whose files changed in the working tree since it was generated
+func NewStalePatchError(files []string) *StagerError {
+	return NewStagerError(ErrorTypeStalePatch,
+		fmt.Sprintf("patch file is stale: the working tree changed since it was generated: %s", strings.Join(files, ", ")), nil)
+}
+
+// NewBaseMismatchError creates an error for a patch whose files do not match the base revision
+func NewBaseMismatchError(base string, files []string) *StagerError {
+	return NewStagerError(ErrorTypeBaseMismatch,
+		fmt.Sprintf("patch was not generated against base %s: %s", base, strings.Join(files, ", ")), nil)
+}
+
+// NewCommittedSinceBaseError creates an error for hunks whose lines were changed by the commits
+// between the base revision and HEAD, so they cannot be staged on top of HEAD
+func NewCommittedSinceBaseError(base string, hunks []string) *StagerError {
+	return NewStagerError(ErrorTypeBaseMismatch,
+		fmt.Sprintf("hunks overlap changes committed between base %s and HEAD, and the staging area starts at HEAD: %s", base, strings.Join(hunks, ", ")), nil)
+}
+
 // SafetyErrorType represents the type of safety-related error
 type SafetyErrorType int
 
@@ -225,3 +280,30 @@ func (t SafetyErrorType) String() string {
 		return "Unknown"
 	}
 }
+
+// VerificationError reports that the staging area does not contain exactly the
+// requested hunks after staging. Hunks are identified by their patch IDs.
+type VerificationError struct {
+	Missing []string // Requested hunks that are not in the staging area
+	Extra   []string // Staged hunks that were not requested
+}
+
+// NewVerificationError creates a new VerificationError
+func NewVerificationError(missing, extra []string) *VerificationError {
+	return &VerificationError{
+		Missing: missing,
+		Extra:   extra,
+	}
+}
+
+// Error returns the formatted error message
+func (e *VerificationError) Error() string {
+	var problems []string
+	if len(e.Missing) > 0 {
+		problems = append(problems, fmt.Sprintf("missing hunks with patch IDs [%s]", strings.Join(e.Missing, ", ")))
+	}
+	if len(e.Extra) > 0 {
+		problems = append(problems, fmt.Sprintf("unexpected hunks with patch IDs [%s]", strings.Join(e.Extra, ", ")))
+	}
+	return fmt.Sprintf("staged diff verification failed: %s", strings.Join(problems, "; "))
+}
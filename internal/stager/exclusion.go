@@ -0,0 +1,206 @@
+package stager
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// negationPrefix starts the hunk part of a specification that selects every hunk
+// of a file except the listed ones (e.g. "main.go:!4")
+const negationPrefix = "!"
+
+// IsNegatedSpec reports whether spec selects every hunk of a file except the listed ones
+func IsNegatedSpec(spec string) bool {
+	if IsIDSelector(spec) {
+		return false
+	}
+	_, hunksSpec, found := strings.Cut(spec, ":")
+	return found && strings.HasPrefix(hunksSpec, negationPrefix)
+}
+
+// HasNegatedSpecs reports whether any of the hunk specifications is negated
+func HasNegatedSpecs(hunkSpecs []string) bool {
+	for _, spec := range hunkSpecs {
+		if IsNegatedSpec(spec) {
+			return true
+		}
+	}
+	return false
+}
+
+// patchFileInfo is what ApplyExclusions needs to know about a file of the patch
+type patchFileInfo struct {
+	hunks    int  // Number of hunks in the file
+	isBinary bool // Binary files have a single hunk that can only be staged as a whole
+}
+
+// ApplyExclusions removes excluded hunks from the hunk specifications.
+// Exclusions come from excludeSpecs ("main.go:4", or "main.go:*" for a whole file) and
+// from negated specifications ("main.go:!4" selects every hunk of main.go except hunk 4).
+// If selectAll is set and hunkSpecs is empty, every file of the patch is selected.
+// A wildcard ("file:*") on a file with exclusions becomes the list of its remaining hunks;
+// binary files keep being staged as a whole, or are dropped if excluded.
+// "id:" specifications are kept as they are.
+func ApplyExclusions(hunkSpecs, excludeSpecs []string, selectAll bool, allHunks []HunkInfo) ([]string, error) {
+	files := make(map[string]*patchFileInfo)
+	var fileOrder []string
+	for _, hunk := range allHunks {
+		info, exists := files[hunk.FilePath]
+		if !exists {
+			info = &patchFileInfo{}
+			files[hunk.FilePath] = info
+			fileOrder = append(fileOrder, hunk.FilePath)
+		}
+		if hunk.IndexInFile > info.hunks {
+			info.hunks = hunk.IndexInFile
+		}
+		info.isBinary = info.isBinary || hunk.IsBinary
+	}
+
+	excluded := make(map[string]map[int]bool) // file -> excluded hunk numbers
+	addExclusion := func(filePath, hunksSpec string) error {
+		info, exists := files[filePath]
+		if !exists {
+			return NewHunkNotFoundError(fmt.Sprintf("file %s not found in patch", filePath), nil)
+		}
+		if excluded[filePath] == nil {
+			excluded[filePath] = make(map[int]bool)
+		}
+		if hunksSpec == "*" {
+			for n := 1; n <= info.hunks; n++ {
+				excluded[filePath][n] = true
+			}
+			return nil
+		}
+
+		spec := filePath + ":" + hunksSpec
+		_, selectors, err := ParseHunkSelectors(spec)
+		if err != nil {
+			return err
+		}
+		for _, selector := range selectors {
+			if selector.IsPartial() {
+				return NewInvalidArgumentError(fmt.Sprintf("line selection not supported here: %s", spec), nil)
+			}
+		}
+		selectors, err = ResolveHunkSelectors(filePath, selectors, info.hunks)
+		if err != nil {
+			return err
+		}
+		for _, selector := range selectors {
+			excluded[filePath][selector.Hunk] = true
+		}
+		return nil
+	}
+
+	for _, spec := range excludeSpecs {
+		filePath, hunksSpec, found := strings.Cut(spec, ":")
+		if !found {
+			return nil, NewInvalidArgumentError(fmt.Sprintf("invalid exclude specification: %s (expected format: file:hunks)", spec), nil)
+		}
+		if err := addExclusion(filePath, hunksSpec); err != nil {
+			return nil, err
+		}
+	}
+
+	// Negated specifications select the whole file minus their hunks
+	var selected []string
+	if selectAll && len(hunkSpecs) == 0 {
+		for _, filePath := range fileOrder {
+			selected = append(selected, filePath+":*")
+		}
+	}
+	for _, spec := range hunkSpecs {
+		if !IsNegatedSpec(spec) {
+			selected = append(selected, spec)
+			continue
+		}
+		filePath, hunksSpec, _ := strings.Cut(spec, ":")
+		if err := addExclusion(filePath, strings.TrimPrefix(hunksSpec, negationPrefix)); err != nil {
+			return nil, err
+		}
+		selected = append(selected, filePath+":*")
+	}
+
+	remaining := make([]string, 0, len(selected))
+	seen := make(map[string]bool)
+	for _, spec := range selected {
+		filePath, hunksSpec, found := strings.Cut(spec, ":")
+		if IsIDSelector(spec) || !found || len(excluded[filePath]) == 0 {
+			if !seen[spec] {
+				seen[spec] = true
+				remaining = append(remaining, spec)
+			}
+			continue
+		}
+
+		spec, ok, err := excludeFromSpec(filePath, hunksSpec, files[filePath], excluded[filePath])
+		if err != nil {
+			return nil, err
+		}
+		if ok && !seen[spec] {
+			seen[spec] = true
+			remaining = append(remaining, spec)
+		}
+	}
+
+	if len(remaining) == 0 && (len(hunkSpecs) > 0 || selectAll) {
+		return nil, NewInvalidArgumentError("every selected hunk is excluded", nil)
+	}
+	return remaining, nil
+}
+
+// excludeFromSpec removes the excluded hunk numbers from the hunk part of a specification.
+// It returns false if nothing is left to stage. Selections of file lines ("L40-L55") are kept.
+func excludeFromSpec(filePath, hunksSpec string, info *patchFileInfo, excluded map[int]bool) (string, bool, error) {
+	if hunksSpec == "*" {
+		if info.isBinary {
+			// A binary file is a single hunk; staging it is all or nothing
+			return "", false, nil
+		}
+		var numbers []string
+		for n := 1; n <= info.hunks; n++ {
+			if !excluded[n] {
+				numbers = append(numbers, strconv.Itoa(n))
+			}
+		}
+		if len(numbers) == 0 {
+			return "", false, nil
+		}
+		return filePath + ":" + strings.Join(numbers, ","), true, nil
+	}
+
+	items, err := splitSelectorItems(hunksSpec)
+	if err != nil {
+		return "", false, err
+	}
+	var kept []string
+	for _, item := range items {
+		_, selectors, err := ParseHunkSelectors(filePath + ":" + item)
+		if err != nil {
+			return "", false, err
+		}
+		if selectors[0].Span == nil {
+			if selectors[0].Hunk == 0 || !excluded[selectors[0].Hunk] {
+				kept = append(kept, item)
+			}
+			continue
+		}
+
+		// A range keeps only its hunks that are not excluded
+		selectors, err = ResolveHunkSelectors(filePath, selectors, info.hunks)
+		if err != nil {
+			return "", false, err
+		}
+		for _, selector := range selectors {
+			if !excluded[selector.Hunk] {
+				kept = append(kept, strconv.Itoa(selector.Hunk))
+			}
+		}
+	}
+	if len(kept) == 0 {
+		return "", false, nil
+	}
+	return filePath + ":" + strings.Join(kept, ","), true, nil
+}
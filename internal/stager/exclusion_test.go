@@ -0,0 +1,75 @@
+package stager
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestApplyExclusions(t *testing.T) {
+	allHunks := []HunkInfo{
+		{FilePath: "main.go", IndexInFile: 1},
+		{FilePath: "main.go", IndexInFile: 2},
+		{FilePath: "main.go", IndexInFile: 3},
+		{FilePath: "main.go", IndexInFile: 4},
+		{FilePath: "image.png", IndexInFile: 1, IsBinary: true},
+		{FilePath: "util.go", IndexInFile: 1},
+	}
+
+	tests := []struct {
+		name      string
+		hunkSpecs []string
+		excludes  []string
+		selectAll bool
+		want      []string
+	}{
+		{"negated spec", []string{"main.go:!4"}, nil, false, []string{"main.go:1,2,3"}},
+		{"negated spec with several hunks", []string{"main.go:!1,3", "util.go:1"}, nil, false, []string{"main.go:2,4", "util.go:1"}},
+		{"exclude from numbers", []string{"main.go:1,2,4[1-2]"}, []string{"main.go:2,4"}, false, []string{"main.go:1"}},
+		{"exclude keeps file line selections", []string{"main.go:L10-L20,3"}, []string{"main.go:3"}, false, []string{"main.go:L10-L20"}},
+		{"exclude from wildcard", []string{"main.go:*", "util.go:*"}, []string{"main.go:2"}, false, []string{"main.go:1,3,4", "util.go:*"}},
+		{"exclude everything else in the patch", nil, []string{"main.go:4"}, true, []string{"main.go:1,2,3", "image.png:*", "util.go:*"}},
+		{"exclude binary file", nil, []string{"image.png:1", "util.go:*"}, true, []string{"main.go:*"}},
+		{"id selectors are kept", []string{"id:3fa9c1d2"}, []string{"main.go:1"}, false, []string{"id:3fa9c1d2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyExclusions(tt.hunkSpecs, tt.excludes, tt.selectAll, allHunks)
+			if err != nil {
+				t.Fatalf("ApplyExclusions failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ApplyExclusions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyExclusions_Errors(t *testing.T) {
+	allHunks := []HunkInfo{
+		{FilePath: "main.go", IndexInFile: 1},
+		{FilePath: "main.go", IndexInFile: 2},
+	}
+
+	tests := []struct {
+		name      string
+		hunkSpecs []string
+		excludes  []string
+		wantErr   string
+	}{
+		{"everything excluded", []string{"main.go:!1,2"}, nil, "every selected hunk is excluded"},
+		{"unknown file", []string{"main.go:1"}, []string{"other.go:1"}, "file other.go not found in patch"},
+		{"hunk out of range", []string{"main.go:!3"}, nil, "but requested [3]"},
+		{"line selection in exclusion", []string{"main.go:1"}, []string{"main.go:2[1-2]"}, "line selection not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ApplyExclusions(tt.hunkSpecs, tt.excludes, false, allHunks)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
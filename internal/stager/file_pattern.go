@@ -0,0 +1,101 @@
+package stager
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// IsFilePattern reports whether the file part of a hunk specification is a glob
+// ("internal/stager/*.go", "docs/**") or a directory ("src/") rather than a literal path
+func IsFilePattern(filePath string) bool {
+	return strings.ContainsAny(filePath, "*?[") || strings.HasSuffix(filePath, "/")
+}
+
+// HasFilePatterns reports whether any of the hunk specifications uses a file pattern
+func HasFilePatterns(hunkSpecs []string) bool {
+	for _, spec := range hunkSpecs {
+		if IsIDSelector(spec) {
+			continue
+		}
+		if filePath, _, found := strings.Cut(spec, ":"); found && IsFilePattern(filePath) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandFilePatterns replaces every hunk specification whose file part is a pattern
+// with one specification per matching file of the patch, keeping the hunk part
+// ("src/:1" becomes "src/a.go:1", "src/b.go:1", ...).
+// Files are matched against the patch rather than the file system, so deleted files match too.
+// For a renamed file, "*" also selects the old path so that the removal is staged with it.
+func ExpandFilePatterns(hunkSpecs []string, allHunks []HunkInfo) ([]string, error) {
+	expanded := make([]string, 0, len(hunkSpecs))
+	for _, spec := range hunkSpecs {
+		filePath, hunksSpec, found := strings.Cut(spec, ":")
+		if IsIDSelector(spec) || !found || !IsFilePattern(filePath) {
+			expanded = append(expanded, spec)
+			continue
+		}
+
+		matched := false
+		seen := make(map[string]bool)
+		for _, hunk := range allHunks {
+			if seen[hunk.FilePath] || !matchFileGlob(filePath, hunk.FilePath) {
+				continue
+			}
+			seen[hunk.FilePath] = true
+			matched = true
+
+			expanded = append(expanded, hunk.FilePath+":"+hunksSpec)
+			if hunksSpec == "*" && hunk.File != nil && hunk.File.IsRename && hunk.OldFilePath != hunk.FilePath {
+				expanded = append(expanded, hunk.OldFilePath+":*")
+			}
+		}
+		if !matched {
+			return nil, NewHunkNotFoundError(fmt.Sprintf("files matching %s in patch", filePath), nil)
+		}
+	}
+	return expanded, nil
+}
+
+// matchFileGlob matches filePath against a glob.
+// A glob without a slash is matched against the file name only, so "*.go" matches "src/main.go".
+// Otherwise the glob is matched segment by segment: "**" matches any number of directories,
+// and a trailing slash matches everything below a directory ("src/" is the same as "src/**").
+func matchFileGlob(glob, filePath string) bool {
+	if strings.HasSuffix(glob, "/") {
+		glob += "**"
+	}
+	if !strings.Contains(glob, "/") {
+		matched, err := path.Match(glob, path.Base(filePath))
+		return err == nil && matched
+	}
+	return matchGlobSegments(strings.Split(glob, "/"), strings.Split(filePath, "/"))
+}
+
+// matchGlobSegments matches path segments against glob segments
+func matchGlobSegments(globSegments, pathSegments []string) bool {
+	if len(globSegments) == 0 {
+		return len(pathSegments) == 0
+	}
+
+	if globSegments[0] == "**" {
+		for i := 0; i <= len(pathSegments); i++ {
+			if matchGlobSegments(globSegments[1:], pathSegments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(pathSegments) == 0 {
+		return false
+	}
+	matched, err := path.Match(globSegments[0], pathSegments[0])
+	if err != nil || !matched {
+		return false
+	}
+	return matchGlobSegments(globSegments[1:], pathSegments[1:])
+}
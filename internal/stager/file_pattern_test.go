@@ -0,0 +1,103 @@
+package stager
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatchFileGlob(t *testing.T) {
+	tests := []struct {
+		glob     string
+		filePath string
+		want     bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "internal/stager/stager.go", true},
+		{"internal/*/*.go", "internal/stager/stager.go", true},
+		{"internal/*.go", "internal/stager/stager.go", false},
+		{"main.go", "cmd/main.go", true},
+		{"*.go", "README.md", false},
+		{"docs/**", "docs/guide/intro.md", true},
+		{"docs/**", "docs/index.md", true},
+		{"docs/**", "src/docs/index.md", false},
+		{"**/*_test.go", "main_test.go", true},
+		{"**/*_test.go", "internal/stager/stager_test.go", true},
+		{"internal/**/*.go", "internal/stager/stager.go", true},
+		{"src/", "src/a.go", true},
+		{"src/", "src/pkg/b.go", true},
+		{"src/", "srcx/a.go", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchFileGlob(tt.glob, tt.filePath); got != tt.want {
+			t.Errorf("matchFileGlob(%q, %q) = %v, want %v", tt.glob, tt.filePath, got, tt.want)
+		}
+	}
+}
+
+func TestExpandFilePatterns(t *testing.T) {
+	patch := `diff --git a/src/a.go b/src/a.go
+index 1234567..abcdefg 100644
+--- a/src/a.go
++++ b/src/a.go
+@@ -1,2 +1,3 @@
+ package src
++// a
+ 
+@@ -10,2 +11,3 @@
+ func a() {
++	// a
+ }
+diff --git a/src/old.go b/src/new.go
+similarity index 100%
+rename from src/old.go
+rename to src/new.go
+diff --git a/src/gone.go b/src/gone.go
+deleted file mode 100644
+index 1234567..0000000
+--- a/src/gone.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package src
+diff --git a/README.md b/README.md
+index 1234567..abcdefg 100644
+--- a/README.md
++++ b/README.md
+@@ -1 +1,2 @@
+ # title
++text
+`
+	allHunks, err := ParsePatchFileWithGitDiff(patch)
+	if err != nil {
+		t.Fatalf("Failed to parse patch: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		specs []string
+		want  []string
+	}{
+		{"literal paths are kept", []string{"src/a.go:1", "id:3fa9c1d2"}, []string{"src/a.go:1", "id:3fa9c1d2"}},
+		{"directory with hunk number", []string{"src/:1"}, []string{"src/a.go:1", "src/new.go:1", "src/gone.go:1"}},
+		{"glob wildcard includes rename source", []string{"src/*.go:*"}, []string{"src/a.go:*", "src/new.go:*", "src/old.go:*", "src/gone.go:*"}},
+		{"file name glob", []string{"*.md:*"}, []string{"README.md:*"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandFilePatterns(tt.specs, allHunks)
+			if err != nil {
+				t.Fatalf("ExpandFilePatterns failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExpandFilePatterns = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	_, err = ExpandFilePatterns([]string{"docs/**:*"}, allHunks)
+	if err == nil || !strings.Contains(err.Error(), "files matching docs/** in patch") {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package stager
+
+import (
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+// minFuzzyConfidence is the lowest similarity at which a current hunk is accepted
+// as the new version of a target hunk in fuzzy mode
+const minFuzzyConfidence = 0.6
+
+// matchFuzzyTargets finds the current version of every target whose patch ID is not
+// in the current diff anymore (e.g. because the working tree was edited after the patch
+// was generated). A current hunk is a candidate if it is in the same file, overlaps the
+// target's line range in the old file and is not claimed by another target; the candidate
+// with the most similar changed lines wins if its similarity reaches minFuzzyConfidence.
+// Matched targets are retargeted to the current hunk, which is then staged as it is now.
+// Targets with line selections are never matched, since the selected lines refer to the old hunk.
+func (s *Stager) matchFuzzyTargets(diff *currentDiff, targets []hunkTarget, result *StageResult) {
+	claimed := make(map[int]bool)
+	var unmatched []int
+	for i := range targets {
+		if h := diff.find(targets[i].PatchID, claimed); h >= 0 {
+			claimed[h] = true
+			continue
+		}
+		unmatched = append(unmatched, i)
+	}
+
+	for _, i := range unmatched {
+		target := &targets[i]
+		if target.Lines != nil || target.Hunk.Fragment == nil {
+			continue
+		}
+
+		best, bestConfidence := -1, 0.0
+		for h := range diff.hunks {
+			current := diff.hunks[h]
+			if claimed[h] || diff.used[h] || diff.patchIDs[h] == "" || current.Fragment == nil || current.FilePath != target.Hunk.FilePath {
+				continue
+			}
+			if !oldRangesOverlap(target.Hunk.Fragment, current.Fragment) {
+				continue
+			}
+			if confidence := changedLineSimilarity(target.Hunk.Fragment, current.Fragment); confidence > bestConfidence {
+				best, bestConfidence = h, confidence
+			}
+		}
+
+		if best < 0 || bestConfidence < minFuzzyConfidence {
+			s.logger.Debug("No fuzzy match for hunk %d of %s (best confidence %.2f)", target.Hunk.IndexInFile, target.Hunk.FilePath, bestConfidence)
+			continue
+		}
+
+		claimed[best] = true
+		s.logger.Info("Fuzzy match: hunk %d of %s (patch ID %s) is now %s (confidence %.2f)",
+			target.Hunk.IndexInFile, target.Hunk.FilePath, target.PatchID, diff.patchIDs[best], bestConfidence)
+		target.PatchID = diff.patchIDs[best]
+		result.markFuzzy(i, diff.patchIDs[best], bestConfidence)
+	}
+}
+
+// oldRangesOverlap reports whether two fragments touch overlapping lines of the old file.
+// A fragment without old lines counts as the line it is inserted before.
+func oldRangesOverlap(a, b *gitdiff.TextFragment) bool {
+	aStart, aEnd := oldRange(a)
+	bStart, bEnd := oldRange(b)
+	return aStart <= bEnd && bStart <= aEnd
+}
+
+// oldRange returns the first and last line of the old file covered by a fragment
+func oldRange(fragment *gitdiff.TextFragment) (int64, int64) {
+	if fragment.OldLines == 0 {
+		return fragment.OldPosition + 1, fragment.OldPosition + 1
+	}
+	return fragment.OldPosition, fragment.OldPosition + fragment.OldLines - 1
+}
+
+// changedLineSimilarity returns the Dice coefficient of the added and removed lines of two
+// fragments: 1 if they make the same changes, 0 if they have no changed line in common
+func changedLineSimilarity(a, b *gitdiff.TextFragment) float64 {
+	aLines := changedLines(a)
+	bLines := changedLines(b)
+	if len(aLines)+len(bLines) == 0 {
+		return 0
+	}
+
+	remaining := make(map[string]int, len(aLines))
+	for _, line := range aLines {
+		remaining[line]++
+	}
+	common := 0
+	for _, line := range bLines {
+		if remaining[line] > 0 {
+			remaining[line]--
+			common++
+		}
+	}
+
+	return float64(2*common) / float64(len(aLines)+len(bLines))
+}
+
+// changedLines returns the added and removed lines of a fragment, prefixed with their operation
+func changedLines(fragment *gitdiff.TextFragment) []string {
+	var lines []string
+	for _, line := range fragment.Lines {
+		if line.Op != gitdiff.OpContext {
+			lines = append(lines, line.Op.String()+line.Line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,111 @@
+package stager
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/internal/executor"
+	"github.com/syou6162/git-sequential-stage/internal/logger"
+)
+
+// editedThreeHunkDiff is threeHunkDiff after the second hunk was edited again
+const editedThreeHunkDiff = `diff --git a/file.txt b/file.txt
+index 1111111..3333333 100644
+--- a/file.txt
++++ b/file.txt
+@@ -1,3 +1,3 @@
+ line1
+-line2
++line2 changed
+ line3
+@@ -10,3 +10,4 @@
+ line10
+-line11
++line11 changed
++line11 added later
+ line12
+@@ -20,3 +21,3 @@
+ line20
+-line21
++line21 changed
+ line22
+`
+
+func TestChangedLineSimilarity(t *testing.T) {
+	original, err := ParsePatchFileWithGitDiff(threeHunkDiff)
+	if err != nil {
+		t.Fatalf("Failed to parse diff: %v", err)
+	}
+	edited, err := ParsePatchFileWithGitDiff(editedThreeHunkDiff)
+	if err != nil {
+		t.Fatalf("Failed to parse diff: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		a, b HunkInfo
+		want float64
+	}{
+		{"identical", original[0], edited[0], 1},
+		{"one added line", original[1], edited[1], 0.8},
+		{"unrelated", original[0], edited[2], 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := changedLineSimilarity(tt.a.Fragment, tt.b.Fragment)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("changedLineSimilarity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if oldRangesOverlap(original[0].Fragment, edited[1].Fragment) {
+		t.Error("Expected hunks 1 and 2 not to overlap")
+	}
+	if !oldRangesOverlap(original[1].Fragment, edited[1].Fragment) {
+		t.Error("Expected both versions of hunk 2 to overlap")
+	}
+}
+
+func TestStageTargets_FuzzyStagesCurrentVersion(t *testing.T) {
+	for _, fuzzy := range []bool{false, true} {
+		mock := executor.NewMockCommandExecutor()
+		mock.Commands["git [rev-parse --git-path index]"] = executor.MockResponse{Output: []byte(filepath.Join(t.TempDir(), "index") + "\n")}
+		mock.Commands["git [diff --end-of-options HEAD -- file.txt]"] = executor.MockResponse{Output: []byte(editedThreeHunkDiff)}
+		mock.Commands["git [apply --cached]"] = executor.MockResponse{}
+		s := &Stager{executor: mock, logger: logger.NewFromEnv(), options: Options{Fuzzy: fuzzy}}
+		ctx := context.Background()
+
+		allHunks, err := s.preparePatchData(ctx, threeHunkDiff)
+		if err != nil {
+			t.Fatalf("Failed to prepare patch data: %v", err)
+		}
+
+		result, err := s.stageTargets(ctx, []string{"file.txt:2"}, allHunks, map[string]bool{"file.txt": true})
+		if !fuzzy {
+			if err == nil || !strings.Contains(err.Error(), allHunks[1].PatchID) {
+				t.Errorf("Expected the edited hunk not to be found without fuzzy mode, got %v", err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("stageTargets with fuzzy mode failed: %v", err)
+		}
+		hunk := result.Hunks[0]
+		if hunk.Status != HunkStatusApplied || hunk.MatchedPatchID == "" || math.Abs(hunk.Confidence-0.8) > 1e-9 {
+			t.Errorf("Expected an applied fuzzy match with confidence 0.8, got %+v", hunk)
+		}
+		if hunk.PatchID != allHunks[1].PatchID {
+			t.Errorf("Expected the result to keep the requested patch ID %s, got %s", allHunks[1].PatchID, hunk.PatchID)
+		}
+
+		applied := string(mock.ExecutedCommands[len(mock.ExecutedCommands)-1].Stdin)
+		if !strings.Contains(applied, "+line11 added later") {
+			t.Errorf("Expected the current version of the hunk to be staged:\n%s", applied)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package stager
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/bluekeyes/go-gitdiff/gitdiff"
@@ -19,28 +20,326 @@ type HunkInfo struct {
 	File        *gitdiff.File         // Original file from go-gitdiff
 }
 
-// ParseHunkSpec parses a hunk specification like "file.go:1,3"
+// ParseHunkSpec parses a hunk specification like "file.go:1,3" or "file.go:2-5".
+// Specifications that select individual lines are rejected; use ParseHunkSelectors for those.
+// Ranges relative to the last hunk ("3-", "last") are rejected as well since they
+// depend on the number of hunks in the file; use ResolveHunkSelectors for those.
 func ParseHunkSpec(spec string) (filePath string, hunkNumbers []int, err error) {
+	filePath, selectors, err := ParseHunkSelectors(spec)
+	if err != nil {
+		return "", nil, err
+	}
+
+	for _, selector := range selectors {
+		if selector.IsPartial() {
+			return "", nil, NewInvalidArgumentError(fmt.Sprintf("line selection not supported here: %s", spec), nil)
+		}
+		if selector.Span != nil {
+			if selector.Span.From.FromEnd || selector.Span.To.FromEnd {
+				return "", nil, NewInvalidArgumentError(fmt.Sprintf("hunk numbers relative to the last hunk not supported here: %s", spec), nil)
+			}
+			for n := selector.Span.From.Number; n <= selector.Span.To.Number; n++ {
+				hunkNumbers = append(hunkNumbers, n)
+			}
+			continue
+		}
+		hunkNumbers = append(hunkNumbers, selector.Hunk)
+	}
+
+	return filePath, hunkNumbers, nil
+}
+
+// LineRange is an inclusive range of line numbers
+type LineRange struct {
+	Start int
+	End   int
+}
+
+// Contains reports whether n is within the range
+func (r LineRange) Contains(n int) bool {
+	return n >= r.Start && n <= r.End
+}
+
+// HunkRef is a hunk number that may count back from the last hunk of the file
+type HunkRef struct {
+	Number  int  // Hunk number (1, 2, 3, ...), or the distance from the last hunk if FromEnd
+	FromEnd bool // "last" is {0, true} and "last-2" is {2, true}
+}
+
+// Resolve returns the hunk number for a file with maxHunks hunks
+func (r HunkRef) Resolve(maxHunks int) int {
+	if r.FromEnd {
+		return maxHunks - r.Number
+	}
+	return r.Number
+}
+
+// String returns the reference as written in a hunk specification
+func (r HunkRef) String() string {
+	switch {
+	case !r.FromEnd:
+		return strconv.Itoa(r.Number)
+	case r.Number == 0:
+		return "last"
+	default:
+		return fmt.Sprintf("last-%d", r.Number)
+	}
+}
+
+// HunkSpan is an inclusive range of hunks ("2-5", "3-", "last")
+type HunkSpan struct {
+	From HunkRef
+	To   HunkRef
+}
+
+// HunkSelector selects a hunk, or some of its lines, from a hunk specification.
+// Exactly one of Hunk, Span and FileLines is set.
+type HunkSelector struct {
+	Hunk      int         // Hunk number within the file (1, 2, 3, ...)
+	Lines     []LineRange // Lines of the hunk body to stage (1-based); empty stages the whole hunk
+	FileLines []LineRange // Line numbers in the file (L40-L55); selects changed lines across hunks
+	Span      *HunkSpan   // Range of whole hunks; resolved with ResolveHunkSelectors
+}
+
+// IsPartial reports whether the selector stages only some lines of a hunk
+func (sel HunkSelector) IsPartial() bool {
+	return len(sel.Lines) > 0 || len(sel.FileLines) > 0
+}
+
+// ParseHunkSelectors parses a hunk specification that may select individual lines.
+// In addition to plain hunk numbers ("file.go:1,3") it accepts:
+//   - "file.go:2-5"       - hunks 2 to 5; "2-" runs to the last hunk
+//   - "file.go:last"      - the last hunk ("-1" is the same); "last-1" is the one before it
+//   - "file.go:2[3-7,10]" - lines 3 to 7 and line 10 of the body of hunk 2
+//   - "file.go:L40-L55"   - changed lines 40 to 55 of the file, in any hunk
+func ParseHunkSelectors(spec string) (filePath string, selectors []HunkSelector, err error) {
 	parts := strings.SplitN(spec, ":", 2)
 	if len(parts) != 2 {
 		return "", nil, NewInvalidArgumentError(fmt.Sprintf("invalid hunk spec format: %s (expected file:numbers)", spec), nil)
 	}
 
 	filePath = parts[0]
-	numbersPart := parts[1]
+	items, err := splitSelectorItems(parts[1])
+	if err != nil {
+		return "", nil, err
+	}
+
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+
+		switch {
+		case strings.HasPrefix(item, "L"):
+			lineRange, err := parseFileLineRange(item)
+			if err != nil {
+				return "", nil, err
+			}
+			selectors = append(selectors, HunkSelector{FileLines: []LineRange{lineRange}})
+
+		case strings.Contains(item, "["):
+			selector, err := parseHunkLineSelector(item)
+			if err != nil {
+				return "", nil, err
+			}
+			selectors = append(selectors, selector)
+
+		default:
+			selector, err := parseHunkItem(item)
+			if err != nil {
+				return "", nil, err
+			}
+			selectors = append(selectors, selector)
+		}
+	}
 
-	// Parse comma-separated numbers
-	for _, numStr := range strings.Split(numbersPart, ",") {
-		numStr = strings.TrimSpace(numStr)
-		var num int
-		if _, err := fmt.Sscanf(numStr, "%d", &num); err != nil {
-			return "", nil, NewInvalidArgumentError(fmt.Sprintf("invalid hunk number: %s", numStr), err)
+	return filePath, selectors, nil
+}
+
+// parseHunkItem parses a hunk number ("3"), a range ("2-5", "2-", "2-last") or a hunk
+// counted from the end of the file ("last", "last-1", "-1")
+func parseHunkItem(item string) (HunkSelector, error) {
+	if item == "-1" || strings.HasPrefix(item, "last") {
+		ref, err := parseHunkRef(item)
+		if err != nil {
+			return HunkSelector{}, err
+		}
+		return HunkSelector{Span: &HunkSpan{From: ref, To: ref}}, nil
+	}
+
+	// A leading "-" is a negative number, not a range
+	if i := strings.Index(item, "-"); i > 0 {
+		from, err := parseHunkRef(item[:i])
+		if err != nil {
+			return HunkSelector{}, err
+		}
+		to := HunkRef{FromEnd: true} // "N-" runs to the last hunk
+		if end := item[i+1:]; end != "" {
+			if to, err = parseHunkRef(end); err != nil {
+				return HunkSelector{}, err
+			}
 		}
-		if num <= 0 {
-			return "", nil, NewInvalidArgumentError(fmt.Sprintf("hunk number must be positive: %d", num), nil)
+		if !to.FromEnd && to.Number < from.Number {
+			return HunkSelector{}, NewInvalidArgumentError(fmt.Sprintf("invalid hunk range: %s (end before start)", item), nil)
 		}
-		hunkNumbers = append(hunkNumbers, num)
+		return HunkSelector{Span: &HunkSpan{From: from, To: to}}, nil
 	}
 
-	return filePath, hunkNumbers, nil
+	num, err := parseHunkNumber(item)
+	if err != nil {
+		return HunkSelector{}, err
+	}
+	return HunkSelector{Hunk: num}, nil
+}
+
+// parseHunkRef parses a hunk number or "last", "last-K" and "-1"
+func parseHunkRef(s string) (HunkRef, error) {
+	if s == "-1" || s == "last" {
+		return HunkRef{FromEnd: true}, nil
+	}
+	if back, found := strings.CutPrefix(s, "last-"); found {
+		n, err := strconv.Atoi(back)
+		if err != nil || n < 0 {
+			return HunkRef{}, NewInvalidArgumentError(fmt.Sprintf("invalid hunk number: %s", s), err)
+		}
+		return HunkRef{Number: n, FromEnd: true}, nil
+	}
+
+	num, err := parseHunkNumber(s)
+	if err != nil {
+		return HunkRef{}, err
+	}
+	return HunkRef{Number: num}, nil
+}
+
+// ResolveHunkSelectors replaces ranges and hunks counted from the end of the file with
+// one selector per hunk, for a file with maxHunks hunks. Hunk numbers beyond the last
+// hunk are reported with NewHunkCountExceededError.
+func ResolveHunkSelectors(filePath string, selectors []HunkSelector, maxHunks int) ([]HunkSelector, error) {
+	var resolved []HunkSelector
+	var invalidHunks []int
+	for _, selector := range selectors {
+		if selector.Span == nil {
+			if selector.Hunk > maxHunks {
+				invalidHunks = append(invalidHunks, selector.Hunk)
+			}
+			resolved = append(resolved, selector)
+			continue
+		}
+
+		from, to := selector.Span.From.Resolve(maxHunks), selector.Span.To.Resolve(maxHunks)
+		for _, ref := range []HunkRef{selector.Span.From, selector.Span.To} {
+			if ref.FromEnd && ref.Resolve(maxHunks) < 1 {
+				return nil, NewInvalidArgumentError(fmt.Sprintf("%s is before the first hunk: %s has %d hunks", ref, filePath, maxHunks), nil)
+			}
+		}
+		if to < from {
+			return nil, NewInvalidArgumentError(fmt.Sprintf("invalid hunk range: %s-%s (end before start in %s)", selector.Span.From, selector.Span.To, filePath), nil)
+		}
+		if to > maxHunks {
+			// Report the end of the range rather than every missing hunk
+			invalidHunks = append(invalidHunks, to)
+			to = maxHunks
+		}
+		for n := from; n <= to; n++ {
+			resolved = append(resolved, HunkSelector{Hunk: n})
+		}
+	}
+
+	if len(invalidHunks) > 0 {
+		return nil, NewHunkCountExceededError(filePath, maxHunks, invalidHunks)
+	}
+	return resolved, nil
+}
+
+// splitSelectorItems splits on commas that are not inside brackets
+func splitSelectorItems(s string) ([]string, error) {
+	var items []string
+	depth := 0
+	start := 0
+	for i, c := range s {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth < 0 {
+				return nil, NewInvalidArgumentError(fmt.Sprintf("unbalanced brackets in hunk spec: %s", s), nil)
+			}
+		case ',':
+			if depth == 0 {
+				items = append(items, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, NewInvalidArgumentError(fmt.Sprintf("unbalanced brackets in hunk spec: %s", s), nil)
+	}
+	return append(items, s[start:]), nil
+}
+
+// parseHunkNumber parses a single positive hunk number
+func parseHunkNumber(numStr string) (int, error) {
+	var num int
+	if _, err := fmt.Sscanf(numStr, "%d", &num); err != nil {
+		return 0, NewInvalidArgumentError(fmt.Sprintf("invalid hunk number: %s", numStr), err)
+	}
+	if num <= 0 {
+		return 0, NewInvalidArgumentError(fmt.Sprintf("hunk number must be positive: %d", num), nil)
+	}
+	return num, nil
+}
+
+// parseHunkLineSelector parses "N[a-b,c]"
+func parseHunkLineSelector(item string) (HunkSelector, error) {
+	open := strings.Index(item, "[")
+	if !strings.HasSuffix(item, "]") {
+		return HunkSelector{}, NewInvalidArgumentError(fmt.Sprintf("invalid line selection: %s (expected N[a-b,c])", item), nil)
+	}
+
+	num, err := strconv.Atoi(strings.TrimSpace(item[:open]))
+	if err != nil {
+		return HunkSelector{}, NewInvalidArgumentError(fmt.Sprintf("invalid hunk number: %s", item[:open]), err)
+	}
+	if num <= 0 {
+		return HunkSelector{}, NewInvalidArgumentError(fmt.Sprintf("hunk number must be positive: %d", num), nil)
+	}
+
+	selector := HunkSelector{Hunk: num}
+	for _, part := range strings.Split(item[open+1:len(item)-1], ",") {
+		lineRange, err := parseLineRange(strings.TrimSpace(part), "")
+		if err != nil {
+			return HunkSelector{}, err
+		}
+		selector.Lines = append(selector.Lines, lineRange)
+	}
+
+	return selector, nil
+}
+
+// parseFileLineRange parses "L40-L55", "L40-55" or "L40"
+func parseFileLineRange(item string) (LineRange, error) {
+	return parseLineRange(strings.TrimPrefix(item, "L"), "L")
+}
+
+// parseLineRange parses "a-b" or "a" into a LineRange.
+// prefix is an optional marker allowed in front of the end of the range.
+func parseLineRange(s, prefix string) (LineRange, error) {
+	startStr, endStr, isRange := strings.Cut(s, "-")
+	if isRange && prefix != "" {
+		endStr = strings.TrimPrefix(endStr, prefix)
+	}
+
+	start, err := strconv.Atoi(strings.TrimSpace(startStr))
+	if err != nil || start <= 0 {
+		return LineRange{}, NewInvalidArgumentError(fmt.Sprintf("invalid line range: %s%s", prefix, s), err)
+	}
+	end := start
+	if isRange {
+		end, err = strconv.Atoi(strings.TrimSpace(endStr))
+		if err != nil || end < start {
+			return LineRange{}, NewInvalidArgumentError(fmt.Sprintf("invalid line range: %s%s", prefix, s), err)
+		}
+	}
+
+	return LineRange{Start: start, End: end}, nil
 }
@@ -0,0 +1,95 @@
+package stager
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveHunkSelectors(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []int
+	}{
+		{"main.go:2-4", []int{2, 3, 4}},
+		{"main.go:3-", []int{3, 4, 5}},
+		{"main.go:2-last-2", []int{2, 3}},
+		{"main.go:last", []int{5}},
+		{"main.go:-1", []int{5}},
+		{"main.go:last-1,1", []int{4, 1}},
+		{"main.go:1,4-4", []int{1, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			filePath, selectors, err := ParseHunkSelectors(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseHunkSelectors failed: %v", err)
+			}
+			resolved, err := ResolveHunkSelectors(filePath, selectors, 5)
+			if err != nil {
+				t.Fatalf("ResolveHunkSelectors failed: %v", err)
+			}
+			var got []int
+			for _, selector := range resolved {
+				got = append(got, selector.Hunk)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolved hunks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveHunkSelectors_Errors(t *testing.T) {
+	tests := []struct {
+		spec     string
+		wantType ErrorType
+		wantErr  string
+	}{
+		{"main.go:2-7", ErrorTypeHunkCountExceeded, "main.go has 5 hunks (valid: 1-5) but requested [7]"},
+		{"main.go:6,3-", ErrorTypeHunkCountExceeded, "main.go has 5 hunks (valid: 1-5) but requested [6]"},
+		{"main.go:last-5", ErrorTypeInvalidArgument, "last-5 is before the first hunk: main.go has 5 hunks"},
+		{"main.go:4-last-3", ErrorTypeInvalidArgument, "invalid hunk range: 4-last-3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			filePath, selectors, err := ParseHunkSelectors(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseHunkSelectors failed: %v", err)
+			}
+			_, err = ResolveHunkSelectors(filePath, selectors, 5)
+			var stagerErr *StagerError
+			if !errors.As(err, &stagerErr) || stagerErr.Type != tt.wantType {
+				t.Fatalf("Expected %s error, got %v", tt.wantType, err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %q", tt.wantErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestParseHunkSelectors_InvalidRanges(t *testing.T) {
+	for _, spec := range []string{"main.go:5-2", "main.go:0-2", "main.go:a-3", "main.go:lastx", "main.go:-2"} {
+		if _, _, err := ParseHunkSelectors(spec); err == nil {
+			t.Errorf("ParseHunkSelectors(%q) should fail", spec)
+		}
+	}
+}
+
+func TestParseHunkSpec_Ranges(t *testing.T) {
+	_, numbers, err := ParseHunkSpec("main.go:1,3-5")
+	if err != nil {
+		t.Fatalf("ParseHunkSpec failed: %v", err)
+	}
+	if !reflect.DeepEqual(numbers, []int{1, 3, 4, 5}) {
+		t.Errorf("numbers = %v, want [1 3 4 5]", numbers)
+	}
+
+	if _, _, err := ParseHunkSpec("main.go:3-"); err == nil {
+		t.Error("Expected an error for an open-ended range")
+	}
+}
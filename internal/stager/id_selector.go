@@ -0,0 +1,104 @@
+package stager
+
+import (
+	"fmt"
+	"strings"
+)
+
+// idSelectorPrefix starts a hunk specification that selects hunks by patch ID
+// instead of by position (e.g. "id:3fa9c1d2" or "id:3fa9c1d2,88aa01bc")
+const idSelectorPrefix = "id:"
+
+// minPatchIDPrefixLength is the shortest patch ID prefix accepted by an "id:" selector
+const minPatchIDPrefixLength = 4
+
+// IsIDSelector reports whether spec selects hunks by patch ID.
+// Every comma-separated item must be a hex patch ID prefix of at least
+// minPatchIDPrefixLength characters, so "id:1,3" still means hunks 1 and 3 of a file named "id".
+func IsIDSelector(spec string) bool {
+	_, ok := parseIDSelector(spec)
+	return ok
+}
+
+// parseIDSelector returns the patch ID prefixes of an "id:" specification
+func parseIDSelector(spec string) ([]string, bool) {
+	if !strings.HasPrefix(spec, idSelectorPrefix) {
+		return nil, false
+	}
+
+	var ids []string
+	for _, item := range strings.Split(strings.TrimPrefix(spec, idSelectorPrefix), ",") {
+		id := strings.ToLower(strings.TrimSpace(item))
+		if len(id) < minPatchIDPrefixLength || !isHex(id) {
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
+// isHex reports whether s consists of hexadecimal digits only
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// hasIDSelectors reports whether any of the hunk specifications selects hunks by patch ID
+func hasIDSelectors(hunkSpecs []string) bool {
+	for _, spec := range hunkSpecs {
+		if IsIDSelector(spec) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveIDSelectors replaces "id:" specifications with "file:N" specifications
+// of the hunks they identify in allHunks. Other specifications are kept as they are.
+func resolveIDSelectors(hunkSpecs []string, allHunks []HunkInfo) ([]string, error) {
+	resolved := make([]string, 0, len(hunkSpecs))
+	for _, spec := range hunkSpecs {
+		ids, ok := parseIDSelector(spec)
+		if !ok {
+			resolved = append(resolved, spec)
+			continue
+		}
+
+		for _, id := range ids {
+			hunk, err := findHunkByPatchID(id, allHunks)
+			if err != nil {
+				return nil, err
+			}
+			resolved = append(resolved, fmt.Sprintf("%s:%d", hunk.FilePath, hunk.IndexInFile))
+		}
+	}
+	return resolved, nil
+}
+
+// findHunkByPatchID returns the only hunk whose patch ID starts with the given prefix
+func findHunkByPatchID(prefix string, allHunks []HunkInfo) (HunkInfo, error) {
+	var matches []HunkInfo
+	for _, hunk := range allHunks {
+		if strings.HasPrefix(hunk.PatchID, prefix) {
+			matches = append(matches, hunk)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return HunkInfo{}, NewHunkNotFoundError(fmt.Sprintf("hunk with patch ID %s in patch", prefix), nil)
+	case 1:
+		return matches[0], nil
+	}
+
+	candidates := make([]string, len(matches))
+	for i, hunk := range matches {
+		candidates[i] = fmt.Sprintf("%s:%d", hunk.FilePath, hunk.IndexInFile)
+	}
+	return HunkInfo{}, NewInvalidArgumentError(fmt.Sprintf("patch ID %s is ambiguous: it matches %s", prefix, strings.Join(candidates, ", ")), nil)
+}
@@ -0,0 +1,62 @@
+package stager
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIsIDSelector(t *testing.T) {
+	tests := []struct {
+		spec string
+		want bool
+	}{
+		{"id:3fa9c1d2", true},
+		{"id:3FA9", true},
+		{"id:3fa9c1d2,88aa01bc", true},
+		{"id:3fa", false},        // Too short to be a patch ID prefix
+		{"id:1,3", false},        // Hunks 1 and 3 of a file named "id"
+		{"id:3fa9c1d2,1", false}, // Every item must be a patch ID prefix
+		{"id:zzzzzzzz", false},
+		{"main.go:1", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsIDSelector(tt.spec); got != tt.want {
+			t.Errorf("IsIDSelector(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestResolveIDSelectors(t *testing.T) {
+	allHunks := []HunkInfo{
+		{FilePath: "a.go", IndexInFile: 1, PatchID: "3fa9c1d2"},
+		{FilePath: "a.go", IndexInFile: 2, PatchID: "88aa01bc"},
+		{FilePath: "b.go", IndexInFile: 1, PatchID: "88ab0000"},
+		{FilePath: "c.go", IndexInFile: 1, PatchID: "unknown-4"},
+	}
+
+	resolved, err := resolveIDSelectors([]string{"id:88aa,3FA9C1D2", "b.go:1"}, allHunks)
+	if err != nil {
+		t.Fatalf("resolveIDSelectors failed: %v", err)
+	}
+	want := []string{"a.go:2", "a.go:1", "b.go:1"}
+	if !reflect.DeepEqual(resolved, want) {
+		t.Errorf("resolveIDSelectors = %v, want %v", resolved, want)
+	}
+
+	_, err = resolveIDSelectors([]string{"id:88a0"}, allHunks)
+	if err == nil || !strings.Contains(err.Error(), "patch ID 88a0") {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+
+	_, err = resolveIDSelectors([]string{"id:88a"}, allHunks)
+	if err != nil {
+		t.Errorf("Expected short id to be kept as a file spec, got %v", err)
+	}
+
+	_, err = resolveIDSelectors([]string{"id:88aa0"}, append(allHunks, HunkInfo{FilePath: "d.go", IndexInFile: 1, PatchID: "88aa0123"}))
+	if err == nil || !strings.Contains(err.Error(), "ambiguous: it matches a.go:2, d.go:1") {
+		t.Errorf("Expected ambiguous error, got %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+package stager
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+// IndexSnapshot is a byte-for-byte copy of the Git index file.
+// Copying the file (instead of using git write-tree) preserves
+// intent-to-add entries and any other index-only state.
+// Working tree files that the stager writes after the snapshot was taken
+// (see Options.AllowWorktreeWrite) are saved as well, so Restore undoes them too.
+type IndexSnapshot struct {
+	path    string                   // Absolute path of the index file
+	content []byte                   // Index content at the time of the snapshot
+	mode    os.FileMode              // File mode of the index file
+	exists  bool                     // False if there was no index file yet (e.g. a fresh repository)
+	files   map[string]*worktreeFile // Working tree files written since the snapshot, by absolute path
+}
+
+// worktreeFile is a working tree file as it was before the stager first wrote it
+type worktreeFile struct {
+	content []byte      // File content
+	mode    os.FileMode // File mode
+	link    string      // Link text if the file was a symbolic link
+	exists  bool        // False if the file did not exist
+}
+
+// SnapshotIndex takes a snapshot of the current index.
+// The index location is resolved with "git rev-parse --git-path index",
+// so GIT_INDEX_FILE and worktrees are honored.
+func (s *Stager) SnapshotIndex(ctx context.Context) (*IndexSnapshot, error) {
+	output, err := s.executor.Execute(ctx, "git", "rev-parse", "--git-path", "index")
+	if err != nil {
+		return nil, NewGitCommandError("git rev-parse --git-path index", err)
+	}
+
+	path, err := filepath.Abs(strings.TrimSpace(string(output)))
+	if err != nil {
+		return nil, NewIOError("resolve index path", err)
+	}
+
+	snap := &IndexSnapshot{path: path}
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, NewIOError("stat index", err)
+	default:
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, NewIOError("read index", err)
+		}
+		snap.content, snap.mode, snap.exists = content, info.Mode().Perm(), true
+	}
+
+	s.snapshots = append(s.snapshots, snap)
+	return snap, nil
+}
+
+// saveWorktreeFiles saves the working tree files that a patch is about to write in every
+// snapshot taken so far, so that restoring any of them also undoes the write
+func (s *Stager) saveWorktreeFiles(ctx context.Context, patchContent []byte) error {
+	if len(s.snapshots) == 0 {
+		return nil
+	}
+
+	files, _, err := gitdiff.Parse(bytes.NewReader(patchContent))
+	if err != nil {
+		return NewParsingError("patch for the working tree", err)
+	}
+
+	output, err := s.executor.Execute(ctx, "git", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return NewGitCommandError("git rev-parse --show-toplevel", err)
+	}
+	root := strings.TrimSpace(string(output))
+
+	for _, file := range files {
+		for _, name := range []string{file.OldName, file.NewName} {
+			if name == "" {
+				continue
+			}
+			for _, snap := range s.snapshots {
+				if err := snap.saveWorktreeFile(filepath.Join(root, name)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// saveWorktreeFile saves the current state of a working tree file unless it was saved before
+func (snap *IndexSnapshot) saveWorktreeFile(path string) error {
+	if _, ok := snap.files[path]; ok {
+		return nil
+	}
+
+	saved := &worktreeFile{}
+	info, err := os.Lstat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return NewIOError(fmt.Sprintf("stat %s", path), err)
+	case info.Mode()&os.ModeSymlink != 0:
+		link, err := os.Readlink(path)
+		if err != nil {
+			return NewIOError(fmt.Sprintf("read link %s", path), err)
+		}
+		saved.link, saved.exists = link, true
+	default:
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return NewIOError(fmt.Sprintf("read %s", path), err)
+		}
+		saved.content, saved.mode, saved.exists = content, info.Mode().Perm(), true
+	}
+
+	if snap.files == nil {
+		snap.files = make(map[string]*worktreeFile)
+	}
+	snap.files[path] = saved
+	return nil
+}
+
+// restore puts a saved working tree file back in place
+func (f *worktreeFile) restore(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return NewIOError(fmt.Sprintf("remove %s", path), err)
+	}
+	switch {
+	case !f.exists:
+		return nil
+	case f.link != "":
+		if err := os.Symlink(f.link, path); err != nil {
+			return NewIOError(fmt.Sprintf("restore link %s", path), err)
+		}
+	default:
+		if err := os.WriteFile(path, f.content, f.mode); err != nil {
+			return NewIOError(fmt.Sprintf("restore %s", path), err)
+		}
+	}
+	return nil
+}
+
+// Restore puts the saved working tree files back and writes the snapshot back to the index file.
+// The index content is written to a temporary file and renamed into place
+// so that a failure cannot leave a truncated index behind.
+func (snap *IndexSnapshot) Restore() error {
+	for path, file := range snap.files {
+		if err := file.restore(path); err != nil {
+			return err
+		}
+	}
+
+	if !snap.exists {
+		if err := os.Remove(snap.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return NewIOError("remove index", err)
+		}
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(snap.path), "index.sequential-stage-*")
+	if err != nil {
+		return NewIOError("create temporary index", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // No-op after a successful rename
+
+	if _, err := tmp.Write(snap.content); err != nil {
+		tmp.Close()
+		return NewIOError("write temporary index", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return NewIOError("close temporary index", err)
+	}
+	if err := os.Chmod(tmpPath, snap.mode); err != nil {
+		return NewIOError("chmod temporary index", err)
+	}
+	if err := os.Rename(tmpPath, snap.path); err != nil {
+		return NewIOError(fmt.Sprintf("restore index %s", snap.path), err)
+	}
+
+	return nil
+}
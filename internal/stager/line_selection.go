@@ -0,0 +1,145 @@
+package stager
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+// selectHunkLines returns the changed lines of the fragment body that fall in the given ranges.
+// Line numbers are 1-based positions in the hunk body (the lines after the @@ header).
+func selectHunkLines(fragment *gitdiff.TextFragment, ranges []LineRange) ([]int, error) {
+	var selected []int
+	for _, r := range ranges {
+		if r.End > len(fragment.Lines) {
+			return nil, NewInvalidArgumentError(fmt.Sprintf("line range %d-%d exceeds hunk body of %d lines", r.Start, r.End, len(fragment.Lines)), nil)
+		}
+		for n := r.Start; n <= r.End; n++ {
+			if fragment.Lines[n-1].Op != gitdiff.OpContext {
+				selected = append(selected, n)
+			}
+		}
+	}
+	return mergeSelectedLines(selected, nil, false), nil
+}
+
+// selectFileLines returns the changed lines of the fragment body whose line number in the file
+// falls in the given ranges. Added lines are matched by their new line number and
+// deleted lines by their old line number.
+func selectFileLines(fragment *gitdiff.TextFragment, ranges []LineRange) []int {
+	var selected []int
+	oldLine := fragment.OldPosition
+	newLine := fragment.NewPosition
+	for i, line := range fragment.Lines {
+		switch line.Op {
+		case gitdiff.OpContext:
+			oldLine++
+			newLine++
+		case gitdiff.OpDelete:
+			if inLineRanges(ranges, oldLine) {
+				selected = append(selected, i+1)
+			}
+			oldLine++
+		case gitdiff.OpAdd:
+			if inLineRanges(ranges, newLine) {
+				selected = append(selected, i+1)
+			}
+			newLine++
+		}
+	}
+	return selected
+}
+
+// inLineRanges reports whether n is within any of the ranges
+func inLineRanges(ranges []LineRange, n int64) bool {
+	for _, r := range ranges {
+		if r.Contains(int(n)) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectsAllChanges reports whether lines covers every added and deleted line of the fragment
+func selectsAllChanges(fragment *gitdiff.TextFragment, lines []int) bool {
+	return int64(len(lines)) == fragment.LinesAdded+fragment.LinesDeleted
+}
+
+// mergeSelectedLines returns the sorted union of two line selections.
+// A nil selection stands for the whole hunk, so if whole is true the result is nil.
+func mergeSelectedLines(a, b []int, whole bool) []int {
+	if whole {
+		return nil
+	}
+	seen := make(map[int]bool, len(a)+len(b))
+	var merged []int
+	for _, n := range append(append([]int{}, a...), b...) {
+		if !seen[n] {
+			seen[n] = true
+			merged = append(merged, n)
+		}
+	}
+	sort.Ints(merged)
+	return merged
+}
+
+// reduceFragment builds a fragment that contains only the selected changes of the original.
+// Unselected deletions are turned into context and unselected additions are dropped,
+// the same way "git add -p" edits a hunk.
+func reduceFragment(fragment *gitdiff.TextFragment, lines []int) (*gitdiff.TextFragment, error) {
+	selected := make(map[int]bool, len(lines))
+	for _, n := range lines {
+		selected[n] = true
+	}
+
+	reduced := &gitdiff.TextFragment{
+		Comment:     fragment.Comment,
+		OldPosition: fragment.OldPosition,
+		NewPosition: fragment.NewPosition,
+	}
+
+	for i, line := range fragment.Lines {
+		switch {
+		case line.Op == gitdiff.OpContext:
+			reduced.Lines = append(reduced.Lines, line)
+		case selected[i+1]:
+			reduced.Lines = append(reduced.Lines, line)
+		case line.Op == gitdiff.OpDelete:
+			reduced.Lines = append(reduced.Lines, gitdiff.Line{Op: gitdiff.OpContext, Line: line.Line})
+		}
+		// Unselected additions are dropped
+	}
+
+	// Recount the header values from the remaining lines
+	for _, line := range reduced.Lines {
+		switch line.Op {
+		case gitdiff.OpContext:
+			reduced.OldLines++
+			reduced.NewLines++
+			if reduced.LinesAdded == 0 && reduced.LinesDeleted == 0 {
+				reduced.LeadingContext++
+			} else {
+				reduced.TrailingContext++
+			}
+		case gitdiff.OpAdd:
+			reduced.NewLines++
+			reduced.LinesAdded++
+			reduced.TrailingContext = 0
+		case gitdiff.OpDelete:
+			reduced.OldLines++
+			reduced.LinesDeleted++
+			reduced.TrailingContext = 0
+		}
+	}
+
+	if reduced.LinesAdded == 0 && reduced.LinesDeleted == 0 {
+		return nil, NewInvalidArgumentError("no changed lines selected", nil)
+	}
+
+	if err := reduced.Validate(); err != nil {
+		return nil, NewParsingError("reduced hunk", err)
+	}
+
+	return reduced, nil
+}
@@ -0,0 +1,202 @@
+package stager
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+func TestParseHunkSelectors(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		wantFile string
+		want     []HunkSelector
+		wantErr  string
+	}{
+		{
+			name:     "plain hunk numbers",
+			spec:     "main.go:1,3",
+			wantFile: "main.go",
+			want:     []HunkSelector{{Hunk: 1}, {Hunk: 3}},
+		},
+		{
+			name:     "hunk body lines",
+			spec:     "main.go:2[3-7,10]",
+			wantFile: "main.go",
+			want:     []HunkSelector{{Hunk: 2, Lines: []LineRange{{3, 7}, {10, 10}}}},
+		},
+		{
+			name:     "file line range",
+			spec:     "main.go:L40-L55",
+			wantFile: "main.go",
+			want:     []HunkSelector{{FileLines: []LineRange{{40, 55}}}},
+		},
+		{
+			name:     "mixed selectors",
+			spec:     "main.go:1,2[4],L9-12",
+			wantFile: "main.go",
+			want: []HunkSelector{
+				{Hunk: 1},
+				{Hunk: 2, Lines: []LineRange{{4, 4}}},
+				{FileLines: []LineRange{{9, 12}}},
+			},
+		},
+		{
+			name:    "unbalanced brackets",
+			spec:    "main.go:2[3-7",
+			wantErr: "unbalanced brackets",
+		},
+		{
+			name:    "reversed range",
+			spec:    "main.go:2[7-3]",
+			wantErr: "invalid line range: 7-3",
+		},
+		{
+			name:    "zero line",
+			spec:    "main.go:L0",
+			wantErr: "invalid line range: L0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, selectors, err := ParseHunkSelectors(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseHunkSelectors(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseHunkSelectors(%q) unexpected error: %v", tt.spec, err)
+			}
+			if file != tt.wantFile {
+				t.Errorf("file = %q, want %q", file, tt.wantFile)
+			}
+			if !reflect.DeepEqual(selectors, tt.want) {
+				t.Errorf("selectors = %+v, want %+v", selectors, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHunkSpec_RejectsLineSelection(t *testing.T) {
+	if _, _, err := ParseHunkSpec("main.go:2[3-7]"); err == nil {
+		t.Error("Expected ParseHunkSpec to reject a line selection")
+	}
+}
+
+// lineSelectionPatch has one hunk that mixes two changes (lines 2 and 5)
+const lineSelectionPatch = `diff --git a/file.txt b/file.txt
+index 1234567..abcdefg 100644
+--- a/file.txt
++++ b/file.txt
+@@ -1,6 +1,6 @@
+ line1
+-line2
++LINE2
+ line3
+ line4
+-line5
++LINE5
+ line6
+`
+
+func TestReduceFragment(t *testing.T) {
+	hunks, err := ParsePatchFileWithGitDiff(lineSelectionPatch)
+	if err != nil {
+		t.Fatalf("Failed to parse patch: %v", err)
+	}
+
+	// Keep only the change of line 5 (body lines 6 and 7)
+	reduced, err := reduceFragment(hunks[0].Fragment, []int{6, 7})
+	if err != nil {
+		t.Fatalf("reduceFragment failed: %v", err)
+	}
+
+	want := `@@ -1,6 +1,6 @@
+ line1
+ line2
+ line3
+ line4
+-line5
++LINE5
+ line6
+`
+	if got := reduced.String(); got != want {
+		t.Errorf("reduced fragment =\n%s\nwant\n%s", got, want)
+	}
+	if reduced.LeadingContext != 4 || reduced.TrailingContext != 1 {
+		t.Errorf("context = %d/%d, want 4/1", reduced.LeadingContext, reduced.TrailingContext)
+	}
+}
+
+func TestReduceFragment_DropsUnselectedAdditions(t *testing.T) {
+	hunks, err := ParsePatchFileWithGitDiff(lineSelectionPatch)
+	if err != nil {
+		t.Fatalf("Failed to parse patch: %v", err)
+	}
+
+	// Keep only the deletion of line2
+	reduced, err := reduceFragment(hunks[0].Fragment, []int{2})
+	if err != nil {
+		t.Fatalf("reduceFragment failed: %v", err)
+	}
+	if reduced.OldLines != 6 || reduced.NewLines != 5 {
+		t.Errorf("header = %s, want -1,6 +1,5", reduced.Header())
+	}
+	for _, line := range reduced.Lines {
+		if line.Op == gitdiff.OpAdd {
+			t.Errorf("Unexpected added line %q", line.Line)
+		}
+	}
+
+	if _, err := reduceFragment(hunks[0].Fragment, []int{1}); err == nil {
+		t.Error("Expected error when only context lines are selected")
+	}
+}
+
+func TestBuildTargets_LineSelections(t *testing.T) {
+	hunks, err := ParsePatchFileWithGitDiff(lineSelectionPatch)
+	if err != nil {
+		t.Fatalf("Failed to parse patch: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		specs []string
+		want  []int
+	}{
+		{name: "hunk body lines", specs: []string{"file.txt:1[2-3]"}, want: []int{2, 3}},
+		{name: "file lines match new and old numbers", specs: []string{"file.txt:L5"}, want: []int{6, 7}},
+		{name: "context lines are ignored", specs: []string{"file.txt:1[1-4]"}, want: []int{2, 3}},
+		{name: "selections are merged", specs: []string{"file.txt:1[2]", "file.txt:1[7]"}, want: []int{2, 7}},
+		{name: "whole selection becomes whole hunk", specs: []string{"file.txt:1[2-3]", "file.txt:L5"}, want: nil},
+		{name: "whole hunk wins", specs: []string{"file.txt:1[2]", "file.txt:1"}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets, err := buildTargets(tt.specs, hunks)
+			if err != nil {
+				t.Fatalf("buildTargets failed: %v", err)
+			}
+			if len(targets) != 1 {
+				t.Fatalf("Expected 1 target, got %d", len(targets))
+			}
+			if !reflect.DeepEqual(targets[0].Lines, tt.want) {
+				t.Errorf("Lines = %v, want %v", targets[0].Lines, tt.want)
+			}
+		})
+	}
+
+	if _, err := buildTargets([]string{"file.txt:1[20]"}, hunks); err == nil {
+		t.Error("Expected error for a line beyond the hunk body")
+	}
+	if _, err := buildTargets([]string{"file.txt:L100"}, hunks); err == nil {
+		t.Error("Expected error for a file line range without changes")
+	}
+}
@@ -0,0 +1,118 @@
+package stager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// HunkSummary describes a single hunk as reported by the list-hunks subcommand.
+// IndexInFile is the number accepted by "stage -hunk=file:N".
+type HunkSummary struct {
+	FilePath     string // File path this hunk belongs to (new path for renames)
+	IndexInFile  int    // Hunk number within the file (1, 2, 3, ...)
+	GlobalIndex  int    // Global hunk number in the diff (1, 2, 3, ...)
+	PatchID      string // Short patch ID accepted by "stage -hunk=id:<patch ID>" (empty if not calculated)
+	OldStart     int64  // First line of the hunk in the old file
+	OldLines     int64  // Number of old lines covered by the hunk
+	NewStart     int64  // First line of the hunk in the new file
+	NewLines     int64  // Number of new lines covered by the hunk
+	Context      string // Function context text following the @@ range
+	LinesAdded   int64  // Number of added lines
+	LinesDeleted int64  // Number of removed lines
+	IsBinary     bool   // Whether this is a binary file
+	HasContent   bool   // False for binary files and file operations without text changes
+	Body         string // Hunk body lines without the @@ header
+}
+
+// Range returns the hunk range in "@@ -a,b +c,d @@" form.
+// Returns an empty string for hunks without text content.
+func (h HunkSummary) Range() string {
+	if !h.HasContent {
+		return ""
+	}
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+}
+
+// ListHunksInDiff parses the given diff output and returns a summary of every hunk
+// in diff order. Numbering matches ParsePatchFileWithGitDiff, so the IndexInFile
+// values can be passed directly to the stage subcommand.
+// Returns an empty slice if diffOutput is empty.
+func ListHunksInDiff(diffOutput string) ([]HunkSummary, error) {
+	if len(diffOutput) == 0 {
+		return []HunkSummary{}, nil
+	}
+
+	hunks, err := ParsePatchFileWithGitDiff(diffOutput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse diff: %w", err)
+	}
+
+	summaries := make([]HunkSummary, 0, len(hunks))
+	for _, hunk := range hunks {
+		summaries = append(summaries, summarizeHunk(hunk))
+	}
+
+	return summaries, nil
+}
+
+// ListHunks works like ListHunksInDiff and additionally reports the patch ID of every hunk
+func (s *Stager) ListHunks(ctx context.Context, diffOutput string) ([]HunkSummary, error) {
+	if len(diffOutput) == 0 {
+		return []HunkSummary{}, nil
+	}
+
+	hunks, err := ParsePatchFileWithGitDiff(diffOutput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse diff: %w", err)
+	}
+
+	if err := s.calculatePatchIDsForHunks(ctx, hunks); err != nil {
+		return nil, err
+	}
+
+	summaries := make([]HunkSummary, 0, len(hunks))
+	for _, hunk := range hunks {
+		summary := summarizeHunk(hunk)
+		if !isFallbackPatchID(hunk.PatchID) {
+			// Fallback IDs of hunks without content cannot be selected
+			summary.PatchID = hunk.PatchID
+		}
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, nil
+}
+
+// summarizeHunk converts a parsed hunk into a HunkSummary
+func summarizeHunk(hunk HunkInfo) HunkSummary {
+	summary := HunkSummary{
+		FilePath:    hunk.FilePath,
+		IndexInFile: hunk.IndexInFile,
+		GlobalIndex: hunk.GlobalIndex,
+		IsBinary:    hunk.IsBinary,
+	}
+
+	if hunk.IsBinary || hunk.Fragment == nil {
+		return summary
+	}
+
+	fragment := hunk.Fragment
+	summary.HasContent = true
+	summary.OldStart = fragment.OldPosition
+	summary.OldLines = fragment.OldLines
+	summary.NewStart = fragment.NewPosition
+	summary.NewLines = fragment.NewLines
+	summary.Context = fragment.Comment
+	summary.LinesAdded = fragment.LinesAdded
+	summary.LinesDeleted = fragment.LinesDeleted
+
+	// Drop the @@ header line; the range is reported separately
+	body := fragment.String()
+	if idx := strings.IndexByte(body, '\n'); idx >= 0 {
+		body = body[idx+1:]
+	}
+	summary.Body = body
+
+	return summary
+}
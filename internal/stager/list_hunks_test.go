@@ -0,0 +1,117 @@
+package stager
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestListHunksInDiff_NoChanges tests listing hunks when diff is empty
+func TestListHunksInDiff_NoChanges(t *testing.T) {
+	result, err := ListHunksInDiff("")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(result) != 0 {
+		t.Errorf("Expected no hunks for empty diff, got %v", result)
+	}
+}
+
+// TestListHunksInDiff_MultipleFiles tests that numbering and ranges match the parsed patch
+func TestListHunksInDiff_MultipleFiles(t *testing.T) {
+	diffOutput := `diff --git a/calculator.go b/calculator.go
+index 1234567..abcdefg 100644
+--- a/calculator.go
++++ b/calculator.go
+@@ -1,3 +1,4 @@
+ package main
++import "fmt"
+
+ func add() {
+@@ -10,3 +11,3 @@ func multiply() {
+ 	x := 1
+-	return 0
++	return x
+ }
+diff --git a/other.go b/other.go
+index 2234567..bbcdefg 100644
+--- a/other.go
++++ b/other.go
+@@ -5,2 +5,3 @@ func other() {
+ 	println("other")
++	println("added")
+ }
+`
+
+	result, err := ListHunksInDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(result) != 3 {
+		t.Fatalf("Expected 3 hunks, got %d", len(result))
+	}
+
+	tests := []struct {
+		filePath    string
+		indexInFile int
+		globalIndex int
+		rangeHeader string
+		context     string
+		added       int64
+		removed     int64
+	}{
+		{"calculator.go", 1, 1, "@@ -1,3 +1,4 @@", "", 1, 0},
+		{"calculator.go", 2, 2, "@@ -10,3 +11,3 @@", "func multiply() {", 1, 1},
+		{"other.go", 1, 3, "@@ -5,2 +5,3 @@", "func other() {", 1, 0},
+	}
+
+	for i, tt := range tests {
+		got := result[i]
+		if got.FilePath != tt.filePath || got.IndexInFile != tt.indexInFile || got.GlobalIndex != tt.globalIndex {
+			t.Errorf("hunk %d: got %s:%d (#%d), want %s:%d (#%d)",
+				i, got.FilePath, got.IndexInFile, got.GlobalIndex, tt.filePath, tt.indexInFile, tt.globalIndex)
+		}
+		if got.Range() != tt.rangeHeader {
+			t.Errorf("hunk %d: Range() = %q, want %q", i, got.Range(), tt.rangeHeader)
+		}
+		if got.Context != tt.context {
+			t.Errorf("hunk %d: Context = %q, want %q", i, got.Context, tt.context)
+		}
+		if got.LinesAdded != tt.added || got.LinesDeleted != tt.removed {
+			t.Errorf("hunk %d: got +%d -%d, want +%d -%d", i, got.LinesAdded, got.LinesDeleted, tt.added, tt.removed)
+		}
+	}
+
+	// Body should contain the changed lines but not the @@ header
+	body := result[1].Body
+	if strings.Contains(body, "@@") {
+		t.Errorf("Body should not contain the hunk header, got:\n%s", body)
+	}
+	if !strings.Contains(body, "-\treturn 0\n") || !strings.Contains(body, "+\treturn x\n") {
+		t.Errorf("Body missing changed lines, got:\n%s", body)
+	}
+}
+
+// TestListHunksInDiff_BinaryFile tests that binary files are reported without a range
+func TestListHunksInDiff_BinaryFile(t *testing.T) {
+	diffOutput := `diff --git a/image.png b/image.png
+index 1234567..abcdefg 100644
+Binary files a/image.png and b/image.png differ
+`
+
+	result, err := ListHunksInDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(result) != 1 {
+		t.Fatalf("Expected 1 hunk, got %d", len(result))
+	}
+	if !result[0].IsBinary {
+		t.Error("Expected binary hunk")
+	}
+	if result[0].HasContent || result[0].Range() != "" {
+		t.Errorf("Binary hunk should not have a range, got %q", result[0].Range())
+	}
+}
@@ -0,0 +1,89 @@
+package stager
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+// MatchSelector selects hunks by content: every hunk of a file matching FileGlob
+// whose added or removed lines, or function context, match Pattern
+type MatchSelector struct {
+	FileGlob string         // Glob for the file path; a glob without "/" is matched against the file name
+	Pattern  *regexp.Regexp // Regular expression for the changed lines and function context
+	spec     string         // Original specification for error messages
+}
+
+// ParseMatchSelector parses a "<file-glob>:<regex>" specification.
+// The regular expression starts after the first colon, so it may contain colons itself.
+func ParseMatchSelector(spec string) (MatchSelector, error) {
+	fileGlob, pattern, found := strings.Cut(spec, ":")
+	if !found || fileGlob == "" || pattern == "" {
+		return MatchSelector{}, NewInvalidArgumentError(fmt.Sprintf("invalid match specification: %s (expected format: file-glob:regex)", spec), nil)
+	}
+
+	if _, err := path.Match(fileGlob, ""); err != nil {
+		return MatchSelector{}, NewInvalidArgumentError(fmt.Sprintf("invalid file glob in match specification: %s", spec), err)
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return MatchSelector{}, NewInvalidArgumentError(fmt.Sprintf("invalid regular expression in match specification: %s", spec), err)
+	}
+
+	return MatchSelector{FileGlob: fileGlob, Pattern: re, spec: spec}, nil
+}
+
+// MatchesFile reports whether filePath matches the file glob of the selector
+func (m MatchSelector) MatchesFile(filePath string) bool {
+	return matchFileGlob(m.FileGlob, filePath)
+}
+
+// MatchesHunk reports whether the selector selects the given hunk.
+// Binary hunks and hunks without text changes are never matched.
+func (m MatchSelector) MatchesHunk(hunk HunkInfo) bool {
+	if hunk.IsBinary || hunk.Fragment == nil || !m.MatchesFile(hunk.FilePath) {
+		return false
+	}
+
+	if hunk.Fragment.Comment != "" && m.Pattern.MatchString(hunk.Fragment.Comment) {
+		return true
+	}
+	for _, line := range hunk.Fragment.Lines {
+		if line.Op == gitdiff.OpContext {
+			continue
+		}
+		if m.Pattern.MatchString(strings.TrimSuffix(line.Line, "\n")) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveMatchSelectors returns "file:N" specifications for every hunk in allHunks
+// selected by the match specifications, in patch order.
+// Each specification must select at least one hunk.
+func resolveMatchSelectors(matchSpecs []string, allHunks []HunkInfo) ([]string, error) {
+	var resolved []string
+	for _, spec := range matchSpecs {
+		selector, err := ParseMatchSelector(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		found := false
+		for _, hunk := range allHunks {
+			if selector.MatchesHunk(hunk) {
+				resolved = append(resolved, fmt.Sprintf("%s:%d", hunk.FilePath, hunk.IndexInFile))
+				found = true
+			}
+		}
+		if !found {
+			return nil, NewHunkNotFoundError(fmt.Sprintf("hunks matching %s", selector.spec), nil)
+		}
+	}
+	return resolved, nil
+}
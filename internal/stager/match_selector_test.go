@@ -0,0 +1,93 @@
+package stager
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMatchSelector(t *testing.T) {
+	selector, err := ParseMatchSelector("*.go:func New[A-Z]\\w+\\(")
+	if err != nil {
+		t.Fatalf("ParseMatchSelector failed: %v", err)
+	}
+	if selector.FileGlob != "*.go" || selector.Pattern.String() != "func New[A-Z]\\w+\\(" {
+		t.Errorf("Unexpected selector: %+v", selector)
+	}
+
+	// The regular expression may contain colons
+	selector, err = ParseMatchSelector("config.yaml:^port: \\d+")
+	if err != nil {
+		t.Fatalf("ParseMatchSelector failed: %v", err)
+	}
+	if selector.Pattern.String() != "^port: \\d+" {
+		t.Errorf("Unexpected pattern: %s", selector.Pattern)
+	}
+
+	for _, spec := range []string{"main.go", ":regex", "main.go:", "[.go:x", "main.go:(unclosed"} {
+		if _, err := ParseMatchSelector(spec); err == nil {
+			t.Errorf("ParseMatchSelector(%q) should fail", spec)
+		}
+	}
+}
+
+func TestResolveMatchSelectors(t *testing.T) {
+	patch := `diff --git a/logger.go b/logger.go
+index 1234567..abcdefg 100644
+--- a/logger.go
++++ b/logger.go
+@@ -1,3 +1,6 @@
+ package main
++
++func NewLogger() *Logger {
++}
+ 
+ type Logger struct{}
+@@ -10,3 +13,3 @@ func (l *Logger) Info() {
+ 	x := 1
+-	return
++	return x
+ }
+diff --git a/main.go b/main.go
+index 2234567..bbcdefg 100644
+--- a/main.go
++++ b/main.go
+@@ -5,2 +5,3 @@ func main() {
+ 	println("a")
++	logger := NewLogger()
+ }
+`
+	allHunks, err := ParsePatchFileWithGitDiff(patch)
+	if err != nil {
+		t.Fatalf("Failed to parse patch: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		specs []string
+		want  []string
+	}{
+		{"added lines in every file", []string{"*.go:NewLogger"}, []string{"logger.go:1", "main.go:1"}},
+		{"restricted to a file", []string{"logger.go:NewLogger"}, []string{"logger.go:1"}},
+		{"function context", []string{"logger.go:func \\(l \\*Logger\\) Info"}, []string{"logger.go:2"}},
+		{"removed lines", []string{"*.go:^\\s+return$"}, []string{"logger.go:2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveMatchSelectors(tt.specs, allHunks)
+			if err != nil {
+				t.Fatalf("resolveMatchSelectors failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveMatchSelectors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Context lines are not matched
+	_, err = resolveMatchSelectors([]string{"*.go:type Logger"}, allHunks)
+	if err == nil || !strings.Contains(err.Error(), "hunks matching *.go:type Logger") {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
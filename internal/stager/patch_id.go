@@ -0,0 +1,95 @@
+package stager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// calculatePatchIDs calculates the stable patch ID of every patch in contents.
+// IDs are cached by patch content. Uncached patches are hashed in-process with
+// CalculatePatchIDStable, or by a single "git patch-id --stable" process if
+// Options.GitPatchID is set. The returned slice has one entry per patch;
+// the entry is empty if no ID could be calculated (e.g. the patch has no diff).
+func (s *Stager) calculatePatchIDs(ctx context.Context, contents [][]byte) ([]string, error) {
+	if s.patchIDCache == nil {
+		s.patchIDCache = make(map[string]string)
+	}
+
+	ids := make([]string, len(contents))
+	var missing []int
+	for i, content := range contents {
+		if len(content) == 0 {
+			continue
+		}
+		if id, ok := s.patchIDCache[string(content)]; ok {
+			ids[i] = id
+			continue
+		}
+		missing = append(missing, i)
+	}
+
+	if len(missing) == 0 {
+		return ids, nil
+	}
+
+	if !s.options.GitPatchID {
+		for _, i := range missing {
+			if id := CalculatePatchIDStable(contents[i]); id != "" {
+				ids[i] = shortPatchID(id)
+				s.patchIDCache[string(contents[i])] = ids[i]
+			}
+		}
+		return ids, nil
+	}
+
+	return s.calculatePatchIDsWithGit(ctx, contents, missing, ids)
+}
+
+// calculatePatchIDsWithGit hashes the patches at the missing positions with one
+// "git patch-id --stable" process and stores the results in ids
+func (s *Stager) calculatePatchIDsWithGit(ctx context.Context, contents [][]byte, missing []int, ids []string) ([]string, error) {
+	// Each patch is preceded by a "commit <id>" line so that git patch-id reports
+	// one ID per patch. The commit ID encodes the patch's position in contents.
+	var stream bytes.Buffer
+	for _, i := range missing {
+		fmt.Fprintf(&stream, "commit %040x\n", i+1)
+		stream.Write(contents[i])
+		if !bytes.HasSuffix(contents[i], []byte("\n")) {
+			stream.WriteByte('\n')
+		}
+	}
+
+	output, err := s.executor.ExecuteWithStdin(ctx, "git", &stream, "patch-id", "--stable")
+	if err != nil {
+		return nil, err
+	}
+
+	// git patch-id output format: "patch-id commit-id", one line per patch with a diff
+	for _, line := range strings.Split(string(output), "\n") {
+		parts := strings.Fields(line)
+		if len(parts) != 2 {
+			continue
+		}
+		position, err := strconv.ParseUint(parts[1], 16, 64)
+		if err != nil || position == 0 || position > uint64(len(contents)) {
+			return nil, NewGitCommandError("git patch-id", fmt.Errorf("unexpected output: %s", line))
+		}
+
+		i := int(position - 1)
+		ids[i] = shortPatchID(parts[0])
+		s.patchIDCache[string(contents[i])] = ids[i]
+	}
+
+	return ids, nil
+}
+
+// shortPatchID returns the first 8 chars of a patch ID for brevity
+func shortPatchID(id string) string {
+	if len(id) >= 8 {
+		return id[:8]
+	}
+	return id
+}
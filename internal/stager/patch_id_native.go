@@ -0,0 +1,231 @@
+package stager
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"hash"
+	"strconv"
+	"strings"
+)
+
+// CalculatePatchIDStable computes the same ID as "git patch-id --stable" for a single patch,
+// without running git. It follows git's builtin/patch-id.c:
+//   - whitespace is removed from every hashed line
+//   - "index" lines, "@@" hunk headers and "\ No newline at end of file" markers are not hashed
+//   - each file is hashed separately and the SHA-1 digests are summed, so the ID does not
+//     depend on the order of the files
+//   - for binary files the object IDs of the "index" line are hashed instead of the content
+//
+// It returns the full 40-character hex ID, or an empty string if the patch contains no diff
+// (in which case git patch-id prints nothing). Only SHA-1 repositories are supported.
+func CalculatePatchIDStable(patch []byte) string {
+	var (
+		result       [sha1.Size]byte
+		h            = sha1.New()
+		patchLen     = 0
+		before       = -1
+		after        = -1
+		diffIsBinary = false
+		preOID       string
+		postOID      string
+	)
+
+	reader := bufio.NewReader(bytes.NewReader(patch))
+	for {
+		line, err := reader.ReadString('\n')
+		if line == "" && err != nil {
+			break
+		}
+
+		// "\ No newline at end of file" markers are ignored
+		if strings.HasPrefix(line, "\\ ") && len(line) > 12 {
+			continue
+		}
+
+		// A line starting with an object ID (or "commit <id>", "From <id>") starts the next patch
+		if startsWithObjectID(line) {
+			break
+		}
+
+		// Ignore commit comments
+		if patchLen == 0 && !strings.HasPrefix(line, "diff ") {
+			continue
+		}
+
+		// Parsing diff header?
+		if before == -1 {
+			if strings.HasPrefix(line, "GIT binary patch") || strings.HasPrefix(line, "Binary files") {
+				diffIsBinary = true
+				before = 0
+				h.Write([]byte(preOID))
+				h.Write([]byte(postOID))
+				flushPatchIDHunk(&result, h)
+				continue
+			} else if rest, ok := strings.CutPrefix(line, "index "); ok {
+				preOID, postOID = parseIndexLineOIDs(rest, preOID, postOID)
+				continue
+			} else if strings.HasPrefix(line, "--- ") {
+				before, after = 1, 1
+			} else if !isASCIIAlpha(line) {
+				break
+			}
+		}
+
+		if diffIsBinary {
+			if strings.HasPrefix(line, "diff ") {
+				diffIsBinary = false
+				before = -1
+			}
+			continue
+		}
+
+		// Looking for a valid hunk header?
+		if before == 0 && after == 0 {
+			if strings.HasPrefix(line, "@@ -") {
+				// Parse next hunk, but ignore line numbers
+				before, after = scanHunkHeader(line, before, after)
+				continue
+			}
+
+			// Split at the end of the patch
+			if !strings.HasPrefix(line, "diff ") {
+				break
+			}
+
+			// Else we're parsing another header
+			flushPatchIDHunk(&result, h)
+			before, after = -1, -1
+		}
+
+		// If we get here, we're inside a hunk
+		if line[0] == '-' || line[0] == ' ' {
+			before--
+		}
+		if line[0] == '+' || line[0] == ' ' {
+			after--
+		}
+
+		// Add line to hash algo (removing whitespace)
+		stripped := removeSpace(line)
+		patchLen += len(stripped)
+		h.Write([]byte(stripped))
+	}
+
+	flushPatchIDHunk(&result, h)
+
+	if patchLen == 0 {
+		return ""
+	}
+	return hex.EncodeToString(result[:])
+}
+
+// flushPatchIDHunk adds the digest of h to result as a 20-byte little-endian sum with carry,
+// then resets h
+func flushPatchIDHunk(result *[sha1.Size]byte, h hash.Hash) {
+	digest := h.Sum(nil)
+	h.Reset()
+
+	carry := 0
+	for i := range result {
+		carry += int(result[i]) + int(digest[i])
+		result[i] = byte(carry)
+		carry >>= 8
+	}
+}
+
+// parseIndexLineOIDs extracts the object IDs from the rest of an "index a..b [mode]" line.
+// The previous values are kept if the line cannot be parsed.
+func parseIndexLineOIDs(rest, preOID, postOID string) (string, string) {
+	rest = strings.TrimSuffix(rest, "\n")
+	pre, post, ok := strings.Cut(rest, "..")
+	if !ok {
+		return preOID, postOID
+	}
+	if i := strings.IndexByte(post, ' '); i >= 0 {
+		post = post[:i]
+	}
+	return pre, post
+}
+
+// scanHunkHeader parses the line counts of a "@@ -a,b +c,d @@" header.
+// Like git, the counts default to 1 when omitted and are left unchanged
+// only as far as parsing got before a malformed header.
+func scanHunkHeader(line string, before, after int) (int, int) {
+	q := line[4:]
+	n := countDigits(q)
+	if n < len(q) && q[n] == ',' {
+		q = q[n+1:]
+		before = atoiPrefix(q)
+		n = countDigits(q)
+	} else {
+		before = 1
+	}
+
+	if n == 0 || n+1 >= len(q) || q[n] != ' ' || q[n+1] != '+' {
+		return before, after
+	}
+
+	r := q[n+2:]
+	n = countDigits(r)
+	if n < len(r) && r[n] == ',' {
+		r = r[n+1:]
+		after = atoiPrefix(r)
+	} else {
+		after = 1
+	}
+
+	return before, after
+}
+
+// countDigits returns the length of the leading run of ASCII digits in s
+func countDigits(s string) int {
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	return n
+}
+
+// atoiPrefix parses the leading digits of s, returning 0 if there are none
+func atoiPrefix(s string) int {
+	n, _ := strconv.Atoi(s[:countDigits(s)])
+	return n
+}
+
+// startsWithObjectID reports whether the line starts a new patch in git patch-id input:
+// an object ID, optionally preceded by "diff-tree ", "commit " or "From "
+func startsWithObjectID(line string) bool {
+	for _, prefix := range []string{"diff-tree ", "commit ", "From "} {
+		if rest, ok := strings.CutPrefix(line, prefix); ok {
+			line = rest
+			break
+		}
+	}
+	if len(line) < 2*sha1.Size {
+		return false
+	}
+	_, err := hex.DecodeString(line[:2*sha1.Size])
+	return err == nil
+}
+
+// isASCIIAlpha reports whether the line starts with an ASCII letter
+func isASCIIAlpha(line string) bool {
+	c := line[0]
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// removeSpace removes all whitespace characters (as defined by C isspace) from the line
+func removeSpace(line string) string {
+	var b strings.Builder
+	b.Grow(len(line))
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ', '\t', '\n', '\v', '\f', '\r':
+			continue
+		}
+		b.WriteByte(line[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,127 @@
+package stager
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/internal/executor"
+	"github.com/syou6162/git-sequential-stage/testutils"
+)
+
+// gitPatchIDStable runs the real "git patch-id --stable" for comparison
+func gitPatchIDStable(t *testing.T, patch string) string {
+	t.Helper()
+	cmd := exec.Command("git", "patch-id", "--stable")
+	cmd.Stdin = strings.NewReader(patch)
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("git patch-id failed: %v", err)
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// TestCalculatePatchIDStable_MatchesGit cross-checks the native implementation against git
+// for whole diffs and for every single-hunk patch generated from them
+func TestCalculatePatchIDStable_MatchesGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found in PATH")
+	}
+
+	testRepo := testutils.NewTestRepo(t, "patch-id-native-*")
+	defer testRepo.Cleanup()
+	defer testRepo.Chdir()()
+
+	lines := make([]string, 30)
+	for i := range lines {
+		lines[i] = "line" + strings.Repeat("x", i%5)
+	}
+	testRepo.CreateFile("multi.txt", strings.Join(lines, "\n")+"\n")
+	testRepo.CreateFile("deleted.txt", "to be deleted\n")
+	testRepo.CreateFile("renamed.txt", "a\nb\nc\nd\ne\nf\n")
+	testRepo.CreateFile("moved.txt", "unchanged content\nfor a pure rename\n")
+	testRepo.CreateFile("script.sh", "#!/bin/sh\necho hi\n")
+	testRepo.CreateFile("noeol.txt", "first\nlast")
+	testRepo.CreateFile("spaces.txt", "func() {\n\treturn 1\n}\n")
+	testRepo.CreateBinaryFile("image.png", testutils.TestData.MinimalPNGTransparent)
+	testRepo.CommitChanges("Initial commit")
+
+	modified := append([]string{}, lines...)
+	modified[1] = "changed near the top"
+	modified[15] = "changed in the middle"
+	modified = append(modified, "appended at the end")
+	testRepo.ModifyFile("multi.txt", strings.Join(modified, "\n")+"\n")
+	testRepo.RunCommandOrFail("git", "rm", "-q", "deleted.txt")
+	testRepo.RunCommandOrFail("git", "mv", "renamed.txt", "renamed_new.txt")
+	testRepo.ModifyFile("renamed_new.txt", "a\nb\nc\nd\ne\nF\n")
+	testRepo.RunCommandOrFail("git", "mv", "moved.txt", "moved_new.txt")
+	testRepo.RunCommandOrFail("chmod", "+x", "script.sh")
+	testRepo.ModifyFile("noeol.txt", "first\nLAST")
+	testRepo.ModifyFile("spaces.txt", "func()  {\n    return 1\n}\n")
+	testRepo.CreateFile("added.txt", "brand new\nfile\n")
+	testRepo.CreateBinaryFile("image.png", testutils.TestData.MinimalPNGRed)
+	testRepo.RunCommandOrFail("git", "add", "-N", "added.txt")
+
+	diff := testRepo.RunCommandOrFail("git", "diff", "HEAD", "-M")
+
+	t.Run("whole diff", func(t *testing.T) {
+		want := gitPatchIDStable(t, diff)
+		if want == "" {
+			t.Fatal("git patch-id returned no ID for the test diff")
+		}
+		if got := CalculatePatchIDStable([]byte(diff)); got != want {
+			t.Errorf("CalculatePatchIDStable() = %s, git patch-id = %s", got, want)
+		}
+	})
+
+	hunks, err := ParsePatchFileWithGitDiff(diff)
+	if err != nil {
+		t.Fatalf("Failed to parse diff: %v", err)
+	}
+	s := NewStager(executor.NewRealCommandExecutor())
+	for i := range hunks {
+		hunk := hunks[i]
+		t.Run(hunk.FilePath, func(t *testing.T) {
+			content, err := s.extractHunkContent(&hunk)
+			if err != nil {
+				// Pure renames have no content to hash
+				t.Skipf("No content for hunk %d: %v", hunk.GlobalIndex, err)
+			}
+			want := gitPatchIDStable(t, string(content))
+			if got := CalculatePatchIDStable(content); got != want {
+				t.Errorf("hunk %d: CalculatePatchIDStable() = %q, git patch-id = %q\n%s", hunk.IndexInFile, got, want, content)
+			}
+		})
+	}
+}
+
+func TestCalculatePatchIDStable_Properties(t *testing.T) {
+	patch := "diff --git a/a.txt b/a.txt\nindex 1234567..abcdefg 100644\n--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,2 @@\n context\n-old\n+new\n"
+
+	// Line numbers and index lines do not affect the ID
+	moved := strings.Replace(patch, "@@ -1,2 +1,2 @@", "@@ -10,2 +12,2 @@", 1)
+	moved = strings.Replace(moved, "1234567..abcdefg", "7654321..gfedcba", 1)
+	if CalculatePatchIDStable([]byte(patch)) != CalculatePatchIDStable([]byte(moved)) {
+		t.Error("Expected the same ID regardless of line numbers and index line")
+	}
+
+	// Whitespace does not affect the ID
+	spaced := strings.Replace(patch, "+new", "+ n e w", 1)
+	if CalculatePatchIDStable([]byte(patch)) != CalculatePatchIDStable([]byte(spaced)) {
+		t.Error("Expected the same ID regardless of whitespace")
+	}
+
+	// Content does affect the ID
+	other := strings.Replace(patch, "+new", "+other", 1)
+	if CalculatePatchIDStable([]byte(patch)) == CalculatePatchIDStable([]byte(other)) {
+		t.Error("Expected different IDs for different content")
+	}
+
+	if got := CalculatePatchIDStable([]byte("not a diff\n")); got != "" {
+		t.Errorf("Expected no ID for input without a diff, got %s", got)
+	}
+}
@@ -0,0 +1,98 @@
+package stager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/internal/executor"
+)
+
+func TestCalculatePatchIDs_BatchesAndCaches(t *testing.T) {
+	mock := executor.NewMockCommandExecutor()
+	mock.Commands["git [patch-id --stable]"] = executor.MockResponse{
+		Output: []byte(fmt.Sprintf("1111111111111111111111111111111111111111 %040x\n3333333333333333333333333333333333333333 %040x\n", 1, 3)),
+	}
+	s := NewStagerWithOptions(mock, Options{GitPatchID: true})
+
+	contents := [][]byte{
+		[]byte("diff --git a/a.txt b/a.txt\n"),
+		nil, // Hunks whose content could not be extracted are skipped
+		[]byte("diff --git a/c.txt b/c.txt\n"),
+	}
+
+	ids, err := s.calculatePatchIDs(context.Background(), contents)
+	if err != nil {
+		t.Fatalf("calculatePatchIDs failed: %v", err)
+	}
+	want := []string{"11111111", "", "33333333"}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Errorf("ids[%d] = %q, want %q", i, ids[i], want[i])
+		}
+	}
+
+	if len(mock.ExecutedCommands) != 1 {
+		t.Fatalf("Expected a single git patch-id call, got %d", len(mock.ExecutedCommands))
+	}
+	stdin := string(mock.ExecutedCommands[0].Stdin)
+	if !strings.Contains(stdin, fmt.Sprintf("commit %040x\ndiff --git a/a.txt", 1)) ||
+		!strings.Contains(stdin, fmt.Sprintf("commit %040x\ndiff --git a/c.txt", 3)) {
+		t.Errorf("Unexpected patch-id input:\n%s", stdin)
+	}
+
+	// Second call with the same content is served from the cache
+	ids, err = s.calculatePatchIDs(context.Background(), [][]byte{contents[2]})
+	if err != nil {
+		t.Fatalf("calculatePatchIDs failed: %v", err)
+	}
+	if ids[0] != "33333333" {
+		t.Errorf("cached id = %q, want %q", ids[0], "33333333")
+	}
+	if len(mock.ExecutedCommands) != 1 {
+		t.Errorf("Expected cached lookup without another git call, got %d calls", len(mock.ExecutedCommands))
+	}
+}
+
+func TestCalculatePatchIDs_RealGit(t *testing.T) {
+	newStager := func() *Stager {
+		return NewStagerWithOptions(executor.NewRealCommandExecutor(), Options{GitPatchID: true})
+	}
+	s := newStager()
+
+	patch := func(name string) []byte {
+		return []byte(fmt.Sprintf("diff --git a/%[1]s b/%[1]s\nindex 1234567..abcdefg 100644\n--- a/%[1]s\n+++ b/%[1]s\n@@ -1 +1 @@\n-old\n+new %[1]s\n", name))
+	}
+
+	batch, err := s.calculatePatchIDs(context.Background(), [][]byte{patch("a.txt"), patch("b.txt")})
+	if err != nil {
+		t.Fatalf("calculatePatchIDs failed: %v", err)
+	}
+
+	// Each ID must match the ID of the patch hashed on its own
+	for i, name := range []string{"a.txt", "b.txt"} {
+		single, err := newStager().calculatePatchIDs(context.Background(), [][]byte{patch(name)})
+		if err != nil {
+			t.Fatalf("calculatePatchIDs failed: %v", err)
+		}
+		if batch[i] == "" || batch[i] != single[0] {
+			t.Errorf("batch id for %s = %q, single id = %q", name, batch[i], single[0])
+		}
+	}
+	if batch[0] == batch[1] {
+		t.Errorf("Expected different IDs for different patches, got %q", batch[0])
+	}
+}
+
+func TestIsFallbackPatchID(t *testing.T) {
+	hunk := HunkInfo{GlobalIndex: 3}
+	setFallbackPatchID(&hunk)
+
+	if !isFallbackPatchID(hunk.PatchID) {
+		t.Errorf("Expected %q to be a fallback patch ID", hunk.PatchID)
+	}
+	if isFallbackPatchID("3fa9c1d2") {
+		t.Error("Expected a calculated patch ID not to be a fallback patch ID")
+	}
+}
@@ -0,0 +1,65 @@
+package stager
+
+import (
+	"context"
+)
+
+// RemainingHunk is a hunk of a patch that has not been committed yet
+type RemainingHunk struct {
+	FilePath           string // File path of the hunk in the patch
+	IndexInFile        int    // Hunk number within the file in the patch
+	PatchID            string // Patch ID shared by both versions of the hunk
+	CurrentFilePath    string // File path of the hunk in "git diff HEAD"
+	CurrentIndexInFile int    // Hunk number within the file in "git diff HEAD"
+}
+
+// RemainingHunks returns the hunks of the patch that are still in the diff between HEAD and
+// the working tree, in patch order, with their current numbers. Hunks are matched by patch ID,
+// so they are found even after committing other hunks has shifted the numbering. Hunks that
+// were committed, or edited since the patch was generated, are not listed.
+func (s *Stager) RemainingHunks(ctx context.Context, patchContent string) ([]RemainingHunk, error) {
+	allHunks, err := s.preparePatchData(ctx, patchContent)
+	if err != nil {
+		return nil, err
+	}
+	if len(allHunks) == 0 {
+		return []RemainingHunk{}, nil
+	}
+
+	files := make(map[string]bool)
+	for _, hunk := range allHunks {
+		files[hunk.FilePath] = true
+		if hunk.OldFilePath != "" {
+			files[hunk.OldFilePath] = true
+		}
+	}
+
+	diff, err := s.loadCurrentDiff(ctx, files)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := []RemainingHunk{}
+	claimed := make(map[int]bool)
+	for _, hunk := range allHunks {
+		// Fallback IDs of hunks without content are not real patch IDs
+		if isFallbackPatchID(hunk.PatchID) {
+			continue
+		}
+		h := diff.find(hunk.PatchID, claimed)
+		if h < 0 {
+			continue
+		}
+		claimed[h] = true
+		remaining = append(remaining, RemainingHunk{
+			FilePath:           hunk.FilePath,
+			IndexInFile:        hunk.IndexInFile,
+			PatchID:            hunk.PatchID,
+			CurrentFilePath:    diff.hunks[h].FilePath,
+			CurrentIndexInFile: diff.hunks[h].IndexInFile,
+		})
+	}
+
+	s.logger.Debug("%d of %d hunks of the patch remain", len(remaining), len(allHunks))
+	return remaining, nil
+}
@@ -0,0 +1,81 @@
+package stager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/internal/executor"
+	"github.com/syou6162/git-sequential-stage/internal/logger"
+)
+
+// afterFirstCommitDiff is threeHunkDiff after its second hunk was committed
+// and line21 was edited in a different way than in the patch
+const afterFirstCommitDiff = `diff --git a/file.txt b/file.txt
+index 3333333..4444444 100644
+--- a/file.txt
++++ b/file.txt
+@@ -1,3 +1,3 @@
+ line1
+-line2
++line2 changed
+ line3
+@@ -20,3 +20,3 @@
+ line20
+-line21
++line21 changed again
+ line22
+`
+
+func TestRemainingHunks(t *testing.T) {
+	mock := executor.NewMockCommandExecutor()
+	mock.Commands["git [diff --end-of-options HEAD -- file.txt]"] = executor.MockResponse{Output: []byte(afterFirstCommitDiff)}
+	s := &Stager{executor: mock, logger: logger.NewFromEnv()}
+
+	remaining, err := s.RemainingHunks(context.Background(), threeHunkDiff)
+	if err != nil {
+		t.Fatalf("RemainingHunks failed: %v", err)
+	}
+	if len(remaining) != 1 {
+		t.Fatalf("Expected only hunk 1 to remain, got %+v", remaining)
+	}
+	if remaining[0].FilePath != "file.txt" || remaining[0].IndexInFile != 1 || remaining[0].CurrentIndexInFile != 1 || remaining[0].PatchID == "" {
+		t.Errorf("Unexpected remaining hunk: %+v", remaining[0])
+	}
+}
+
+func TestRemainingHunks_CurrentNumbers(t *testing.T) {
+	mock := executor.NewMockCommandExecutor()
+	s := &Stager{executor: mock, logger: logger.NewFromEnv()}
+	ctx := context.Background()
+
+	// Only the third hunk is left, so it is hunk 1 of the current diff
+	allHunks, err := ParsePatchFileWithGitDiff(threeHunkDiff)
+	if err != nil {
+		t.Fatalf("Failed to parse diff: %v", err)
+	}
+	current, err := s.extractHunkContent(&allHunks[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.Commands["git [diff --end-of-options HEAD -- file.txt]"] = executor.MockResponse{Output: current}
+
+	remaining, err := s.RemainingHunks(ctx, threeHunkDiff)
+	if err != nil {
+		t.Fatalf("RemainingHunks failed: %v", err)
+	}
+	if len(remaining) != 1 || remaining[0].IndexInFile != 3 || remaining[0].CurrentFilePath != "file.txt" || remaining[0].CurrentIndexInFile != 1 {
+		t.Errorf("Expected hunk 3 of the patch to be hunk 1 now, got %+v", remaining)
+	}
+}
+
+func TestRemainingHunks_EmptyPatch(t *testing.T) {
+	s := &Stager{executor: executor.NewMockCommandExecutor(), logger: logger.NewFromEnv()}
+
+	remaining, err := s.RemainingHunks(context.Background(), "")
+	if err != nil {
+		t.Fatalf("RemainingHunks failed: %v", err)
+	}
+	if len(remaining) != 0 {
+		t.Errorf("Expected no remaining hunks, got %+v", remaining)
+	}
+}
@@ -0,0 +1,52 @@
+package stager
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SafetyPolicy decides which changes may already be staged before hunks are staged
+type SafetyPolicy int
+
+const (
+	// SafetyPolicyTargetsOnly allows staged changes only in the files being staged
+	// (besides intent-to-add files). This is the default.
+	SafetyPolicyTargetsOnly SafetyPolicy = iota
+	// SafetyPolicyStrict requires a clean staging area (besides intent-to-add files and file moves)
+	SafetyPolicyStrict
+	// SafetyPolicyAllowPreexisting allows any staged changes; they are logged and kept
+	SafetyPolicyAllowPreexisting
+	// SafetyPolicyOff skips the safety checks entirely
+	SafetyPolicyOff
+)
+
+// safetyPolicies lists the policies in the order of the constants
+var safetyPolicies = []SafetyPolicy{SafetyPolicyTargetsOnly, SafetyPolicyStrict, SafetyPolicyAllowPreexisting, SafetyPolicyOff}
+
+// String returns the name of the policy as accepted by ParseSafetyPolicy
+func (p SafetyPolicy) String() string {
+	switch p {
+	case SafetyPolicyTargetsOnly:
+		return "targets-only"
+	case SafetyPolicyStrict:
+		return "strict"
+	case SafetyPolicyAllowPreexisting:
+		return "allow-preexisting"
+	case SafetyPolicyOff:
+		return "off"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseSafetyPolicy parses a policy name: strict, targets-only, allow-preexisting or off
+func ParseSafetyPolicy(name string) (SafetyPolicy, error) {
+	names := make([]string, 0, len(safetyPolicies))
+	for _, policy := range safetyPolicies {
+		if name == policy.String() {
+			return policy, nil
+		}
+		names = append(names, policy.String())
+	}
+	return SafetyPolicyTargetsOnly, NewInvalidArgumentError(fmt.Sprintf("unknown safety policy: %s (expected %s)", name, strings.Join(names, ", ")), nil)
+}
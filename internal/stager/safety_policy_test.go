@@ -0,0 +1,49 @@
+package stager
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSafetyPolicy(t *testing.T) {
+	tests := []struct {
+		name string
+		want SafetyPolicy
+	}{
+		{"strict", SafetyPolicyStrict},
+		{"targets-only", SafetyPolicyTargetsOnly},
+		{"allow-preexisting", SafetyPolicyAllowPreexisting},
+		{"off", SafetyPolicyOff},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSafetyPolicy(tt.name)
+			if err != nil {
+				t.Fatalf("ParseSafetyPolicy(%q) failed: %v", tt.name, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSafetyPolicy(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+			if got.String() != tt.name {
+				t.Errorf("String() = %q, want %q", got.String(), tt.name)
+			}
+		})
+	}
+
+	if _, err := ParseSafetyPolicy("lenient"); err == nil || !strings.Contains(err.Error(), "unknown safety policy: lenient") {
+		t.Errorf("Expected an unknown policy error, got %v", err)
+	}
+	if SafetyPolicy(99).String() != "unknown" {
+		t.Errorf("Expected an out-of-range policy to be unknown, got %q", SafetyPolicy(99).String())
+	}
+}
+
+func TestStager_performSafetyChecks_PolicyOff(t *testing.T) {
+	stager := NewStagerWithOptions(nil, Options{SafetyPolicy: SafetyPolicyOff})
+
+	// Under the off policy the staging area is not even looked at
+	if err := stager.performSafetyChecks("not a patch", nil); err != nil {
+		t.Errorf("Expected no safety checks with policy off, got %v", err)
+	}
+}
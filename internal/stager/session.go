@@ -0,0 +1,328 @@
+package stager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sessionPath is the location of the session manifest relative to the git directory
+const sessionPath = "sequential-stage/session.json"
+
+// Session is the manifest of a staging session: the hunks of one patch with their patch IDs
+// and the hunks staged from it. It is kept in .git/sequential-stage/session.json, so later
+// runs against the same patch reuse the patch IDs instead of recalculating them and the
+// "status" subcommand can report which hunks are left. Staging from a different patch
+// starts a new session.
+type Session struct {
+	PatchHash string        `json:"patch_hash"` // SHA-256 of the patch content
+	Hunks     []SessionHunk `json:"hunks"`      // Hunks in patch order
+}
+
+// SessionHunk is a hunk of the session's patch
+type SessionHunk struct {
+	FilePath      string `json:"file"`
+	IndexInFile   int    `json:"hunk"`
+	PatchID       string `json:"patch_id"`
+	StagedOn      string `json:"staged_on,omitempty"`       // HEAD commit when the hunk was staged; empty if it was not
+	StagedPatchID string `json:"staged_patch_id,omitempty"` // Patch ID of the current hunk --fuzzy staged instead, if any
+	Partial       bool   `json:"partial,omitempty"`         // Whether only selected lines of the hunk were staged
+}
+
+// stagedID returns the patch ID the hunk has in the staged diff and in commits
+func (hunk SessionHunk) stagedID() string {
+	if hunk.StagedPatchID != "" {
+		return hunk.StagedPatchID
+	}
+	return hunk.PatchID
+}
+
+// SessionHunkState is the current state of a session hunk
+type SessionHunkState int
+
+const (
+	// SessionHunkUnstaged indicates the hunk was not staged in the session (or was unstaged again)
+	SessionHunkUnstaged SessionHunkState = iota
+	// SessionHunkStaged indicates the hunk was staged and is still in the staged diff
+	SessionHunkStaged
+	// SessionHunkCommitted indicates the hunk was staged and committed since
+	SessionHunkCommitted
+	// SessionHunkPartial indicates only some lines of the hunk were staged; they are not followed further
+	SessionHunkPartial
+)
+
+// String returns the string representation of SessionHunkState
+func (st SessionHunkState) String() string {
+	switch st {
+	case SessionHunkUnstaged:
+		return "unstaged"
+	case SessionHunkStaged:
+		return "staged"
+	case SessionHunkCommitted:
+		return "committed"
+	case SessionHunkPartial:
+		return "partial"
+	default:
+		return "unknown"
+	}
+}
+
+// SessionHunkStatus is a session hunk with its current state
+type SessionHunkStatus struct {
+	SessionHunk
+	State SessionHunkState
+}
+
+// hashPatch returns the hash that identifies a patch in the session manifest
+func hashPatch(patchContent string) string {
+	sum := sha256.Sum256([]byte(patchContent))
+	return hex.EncodeToString(sum[:])
+}
+
+// newSession creates a session for a patch whose hunks have patch IDs
+func newSession(patchContent string, hunks []HunkInfo) *Session {
+	session := &Session{PatchHash: hashPatch(patchContent), Hunks: make([]SessionHunk, 0, len(hunks))}
+	for _, hunk := range hunks {
+		session.Hunks = append(session.Hunks, SessionHunk{
+			FilePath:    hunk.FilePath,
+			IndexInFile: hunk.IndexInFile,
+			PatchID:     hunk.PatchID,
+		})
+	}
+	return session
+}
+
+// assignPatchIDs copies the recorded patch IDs to the hunks of the given patch.
+// It reports false, leaving the hunks untouched, unless the session was recorded for this patch.
+func (session *Session) assignPatchIDs(patchContent string, hunks []HunkInfo) bool {
+	if session == nil || session.PatchHash != hashPatch(patchContent) || len(session.Hunks) != len(hunks) {
+		return false
+	}
+	for i, hunk := range hunks {
+		if session.Hunks[i].FilePath != hunk.FilePath || session.Hunks[i].IndexInFile != hunk.IndexInFile {
+			return false
+		}
+	}
+	for i := range hunks {
+		hunks[i].PatchID = session.Hunks[i].PatchID
+	}
+	return true
+}
+
+// sessionFile returns the absolute path of the session manifest.
+// It is resolved with "git rev-parse --git-path", so worktrees get a session of their own.
+func (s *Stager) sessionFile(ctx context.Context) (string, error) {
+	output, err := s.executor.Execute(ctx, "git", "rev-parse", "--git-path", sessionPath)
+	if err != nil {
+		return "", NewGitCommandError("git rev-parse --git-path "+sessionPath, err)
+	}
+
+	path, err := filepath.Abs(strings.TrimSpace(string(output)))
+	if err != nil {
+		return "", NewIOError("resolve session path", err)
+	}
+	return path, nil
+}
+
+// LoadSession reads the session manifest. It returns nil without error if no session was recorded.
+func (s *Stager) LoadSession(ctx context.Context) (*Session, error) {
+	path, err := s.sessionFile(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, NewIOError("read session", err)
+	}
+
+	var session Session
+	if err := json.Unmarshal(content, &session); err != nil {
+		return nil, NewParsingError("session", err)
+	}
+	return &session, nil
+}
+
+// saveSession writes the session manifest, replacing it atomically
+func (s *Stager) saveSession(ctx context.Context, session *Session) error {
+	path, err := s.sessionFile(ctx)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return NewIOError("encode session", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return NewIOError("create session directory", err)
+	}
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), "session-*.json")
+	if err != nil {
+		return NewIOError("write session", err)
+	}
+	defer func() { _ = os.Remove(tmpFile.Name()) }()
+
+	if _, err := tmpFile.Write(append(content, '\n')); err != nil {
+		_ = tmpFile.Close()
+		return NewIOError("write session", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return NewIOError("write session", err)
+	}
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return NewIOError("write session", err)
+	}
+	return nil
+}
+
+// recordsSession reports whether patch IDs are shared with the session manifest.
+// Staged diffs parsed for unstaging are never part of a session.
+func (s *Stager) recordsSession() bool {
+	return s.options.Session && !s.unstaging
+}
+
+// RecordSession marks the hunks staged by a successful run as staged in the session of
+// the patch, starting a new session if the manifest was recorded for another patch.
+// Files staged as a whole mark all of their hunks. Hunks matched by --fuzzy keep the patch ID
+// of the version that was staged, and line selections are recorded as partial stages.
+func (s *Stager) RecordSession(ctx context.Context, patchFile string, result *StageResult) error {
+	content, err := os.ReadFile(patchFile)
+	if err != nil {
+		return NewFileNotFoundError(patchFile, err)
+	}
+
+	session, err := s.LoadSession(ctx)
+	if err != nil {
+		return err
+	}
+	if session == nil || session.PatchHash != hashPatch(string(content)) {
+		// The staging run already prepared the patch, so its patch IDs are cached
+		hunks, err := s.preparePatchData(ctx, string(content))
+		if err != nil {
+			return err
+		}
+		session = newSession(string(content), hunks)
+		s.logger.Debug("Starting a new session for patch %s", session.PatchHash)
+	}
+
+	head, err := s.executor.Execute(ctx, "git", "rev-parse", "HEAD")
+	if err != nil {
+		return NewGitCommandError("git rev-parse HEAD", err)
+	}
+	stagedOn := strings.TrimSpace(string(head))
+
+	staged := make(map[string]HunkResult)
+	wholeFiles := make(map[string]bool)
+	for _, hunk := range result.Hunks {
+		if hunk.Status == HunkStatusApplied {
+			staged[hunkKey(hunk.FilePath, hunk.IndexInFile)] = hunk
+		}
+	}
+	for _, file := range result.Files {
+		wholeFiles[file] = true
+	}
+	for i := range session.Hunks {
+		hunk := &session.Hunks[i]
+		if wholeFiles[hunk.FilePath] {
+			hunk.StagedOn, hunk.StagedPatchID, hunk.Partial = stagedOn, "", false
+		} else if applied, ok := staged[hunkKey(hunk.FilePath, hunk.IndexInFile)]; ok {
+			hunk.StagedOn, hunk.StagedPatchID, hunk.Partial = stagedOn, applied.MatchedPatchID, applied.Partial
+		}
+	}
+
+	return s.saveSession(ctx, session)
+}
+
+// SessionStatus returns the recorded session with the current state of each of its hunks,
+// or nil if no session was recorded. A staged hunk counts as committed once it has left
+// the staged diff and is in the diff between the commit it was staged on and HEAD;
+// a hunk unstaged again before committing counts as unstaged.
+func (s *Stager) SessionStatus(ctx context.Context) (*Session, []SessionHunkStatus, error) {
+	session, err := s.LoadSession(ctx)
+	if err != nil || session == nil {
+		return nil, nil, err
+	}
+
+	head, err := s.executor.Execute(ctx, "git", "rev-parse", "HEAD")
+	if err != nil {
+		return nil, nil, NewGitCommandError("git rev-parse HEAD", err)
+	}
+	currentHead := strings.TrimSpace(string(head))
+
+	stagedDiff, err := s.executor.Execute(ctx, "git", "diff", "--cached")
+	if err != nil {
+		return nil, nil, NewGitCommandError("git diff --cached", err)
+	}
+	stagedHunks, err := ParsePatchFileWithGitDiff(string(stagedDiff))
+	if err != nil {
+		return nil, nil, NewParsingError("staged diff", err)
+	}
+	if err := s.calculatePatchIDsForHunks(ctx, stagedHunks); err != nil {
+		return nil, nil, err
+	}
+	stagedIDs := make(map[string]bool, len(stagedHunks))
+	for _, hunk := range stagedHunks {
+		stagedIDs[hunk.PatchID] = true
+	}
+
+	committedIDs := make(map[string]map[string]bool) // StagedOn -> patch IDs committed since
+	statuses := make([]SessionHunkStatus, 0, len(session.Hunks))
+	for _, hunk := range session.Hunks {
+		state := SessionHunkUnstaged
+		switch {
+		case hunk.StagedOn == "":
+		case hunk.Partial:
+			state = SessionHunkPartial
+		case stagedIDs[hunk.stagedID()]:
+			state = SessionHunkStaged
+		case hunk.StagedOn != currentHead:
+			if committedIDs[hunk.StagedOn] == nil {
+				if committedIDs[hunk.StagedOn], err = s.committedPatchIDs(ctx, hunk.StagedOn); err != nil {
+					return nil, nil, err
+				}
+			}
+			if committedIDs[hunk.StagedOn][hunk.stagedID()] {
+				state = SessionHunkCommitted
+			}
+		}
+		statuses = append(statuses, SessionHunkStatus{SessionHunk: hunk, State: state})
+	}
+	return session, statuses, nil
+}
+
+// committedPatchIDs returns the patch IDs of the hunks committed between since and HEAD
+func (s *Stager) committedPatchIDs(ctx context.Context, since string) (map[string]bool, error) {
+	diff, err := s.executor.Execute(ctx, "git", "diff", "--end-of-options", since, "HEAD")
+	if err != nil {
+		return nil, NewGitCommandError(fmt.Sprintf("git diff %s HEAD", since), err)
+	}
+	hunks, err := ParsePatchFileWithGitDiff(string(diff))
+	if err != nil {
+		return nil, NewParsingError("committed diff", err)
+	}
+	if err := s.calculatePatchIDsForHunks(ctx, hunks); err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]bool, len(hunks))
+	for _, hunk := range hunks {
+		ids[hunk.PatchID] = true
+	}
+	return ids, nil
+}
+
+// hunkKey identifies a hunk of the patch by its file and number
+func hunkKey(filePath string, indexInFile int) string {
+	return fmt.Sprintf("%s:%d", filePath, indexInFile)
+}
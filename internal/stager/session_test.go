@@ -0,0 +1,207 @@
+package stager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/internal/executor"
+	"github.com/syou6162/git-sequential-stage/internal/logger"
+)
+
+// newSessionMock returns a mock whose session manifest lives in a temporary directory
+func newSessionMock(t *testing.T, head string) *executor.MockCommandExecutor {
+	mock := executor.NewMockCommandExecutor()
+	mock.Commands["git [rev-parse --git-path "+sessionPath+"]"] = executor.MockResponse{Output: []byte(filepath.Join(t.TempDir(), sessionPath) + "\n")}
+	mock.Commands["git [rev-parse HEAD]"] = executor.MockResponse{Output: []byte(head + "\n")}
+	return mock
+}
+
+// writePatchFile writes the patch content to a temporary file and returns its path
+func writePatchFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "changes.patch")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRecordSession(t *testing.T) {
+	mock := newSessionMock(t, "1111111111111111111111111111111111111111")
+	s := &Stager{executor: mock, logger: logger.NewFromEnv(), options: Options{Session: true}}
+	ctx := context.Background()
+	patchFile := writePatchFile(t, threeHunkDiff)
+
+	if session, err := s.LoadSession(ctx); err != nil || session != nil {
+		t.Fatalf("Expected no session before recording, got %v, %v", session, err)
+	}
+
+	result := &StageResult{Hunks: []HunkResult{
+		{FilePath: "file.txt", IndexInFile: 2, Status: HunkStatusApplied},
+		{FilePath: "file.txt", IndexInFile: 3, Status: HunkStatusSkipped},
+	}}
+	if err := s.RecordSession(ctx, patchFile, result); err != nil {
+		t.Fatalf("RecordSession failed: %v", err)
+	}
+
+	session, err := s.LoadSession(ctx)
+	if err != nil || session == nil {
+		t.Fatalf("Expected a recorded session, got %v, %v", session, err)
+	}
+	if session.PatchHash != hashPatch(threeHunkDiff) {
+		t.Errorf("Expected the hash of the patch, got %s", session.PatchHash)
+	}
+	if len(session.Hunks) != 3 {
+		t.Fatalf("Expected 3 hunks, got %d", len(session.Hunks))
+	}
+	for i, hunk := range session.Hunks {
+		staged := hunk.StagedOn != ""
+		if staged != (i == 1) {
+			t.Errorf("Hunk %d: expected staged=%v, got StagedOn=%q", hunk.IndexInFile, i == 1, hunk.StagedOn)
+		}
+		if hunk.PatchID == "" {
+			t.Errorf("Hunk %d: expected a patch ID", hunk.IndexInFile)
+		}
+	}
+
+	// A second run against the same patch keeps what was staged before
+	result = &StageResult{Hunks: []HunkResult{{FilePath: "file.txt", IndexInFile: 1, Status: HunkStatusApplied}}}
+	if err := s.RecordSession(ctx, patchFile, result); err != nil {
+		t.Fatalf("RecordSession failed: %v", err)
+	}
+	session, _ = s.LoadSession(ctx)
+	if session.Hunks[0].StagedOn == "" || session.Hunks[1].StagedOn == "" || session.Hunks[2].StagedOn != "" {
+		t.Errorf("Expected hunks 1 and 2 to be staged, got %+v", session.Hunks)
+	}
+}
+
+func TestRecordSession_WholeFiles(t *testing.T) {
+	mock := newSessionMock(t, "1111111111111111111111111111111111111111")
+	s := &Stager{executor: mock, logger: logger.NewFromEnv(), options: Options{Session: true}}
+	ctx := context.Background()
+
+	if err := s.RecordSession(ctx, writePatchFile(t, threeHunkDiff), &StageResult{Files: []string{"file.txt"}}); err != nil {
+		t.Fatalf("RecordSession failed: %v", err)
+	}
+
+	session, _ := s.LoadSession(ctx)
+	for _, hunk := range session.Hunks {
+		if hunk.StagedOn == "" {
+			t.Errorf("Expected hunk %d of a file staged as a whole to be staged", hunk.IndexInFile)
+		}
+	}
+}
+
+func TestRecordSession_FuzzyAndPartial(t *testing.T) {
+	mock := newSessionMock(t, "1111111111111111111111111111111111111111")
+	s := &Stager{executor: mock, logger: logger.NewFromEnv(), options: Options{Session: true}}
+	ctx := context.Background()
+
+	result := &StageResult{Hunks: []HunkResult{
+		{FilePath: "file.txt", IndexInFile: 1, Status: HunkStatusApplied, MatchedPatchID: "beef0001"},
+		{FilePath: "file.txt", IndexInFile: 2, Status: HunkStatusApplied, Partial: true},
+	}}
+	if err := s.RecordSession(ctx, writePatchFile(t, threeHunkDiff), result); err != nil {
+		t.Fatalf("RecordSession failed: %v", err)
+	}
+
+	session, _ := s.LoadSession(ctx)
+	if hunk := session.Hunks[0]; hunk.StagedPatchID != "beef0001" || hunk.stagedID() != "beef0001" || hunk.Partial {
+		t.Errorf("Expected hunk 1 to be recorded with the fuzzy matched patch ID, got %+v", hunk)
+	}
+	if hunk := session.Hunks[1]; !hunk.Partial || hunk.stagedID() != hunk.PatchID {
+		t.Errorf("Expected hunk 2 to be recorded as a partial stage, got %+v", hunk)
+	}
+}
+
+func TestPreparePatchData_ReusesSessionPatchIDs(t *testing.T) {
+	mock := newSessionMock(t, "1111111111111111111111111111111111111111")
+	s := &Stager{executor: mock, logger: logger.NewFromEnv(), options: Options{Session: true}}
+	ctx := context.Background()
+
+	session := &Session{PatchHash: hashPatch(threeHunkDiff), Hunks: []SessionHunk{
+		{FilePath: "file.txt", IndexInFile: 1, PatchID: "aaaa0001"},
+		{FilePath: "file.txt", IndexInFile: 2, PatchID: "aaaa0002"},
+		{FilePath: "file.txt", IndexInFile: 3, PatchID: "aaaa0003"},
+	}}
+	if err := s.saveSession(ctx, session); err != nil {
+		t.Fatalf("saveSession failed: %v", err)
+	}
+
+	hunks, err := s.preparePatchData(ctx, threeHunkDiff)
+	if err != nil {
+		t.Fatalf("preparePatchData failed: %v", err)
+	}
+	for i, hunk := range hunks {
+		if hunk.PatchID != session.Hunks[i].PatchID {
+			t.Errorf("Hunk %d: expected the recorded patch ID %s, got %s", i+1, session.Hunks[i].PatchID, hunk.PatchID)
+		}
+	}
+
+	// Another patch is not matched by the session
+	session.PatchHash = hashPatch("other patch")
+	if err := s.saveSession(ctx, session); err != nil {
+		t.Fatalf("saveSession failed: %v", err)
+	}
+	hunks, err = s.preparePatchData(ctx, threeHunkDiff)
+	if err != nil {
+		t.Fatalf("preparePatchData failed: %v", err)
+	}
+	if hunks[0].PatchID == "aaaa0001" {
+		t.Error("Expected patch IDs to be recalculated for another patch")
+	}
+}
+
+func TestSessionStatus(t *testing.T) {
+	const stagedOn = "1111111111111111111111111111111111111111"
+	mock := newSessionMock(t, stagedOn)
+	s := &Stager{executor: mock, logger: logger.NewFromEnv()}
+	ctx := context.Background()
+
+	allHunks, err := s.preparePatchData(ctx, threeHunkDiff)
+	if err != nil {
+		t.Fatalf("Failed to prepare patch data: %v", err)
+	}
+	session := newSession(threeHunkDiff, allHunks)
+	for i := range session.Hunks {
+		session.Hunks[i].StagedOn = stagedOn
+	}
+	if err := s.saveSession(ctx, session); err != nil {
+		t.Fatalf("saveSession failed: %v", err)
+	}
+
+	// Hunk 1 was committed and HEAD moved; hunk 2 is still staged; hunk 3 was unstaged
+	// again before the commit
+	hunk1, err := s.extractHunkContent(&allHunks[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	hunk2, err := s.extractHunkContent(&allHunks[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.Commands["git [rev-parse HEAD]"] = executor.MockResponse{Output: []byte("2222222222222222222222222222222222222222\n")}
+	mock.Commands["git [diff --cached]"] = executor.MockResponse{Output: hunk2}
+	mock.Commands["git [diff --end-of-options "+stagedOn+" HEAD]"] = executor.MockResponse{Output: hunk1}
+
+	_, statuses, err := s.SessionStatus(ctx)
+	if err != nil {
+		t.Fatalf("SessionStatus failed: %v", err)
+	}
+	expected := []SessionHunkState{SessionHunkCommitted, SessionHunkStaged, SessionHunkUnstaged}
+	for i, status := range statuses {
+		if status.State != expected[i] {
+			t.Errorf("Hunk %d: expected %v, got %v", status.IndexInFile, expected[i], status.State)
+		}
+	}
+}
+
+func TestSessionStatus_NoSession(t *testing.T) {
+	s := &Stager{executor: newSessionMock(t, "1111111111111111111111111111111111111111"), logger: logger.NewFromEnv()}
+
+	session, statuses, err := s.SessionStatus(context.Background())
+	if err != nil || session != nil || statuses != nil {
+		t.Errorf("Expected no session, got %v, %v, %v", session, statuses, err)
+	}
+}
@@ -0,0 +1,197 @@
+package stager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+// PatchPart is a standalone patch produced by SplitPatch
+type PatchPart struct {
+	Files   []string // Files in the patch, in patch order
+	Content []byte   // Patch content accepted by "git apply"
+}
+
+// splitFile collects the selected hunks of one file in a patch part
+type splitFile struct {
+	file      *gitdiff.File
+	first     int                           // Lowest GlobalIndex, used to keep the patch order
+	whole     bool                          // Write the whole file diff (binary files and file operations without content)
+	fragments map[int]*gitdiff.TextFragment // IndexInFile -> fragment (reduced for line selections)
+}
+
+// SplitPatch builds one standalone patch per group of hunk specifications without touching
+// the repository. Each group accepts the same specifications as staging, including "file:*".
+// With perFile, every file of a group is written to a patch part of its own.
+// Later hunks of a file are renumbered so that each part applies to the original tree on its own.
+func (s *Stager) SplitPatch(ctx context.Context, patchContent string, groups [][]string, perFile bool) ([]PatchPart, error) {
+	allHunks, err := ParsePatchFileWithGitDiff(patchContent)
+	if err != nil {
+		return nil, NewParsingError("patch file", err)
+	}
+
+	// Patch IDs are only needed to resolve id: selectors
+	for _, group := range groups {
+		if hasIDSelectors(group) {
+			if err := s.calculatePatchIDsForHunks(ctx, allHunks); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	var parts []PatchPart
+	for _, group := range groups {
+		files, err := collectSplitFiles(group, allHunks)
+		if err != nil {
+			return nil, err
+		}
+
+		if perFile {
+			for _, file := range files {
+				part, err := buildPatchPart([]*splitFile{file})
+				if err != nil {
+					return nil, err
+				}
+				parts = append(parts, part)
+			}
+			continue
+		}
+
+		part, err := buildPatchPart(files)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+	}
+
+	return parts, nil
+}
+
+// collectSplitFiles resolves a group of hunk specifications into the selected hunks of each file, in patch order
+func collectSplitFiles(group []string, allHunks []HunkInfo) ([]*splitFile, error) {
+	specs, err := resolveIDSelectors(group, allHunks)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]*splitFile)
+	add := func(hunk HunkInfo, fragment *gitdiff.TextFragment) {
+		file, exists := files[hunk.FilePath]
+		if !exists {
+			file = &splitFile{file: hunk.File, first: hunk.GlobalIndex, fragments: make(map[int]*gitdiff.TextFragment)}
+			files[hunk.FilePath] = file
+		}
+		if hunk.GlobalIndex < file.first {
+			file.first = hunk.GlobalIndex
+		}
+		if fragment == nil {
+			file.whole = true
+			return
+		}
+		file.fragments[hunk.IndexInFile] = fragment
+	}
+
+	var normalSpecs []string
+	for _, spec := range specs {
+		filePath, wildcard := strings.CutSuffix(spec, ":*")
+		if !wildcard {
+			normalSpecs = append(normalSpecs, spec)
+			continue
+		}
+
+		found := false
+		for _, hunk := range allHunks {
+			if hunk.FilePath == filePath || hunk.OldFilePath == filePath {
+				add(hunk, hunk.Fragment)
+				found = true
+			}
+		}
+		if !found {
+			return nil, NewHunkNotFoundError(fmt.Sprintf("file %s not found in patch", filePath), nil)
+		}
+	}
+
+	targets, err := buildTargets(normalSpecs, allHunks)
+	if err != nil {
+		return nil, err
+	}
+	for _, target := range targets {
+		if target.Lines == nil {
+			add(target.Hunk, target.Hunk.Fragment)
+			continue
+		}
+		reduced, err := reduceFragment(target.Hunk.Fragment, target.Lines)
+		if err != nil {
+			return nil, err
+		}
+		add(target.Hunk, reduced)
+	}
+
+	ordered := make([]*splitFile, 0, len(files))
+	for _, file := range files {
+		ordered = append(ordered, file)
+	}
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].first < ordered[j].first })
+	return ordered, nil
+}
+
+// buildPatchPart writes the selected hunks of the given files as one patch
+func buildPatchPart(files []*splitFile) (PatchPart, error) {
+	var part PatchPart
+	var content strings.Builder
+
+	for _, file := range files {
+		name := file.file.NewName
+		if file.file.IsDelete {
+			name = file.file.OldName
+		}
+		part.Files = append(part.Files, name)
+
+		if file.whole || len(file.fragments) == 0 {
+			if file.file.IsBinary && file.file.BinaryFragment == nil {
+				return PatchPart{}, NewInvalidArgumentError(fmt.Sprintf("binary file %s has no binary data in the patch; generate the patch with 'git diff --binary'", name), nil)
+			}
+			content.WriteString(file.file.String())
+			continue
+		}
+
+		content.Write(generateFragmentsPatch(file.file, renumberFragments(file.fragments)))
+	}
+
+	part.Content = []byte(content.String())
+	return part, nil
+}
+
+// renumberFragments returns the fragments in file order with their new line positions
+// recomputed as if only these fragments had been applied to the old file
+func renumberFragments(fragments map[int]*gitdiff.TextFragment) []*gitdiff.TextFragment {
+	indexes := make([]int, 0, len(fragments))
+	for index := range fragments {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	result := make([]*gitdiff.TextFragment, 0, len(indexes))
+	offset := int64(0)
+	for _, index := range indexes {
+		fragment := *fragments[index]
+
+		// An empty side of a hunk points at the line before the change
+		oldStart := fragment.OldPosition
+		if fragment.OldLines == 0 {
+			oldStart++
+		}
+		fragment.NewPosition = oldStart + offset
+		if fragment.NewLines == 0 {
+			fragment.NewPosition--
+		}
+
+		offset += fragment.NewLines - fragment.OldLines
+		result = append(result, &fragment)
+	}
+	return result
+}
@@ -0,0 +1,76 @@
+package stager
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/internal/executor"
+	"github.com/syou6162/git-sequential-stage/internal/logger"
+)
+
+const unevenHunkDiff = `diff --git a/file.txt b/file.txt
+index 1111111..2222222 100644
+--- a/file.txt
++++ b/file.txt
+@@ -1,3 +1,5 @@
+ line1
++added1
++added2
+ line2
+ line3
+@@ -10,3 +12,2 @@
+ line10
+-line11
+ line12
+@@ -20,3 +21,3 @@
+ line20
+-line21
++line21 changed
+ line22
+`
+
+func TestSplitPatch_RenumbersLaterHunks(t *testing.T) {
+	mock := executor.NewMockCommandExecutor()
+	s := &Stager{executor: mock, logger: logger.NewFromEnv()}
+
+	parts, err := s.SplitPatch(context.Background(), unevenHunkDiff, [][]string{{"file.txt:3"}, {"file.txt:2,3"}}, false)
+	if err != nil {
+		t.Fatalf("SplitPatch failed: %v", err)
+	}
+	if len(parts) != 2 {
+		t.Fatalf("Expected 2 parts, got %d", len(parts))
+	}
+
+	if !strings.Contains(string(parts[0].Content), "@@ -20,3 +20,3 @@") {
+		t.Errorf("Expected hunk 3 alone to start at line 20 of the new file:\n%s", parts[0].Content)
+	}
+	second := string(parts[1].Content)
+	for _, want := range []string{"@@ -10,3 +10,2 @@", "@@ -20,3 +19,3 @@"} {
+		if !strings.Contains(second, want) {
+			t.Errorf("Expected %q in the second part:\n%s", want, second)
+		}
+	}
+	if strings.Count(second, "diff --git") != 1 {
+		t.Errorf("Expected one file header in the second part:\n%s", second)
+	}
+	if len(mock.ExecutedCommands) != 0 {
+		t.Errorf("Expected no git commands, got %v", mock.ExecutedCommands)
+	}
+}
+
+func TestSplitPatch_PerFile(t *testing.T) {
+	s := &Stager{executor: executor.NewMockCommandExecutor(), logger: logger.NewFromEnv()}
+	diff := unevenHunkDiff + strings.ReplaceAll(threeHunkDiff, "file.txt", "other.txt")
+
+	parts, err := s.SplitPatch(context.Background(), diff, [][]string{{"other.txt:1", "file.txt:*"}}, true)
+	if err != nil {
+		t.Fatalf("SplitPatch failed: %v", err)
+	}
+	if len(parts) != 2 || parts[0].Files[0] != "file.txt" || parts[1].Files[0] != "other.txt" {
+		t.Fatalf("Expected file.txt and other.txt parts in patch order, got %+v", parts)
+	}
+	if strings.Count(string(parts[0].Content), "@@ ") != 3 {
+		t.Errorf("Expected every hunk of file.txt:\n%s", parts[0].Content)
+	}
+}
@@ -0,0 +1,118 @@
+package stager
+
+// HunkStatus represents the outcome of staging a single hunk
+type HunkStatus int
+
+const (
+	// HunkStatusSkipped indicates the hunk was not applied
+	HunkStatusSkipped HunkStatus = iota
+	// HunkStatusApplied indicates the hunk was applied to the staging area
+	HunkStatusApplied
+	// HunkStatusRolledBack indicates the hunk was applied but the staging area was
+	// restored afterwards because a later hunk failed
+	HunkStatusRolledBack
+)
+
+// String returns the string representation of HunkStatus
+func (hs HunkStatus) String() string {
+	switch hs {
+	case HunkStatusSkipped:
+		return "skipped"
+	case HunkStatusApplied:
+		return "applied"
+	case HunkStatusRolledBack:
+		return "rolled-back"
+	default:
+		return "unknown"
+	}
+}
+
+// ApplyStrategy represents the strategy that successfully applied a hunk
+type ApplyStrategy int
+
+const (
+	// ApplyStrategyNone indicates no strategy was used (the hunk was not applied)
+	ApplyStrategyNone ApplyStrategy = iota
+	// ApplyStrategyCached indicates a plain "git apply --cached"
+	ApplyStrategyCached
+	// ApplyStrategyResetApply indicates "git reset" of the file followed by "git apply --cached" (git mv scenarios)
+	ApplyStrategyResetApply
+	// ApplyStrategyWorkingDirectory indicates "git apply" to the working directory
+	ApplyStrategyWorkingDirectory
+)
+
+// String returns the string representation of ApplyStrategy
+func (as ApplyStrategy) String() string {
+	switch as {
+	case ApplyStrategyNone:
+		return "none"
+	case ApplyStrategyCached:
+		return "cached"
+	case ApplyStrategyResetApply:
+		return "reset-apply"
+	case ApplyStrategyWorkingDirectory:
+		return "working-directory"
+	default:
+		return "unknown"
+	}
+}
+
+// HunkResult describes what happened to a single requested hunk
+type HunkResult struct {
+	FilePath    string        // File path of the hunk in the patch file
+	IndexInFile int           // Hunk number within the file in the patch file
+	PatchID     string        // Patch ID used to track the hunk
+	Status      HunkStatus    // Whether the hunk was applied
+	Strategy    ApplyStrategy // Strategy that applied the hunk (ApplyStrategyNone if skipped)
+	Partial     bool          // Whether only selected lines of the hunk were requested
+
+	// Set when the hunk was matched by similarity in fuzzy mode instead of by patch ID
+	MatchedPatchID string  // Patch ID of the current hunk that was staged instead
+	Confidence     float64 // Similarity of the match, from minFuzzyConfidence to 1
+}
+
+// StageResult reports the outcome of a staging run.
+// Hunks are listed in the order they were requested, not in the order they were applied.
+type StageResult struct {
+	Hunks      []HunkResult
+	Files      []string // Files staged as a whole (wildcard specifications)
+	RolledBack bool     // Whether the staging area was restored after a failure
+}
+
+// newStageResult creates a result with every target marked as skipped
+func newStageResult(targets []hunkTarget) *StageResult {
+	result := &StageResult{Hunks: make([]HunkResult, len(targets))}
+	for i, target := range targets {
+		result.Hunks[i] = HunkResult{
+			FilePath:    target.Hunk.FilePath,
+			IndexInFile: target.Hunk.IndexInFile,
+			PatchID:     target.PatchID,
+			Partial:     target.Lines != nil,
+			Status:      HunkStatusSkipped,
+			Strategy:    ApplyStrategyNone,
+		}
+	}
+	return result
+}
+
+// markFuzzy records that the target at index i was matched by similarity to the current hunk matchedID
+func (r *StageResult) markFuzzy(i int, matchedID string, confidence float64) {
+	r.Hunks[i].MatchedPatchID = matchedID
+	r.Hunks[i].Confidence = confidence
+}
+
+// markApplied records that the target at index i was applied using the given strategy
+func (r *StageResult) markApplied(i int, strategy ApplyStrategy) {
+	r.Hunks[i].Status = HunkStatusApplied
+	r.Hunks[i].Strategy = strategy
+}
+
+// MarkRolledBack records that the staging area was restored to its state before staging
+func (r *StageResult) MarkRolledBack() {
+	r.RolledBack = true
+	for i := range r.Hunks {
+		if r.Hunks[i].Status == HunkStatusApplied {
+			r.Hunks[i].Status = HunkStatusRolledBack
+		}
+	}
+}
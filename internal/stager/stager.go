@@ -7,8 +7,10 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"sort"
 	"strings"
 
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
 	"github.com/syou6162/git-sequential-stage/internal/executor"
 	"github.com/syou6162/git-sequential-stage/internal/logger"
 )
@@ -17,16 +19,67 @@ import (
 // It provides functionality to selectively stage specific hunks identified by patch IDs,
 // solving the "hunk number drift" problem that occurs with dependent changes.
 type Stager struct {
-	executor executor.CommandExecutor
-	logger   *logger.Logger
+	executor     executor.CommandExecutor
+	logger       *logger.Logger
+	options      Options
+	patchIDCache map[string]string // Patch content -> patch ID
+	unstaging    bool              // Hunks come from "git diff --cached" and are applied in reverse
+	snapshots    []*IndexSnapshot  // Snapshots taken so far; working tree writes are saved in each
+}
+
+// Options configures optional behavior of a Stager.
+// The zero value gives the same behavior as NewStager.
+type Options struct {
+	// DryRun guarantees that the working tree is never modified, even with AllowWorktreeWrite.
+	DryRun bool
+
+	// AllowWorktreeWrite enables the last-resort fallback that applies a hunk to the
+	// working tree with plain "git apply" when it cannot be applied to the index.
+	// Hunks applied this way are reported with ApplyStrategyWorkingDirectory.
+	// The files written are saved in every IndexSnapshot taken before, so a rollback
+	// (a failed hunk, a failed Verify or IndexSnapshot.Restore) puts them back as well.
+	// Without it, only the index is ever written.
+	AllowWorktreeWrite bool
+
+	// GitPatchID calculates patch IDs with "git patch-id --stable" instead of the
+	// built-in implementation (e.g. for repositories that use SHA-256 object names).
+	GitPatchID bool
+
+	// Fuzzy matches target hunks whose patch ID is not in the current diff anymore to the
+	// most similar current hunk (same file, overlapping lines) and stages its current version.
+	// Every such match is reported in the result with its confidence.
+	Fuzzy bool
+
+	// SafetyPolicy decides which changes may already be staged before staging starts.
+	// The zero value is SafetyPolicyTargetsOnly.
+	SafetyPolicy SafetyPolicy
+
+	// Verify compares the staged diff with the requested hunks after staging and fails
+	// with a VerificationError (restoring the staging area) if anything is missing or extra.
+	Verify bool
+
+	// Base is the revision the current diff is taken against ("git diff <base>") when
+	// hunks are matched by patch ID; empty means HEAD. If it is set, the old blobs of the
+	// patch are also checked against it before staging.
+	Base string
+
+	// Session reuses the patch IDs recorded in the session manifest for the same patch
+	// (see Session) instead of recalculating them. Runs are recorded with RecordSession.
+	Session bool
 }
 
 // NewStager creates a new Stager instance with the provided command executor.
 // The executor is used to run Git commands.
 func NewStager(exec executor.CommandExecutor) *Stager {
+	return NewStagerWithOptions(exec, Options{})
+}
+
+// NewStagerWithOptions creates a new Stager instance with the provided command executor and options.
+func NewStagerWithOptions(exec executor.CommandExecutor, opts Options) *Stager {
 	return &Stager{
 		executor: exec,
 		logger:   logger.NewFromEnv(),
+		options:  opts,
 	}
 }
 
@@ -53,12 +106,31 @@ func (s *Stager) extractHunkContent(hunk *HunkInfo) ([]byte, error) {
 	return nil, fmt.Errorf("fragment or file object is nil for %s", hunk.FilePath)
 }
 
+// extractSelectedHunkContent generates a patch containing only the selected lines of a hunk
+func (s *Stager) extractSelectedHunkContent(hunk *HunkInfo, lines []int) ([]byte, error) {
+	if hunk.Fragment == nil || hunk.File == nil {
+		return nil, fmt.Errorf("fragment or file object is nil for %s", hunk.FilePath)
+	}
+
+	reduced, err := reduceFragment(hunk.Fragment, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	partial := *hunk
+	partial.Fragment = reduced
+	return s.generateHunkPatch(&partial)
+}
+
 // generateHunkPatch generates a patch for a single hunk using go-gitdiff objects
 func (s *Stager) generateHunkPatch(hunk *HunkInfo) ([]byte, error) {
-	var result strings.Builder
+	return generateFragmentsPatch(hunk.File, []*gitdiff.TextFragment{hunk.Fragment}), nil
+}
 
-	file := hunk.File
-	fragment := hunk.Fragment
+// generateFragmentsPatch generates a patch for the given fragments of a file.
+// Fragments must be in file order.
+func generateFragmentsPatch(file *gitdiff.File, fragments []*gitdiff.TextFragment) []byte {
+	var result strings.Builder
 
 	// Write file header
 	result.WriteString(fmt.Sprintf("diff --git a/%s b/%s\n", file.OldName, file.NewName))
@@ -98,44 +170,58 @@ func (s *Stager) generateHunkPatch(hunk *HunkInfo) ([]byte, error) {
 		result.WriteString(fmt.Sprintf("+++ b/%s\n", file.NewName))
 	}
 
-	// Write the fragment
-	result.WriteString(fragment.String())
+	// Write the fragments
+	for _, fragment := range fragments {
+		result.WriteString(fragment.String())
+	}
 
-	return []byte(result.String()), nil
+	return []byte(result.String())
 }
 
+// fallbackPatchIDPrefix starts the patch IDs of hunks whose patch ID cannot be calculated
+const fallbackPatchIDPrefix = "unknown-"
+
 // setFallbackPatchID sets a fallback patch ID for a hunk when calculation fails
 func setFallbackPatchID(hunk *HunkInfo) {
-	hunk.PatchID = fmt.Sprintf("unknown-%d", hunk.GlobalIndex)
+	hunk.PatchID = fmt.Sprintf("%s%d", fallbackPatchIDPrefix, hunk.GlobalIndex)
+}
+
+// isFallbackPatchID reports whether patchID was set by setFallbackPatchID.
+// Fallback IDs only number the hunks of one patch, so they cannot be matched across diffs.
+func isFallbackPatchID(patchID string) bool {
+	return strings.HasPrefix(patchID, fallbackPatchIDPrefix)
 }
 
 // calculatePatchIDsForHunks calculates patch IDs for all hunks in the list
 func (s *Stager) calculatePatchIDsForHunks(ctx context.Context, allHunks []HunkInfo) error {
+	contents := make([][]byte, len(allHunks))
 	for i := range allHunks {
 		hunkContent, err := s.extractHunkContent(&allHunks[i])
 		if err != nil {
 			// Continue without this hunk
 			s.logger.Debug("Failed to extract hunk content for hunk %d: %v", allHunks[i].GlobalIndex, err)
-			setFallbackPatchID(&allHunks[i])
 			continue
 		}
+		contents[i] = hunkContent
+	}
 
-		if len(hunkContent) == 0 {
-			setFallbackPatchID(&allHunks[i])
-			continue
+	patchIDs, err := s.calculatePatchIDs(ctx, contents)
+	if err != nil {
+		// Check if context was canceled or timed out
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return ctx.Err()
 		}
+		// Continue without patch IDs for other errors
+		s.logger.Debug("Failed to calculate patch IDs: %v", err)
+		patchIDs = make([]string, len(allHunks))
+	}
 
-		patchID, err := s.calculatePatchIDStable(ctx, hunkContent)
-		if err != nil {
-			// Check if context was canceled or timed out
-			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-				return ctx.Err()
-			}
-			// Continue without patch ID for other errors
+	for i := range allHunks {
+		if patchIDs[i] == "" {
 			setFallbackPatchID(&allHunks[i])
-		} else {
-			allHunks[i].PatchID = patchID
+			continue
 		}
+		allHunks[i].PatchID = patchIDs[i]
 	}
 
 	return nil
@@ -145,7 +231,7 @@ func (s *Stager) calculatePatchIDsForHunks(ctx context.Context, allHunks []HunkI
 func collectTargetFiles(hunkSpecs []string) (map[string]bool, error) {
 	targetFiles := make(map[string]bool)
 	for _, spec := range hunkSpecs {
-		filePath, _, err := ParseHunkSpec(spec)
+		filePath, _, err := ParseHunkSelectors(spec)
 		if err != nil {
 			return nil, err
 		}
@@ -154,11 +240,20 @@ func collectTargetFiles(hunkSpecs []string) (map[string]bool, error) {
 	return targetFiles, nil
 }
 
-// buildTargetIDs builds a list of patch IDs from hunk specifications
-func buildTargetIDs(hunkSpecs []string, allHunks []HunkInfo) ([]string, error) {
+// hunkTarget is a hunk from the patch file that has been requested for staging
+type hunkTarget struct {
+	PatchID string   // Patch ID used to find the hunk in the current diff
+	Hunk    HunkInfo // Hunk as it appears in the patch file
+	Lines   []int    // Selected lines of the hunk body (1-based); nil stages the whole hunk
+}
+
+// buildTargets builds the list of hunks to stage from hunk specifications.
+// A hunk requested more than once becomes a single target whose line selection
+// is the union of all requests.
+func buildTargets(hunkSpecs []string, allHunks []HunkInfo) ([]hunkTarget, error) {
 	// Build maps for O(1) lookup performance
 	fileHunkCounts := make(map[string]int)
-	fileHunkMap := make(map[string]map[int]string) // file -> (hunkIndex -> patchID)
+	fileHunkMap := make(map[string]map[int]HunkInfo) // file -> (hunkIndex -> hunk)
 
 	// Single pass to build both maps - O(H)
 	for _, hunk := range allHunks {
@@ -167,16 +262,31 @@ func buildTargetIDs(hunkSpecs []string, allHunks []HunkInfo) ([]string, error) {
 			fileHunkCounts[hunk.FilePath] = hunk.IndexInFile
 		}
 
-		// Build file->hunk->patchID map for O(1) lookup
+		// Build file->hunk map for O(1) lookup
 		if fileHunkMap[hunk.FilePath] == nil {
-			fileHunkMap[hunk.FilePath] = make(map[int]string)
+			fileHunkMap[hunk.FilePath] = make(map[int]HunkInfo)
+		}
+		fileHunkMap[hunk.FilePath][hunk.IndexInFile] = hunk
+	}
+
+	var targets []hunkTarget
+	targetIndex := make(map[int]int) // GlobalIndex -> position in targets
+	addTarget := func(hunk HunkInfo, lines []int) {
+		if i, exists := targetIndex[hunk.GlobalIndex]; exists {
+			whole := targets[i].Lines == nil || lines == nil
+			merged := mergeSelectedLines(targets[i].Lines, lines, whole)
+			if merged != nil && selectsAllChanges(hunk.Fragment, merged) {
+				merged = nil
+			}
+			targets[i].Lines = merged
+			return
 		}
-		fileHunkMap[hunk.FilePath][hunk.IndexInFile] = hunk.PatchID
+		targetIndex[hunk.GlobalIndex] = len(targets)
+		targets = append(targets, hunkTarget{PatchID: hunk.PatchID, Hunk: hunk, Lines: lines})
 	}
 
-	var targetIDs []string
 	for _, spec := range hunkSpecs {
-		filePath, hunkNumbers, err := ParseHunkSpec(spec)
+		filePath, selectors, err := ParseHunkSelectors(spec)
 		if err != nil {
 			return nil, err
 		}
@@ -191,33 +301,96 @@ func buildTargetIDs(hunkSpecs []string, allHunks []HunkInfo) ([]string, error) {
 			return nil, NewHunkNotFoundError(fullMessage, nil)
 		}
 
-		// Check for hunk numbers that exceed available hunks
-		var invalidHunks []int
-		for _, hunkNum := range hunkNumbers {
-			if hunkNum > maxHunks {
-				invalidHunks = append(invalidHunks, hunkNum)
-			}
-		}
-
-		if len(invalidHunks) > 0 {
-			return nil, NewHunkCountExceededError(filePath, maxHunks, invalidHunks)
+		// Resolve ranges and check for hunk numbers that exceed available hunks
+		selectors, err = ResolveHunkSelectors(filePath, selectors, maxHunks)
+		if err != nil {
+			return nil, err
 		}
 
 		// Find matching hunks using O(1) map lookup - O(N) total
 		hunkLookup := fileHunkMap[filePath]
-		for _, hunkNum := range hunkNumbers {
-			patchID, found := hunkLookup[hunkNum]
+		for _, selector := range selectors {
+			if len(selector.FileLines) > 0 {
+				found := false
+				for hunkNum := 1; hunkNum <= maxHunks; hunkNum++ {
+					hunk := hunkLookup[hunkNum]
+					if hunk.Fragment == nil {
+						continue
+					}
+					lines := selectFileLines(hunk.Fragment, selector.FileLines)
+					if len(lines) == 0 {
+						continue
+					}
+					lines, err := normalizeLineSelection(hunk, lines)
+					if err != nil {
+						return nil, err
+					}
+					addTarget(hunk, lines)
+					found = true
+				}
+				if !found {
+					lineRange := selector.FileLines[0]
+					return nil, NewHunkNotFoundError(fmt.Sprintf("changed lines L%d-L%d in file %s", lineRange.Start, lineRange.End, filePath), nil)
+				}
+				continue
+			}
+
+			hunk, found := hunkLookup[selector.Hunk]
 			if !found {
-				return nil, NewHunkNotFoundError(fmt.Sprintf("hunk %d in file %s", hunkNum, filePath), nil)
+				return nil, NewHunkNotFoundError(fmt.Sprintf("hunk %d in file %s", selector.Hunk, filePath), nil)
+			}
+
+			if len(selector.Lines) == 0 {
+				addTarget(hunk, nil)
+				continue
+			}
+
+			if hunk.Fragment == nil {
+				return nil, NewInvalidArgumentError(fmt.Sprintf("hunk %d in file %s has no lines to select", selector.Hunk, filePath), nil)
+			}
+			lines, err := selectHunkLines(hunk.Fragment, selector.Lines)
+			if err != nil {
+				return nil, NewInvalidArgumentError(fmt.Sprintf("invalid line selection for hunk %d in file %s", selector.Hunk, filePath), err)
 			}
-			targetIDs = append(targetIDs, patchID)
+			if len(lines) == 0 {
+				return nil, NewInvalidArgumentError(fmt.Sprintf("no changed lines selected in hunk %d of file %s", selector.Hunk, filePath), nil)
+			}
+			lines, err = normalizeLineSelection(hunk, lines)
+			if err != nil {
+				return nil, err
+			}
+			addTarget(hunk, lines)
 		}
 	}
-	return targetIDs, nil
+	return targets, nil
 }
 
-// performSafetyChecks checks the safety of the staging area using hybrid approach
+// normalizeLineSelection returns nil if the selection covers the whole hunk.
+// Deleted files cannot be staged partially because the deletion removes the whole file.
+func normalizeLineSelection(hunk HunkInfo, lines []int) ([]int, error) {
+	if selectsAllChanges(hunk.Fragment, lines) {
+		return nil, nil
+	}
+	if hunk.File != nil && hunk.File.IsDelete {
+		return nil, NewInvalidArgumentError(fmt.Sprintf("cannot stage part of the deletion of %s", hunk.FilePath), nil)
+	}
+	return lines, nil
+}
+
+// performSafetyChecks checks the safety of the staging area using hybrid approach.
+// The evaluation is interpreted according to the configured safety policy.
 func (s *Stager) performSafetyChecks(patchContent string, targetFiles map[string]bool) error {
+	policy := s.options.SafetyPolicy
+	if policy == SafetyPolicyOff {
+		s.logger.Debug("Safety checks disabled by safety policy %s", policy)
+		return nil
+	}
+
+	// Under the strict policy, staged target files are not exempt
+	if policy == SafetyPolicyStrict {
+		targetFiles = nil
+	}
+
 	// Use hybrid approach: patch-first with git command fallback
 	checker := NewSafetyChecker(".")
 	evaluation, err := checker.EvaluateWithFallbackAndTargets(patchContent, targetFiles)
@@ -236,6 +409,12 @@ func (s *Stager) performSafetyChecks(patchContent string, targetFiles map[string
 		return nil
 	}
 
+	// Changes staged beforehand are kept under the allow-preexisting policy
+	if policy == SafetyPolicyAllowPreexisting {
+		s.logger.Info("Keeping changes that were already staged (safety policy %s): %v", policy, evaluation.StagedFiles)
+		return nil
+	}
+
 	// Not clean and not allowed to continue
 	if !evaluation.IsClean {
 		return s.generateDetailedStagingError(evaluation)
@@ -281,12 +460,14 @@ func (s *Stager) generateDetailedStagingError(evaluation *StagingAreaEvaluation)
 // This is used for wildcard specifications (file:*).
 func (s *Stager) StageFiles(ctx context.Context, files []string) error {
 	for _, file := range files {
-		// Check if file exists
+		// Check if file exists; a deleted file can still be staged if git tracks it
 		if _, err := os.Stat(file); os.IsNotExist(err) {
-			return NewFileNotFoundError(file, err)
+			if _, lsErr := s.executor.Execute(ctx, "git", "ls-files", "--error-unmatch", "--", file); lsErr != nil {
+				return NewFileNotFoundError(file, err)
+			}
 		}
 
-		// Stage the entire file
+		// Stage the entire file (or its deletion)
 		if _, err := s.executor.Execute(ctx, "git", "add", file); err != nil {
 			return NewGitCommandError(fmt.Sprintf("git add %s", file), err)
 		}
@@ -301,78 +482,390 @@ func (s *Stager) StageFiles(ctx context.Context, files []string) error {
 // hunkSpecs should be in the format "file:hunk_numbers" (e.g., "main.go:1,3").
 // The function uses patch IDs to track hunks across changes, solving the drift problem.
 func (s *Stager) StageHunks(ctx context.Context, hunkSpecs []string, patchFile string) error {
+	_, err := s.StageHunksWithResult(ctx, hunkSpecs, patchFile)
+	return err
+}
+
+// StageHunksWithResult works like StageHunks and additionally reports the outcome of every requested hunk.
+// The result is non-nil whenever the requested hunks could be resolved, even if staging failed partway,
+// so callers can report which hunks were applied before the failure.
+func (s *Stager) StageHunksWithResult(ctx context.Context, hunkSpecs []string, patchFile string) (*StageResult, error) {
+	return s.StageSelectionWithResult(ctx, Selection{HunkSpecs: hunkSpecs}, patchFile)
+}
+
+// Selection describes the hunks requested for staging
+type Selection struct {
+	HunkSpecs []string // "file:numbers" and "id:<patch ID>" specifications
+	Matches   []string // "<file-glob>:<regex>" specifications selecting hunks by content
+	Excludes  []string // "file:numbers" specifications of hunks not to stage
+}
+
+// StageSelectionWithResult works like StageHunksWithResult for a selection that may
+// also select hunks by content. The hunks selected by either way are staged together.
+func (s *Stager) StageSelectionWithResult(ctx context.Context, selection Selection, patchFile string) (*StageResult, error) {
 	// Phase 0: Safety checks (always enabled)
 	patchContent, err := os.ReadFile(patchFile)
 	if err != nil {
-		return NewFileNotFoundError(patchFile, err)
+		return nil, NewFileNotFoundError(patchFile, err)
+	}
+	if err := s.validatePatchBase(ctx, string(patchContent)); err != nil {
+		return nil, err
+	}
+
+	// "id:" and match selectors need the parsed patch to know which files they target
+	hunkSpecs := selection.HunkSpecs
+	var allHunks []HunkInfo
+	if hasIDSelectors(hunkSpecs) || len(selection.Matches) > 0 {
+		allHunks, err = s.preparePatchData(ctx, string(patchContent))
+		if err != nil {
+			return nil, err
+		}
+		hunkSpecs, err = resolveIDSelectors(hunkSpecs, allHunks)
+		if err != nil {
+			return nil, err
+		}
+		matchedSpecs, err := resolveMatchSelectors(selection.Matches, allHunks)
+		if err != nil {
+			return nil, err
+		}
+		hunkSpecs = append(hunkSpecs, matchedSpecs...)
+
+		// Hunks selected by patch ID or content can be excluded as well
+		if len(selection.Excludes) > 0 {
+			hunkSpecs, err = ApplyExclusions(hunkSpecs, selection.Excludes, false, allHunks)
+			if err != nil {
+				return nil, err
+			}
+			if len(hunkSpecs) == 0 {
+				return nil, NewInvalidArgumentError("every selected hunk is excluded", nil)
+			}
+		}
 	}
 
 	// Get target files for safety check
 	targetFiles, err := collectTargetFiles(hunkSpecs)
 	if err != nil {
-		return NewInvalidArgumentError("failed to collect target files", err)
+		return nil, NewInvalidArgumentError("failed to collect target files", err)
 	}
 
 	if err := s.performSafetyChecks(string(patchContent), targetFiles); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Phase 1: Preparation
-	allHunks, err := s.preparePatchData(ctx, patchFile)
+	if allHunks == nil {
+		allHunks, err = s.preparePatchData(ctx, string(patchContent))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.stageTargets(ctx, hunkSpecs, allHunks, targetFiles)
+}
+
+// PreparedPatch is a parsed patch file with patch IDs computed for every hunk.
+// It can be reused for several staging runs against the same patch file,
+// e.g. when creating multiple commits from a single patch.
+type PreparedPatch struct {
+	Content string     // Raw patch content
+	Hunks   []HunkInfo // Parsed hunks with patch IDs
+}
+
+// PreparePatch reads and parses a patch file and calculates patch IDs for all of its hunks
+func (s *Stager) PreparePatch(ctx context.Context, patchFile string) (*PreparedPatch, error) {
+	content, err := os.ReadFile(patchFile)
 	if err != nil {
-		return err
+		return nil, NewFileNotFoundError(patchFile, err)
+	}
+	if err := s.validatePatchBase(ctx, string(content)); err != nil {
+		return nil, err
+	}
+
+	allHunks, err := s.preparePatchData(ctx, string(content))
+	if err != nil {
+		return nil, err
+	}
+
+	return &PreparedPatch{Content: string(content), Hunks: allHunks}, nil
+}
+
+// StagePreparedHunks stages the specified hunks of a prepared patch.
+// It performs the same safety checks as StageHunks but reuses the patch IDs
+// computed by PreparePatch instead of recalculating them.
+func (s *Stager) StagePreparedHunks(ctx context.Context, patch *PreparedPatch, hunkSpecs []string) (*StageResult, error) {
+	hunkSpecs, err := resolveIDSelectors(hunkSpecs, patch.Hunks)
+	if err != nil {
+		return nil, err
+	}
+
+	targetFiles, err := collectTargetFiles(hunkSpecs)
+	if err != nil {
+		return nil, NewInvalidArgumentError("failed to collect target files", err)
+	}
+
+	if err := s.performSafetyChecks(patch.Content, targetFiles); err != nil {
+		return nil, err
+	}
+
+	return s.stageTargets(ctx, hunkSpecs, patch.Hunks, targetFiles)
+}
+
+// stageTargets resolves hunk specifications against the parsed patch and
+// runs the sequential staging loop (Phase 2).
+// Staging is atomic: if any hunk fails or the context is cancelled, the index
+// is restored to its state before Phase 2, so no hunk of this call stays staged.
+func (s *Stager) stageTargets(ctx context.Context, hunkSpecs []string, allHunks []HunkInfo, targetFiles map[string]bool) (*StageResult, error) {
+	// Build target list
+	targets, err := buildTargets(hunkSpecs, allHunks)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nothing to stage, so there is nothing to roll back either
+	if len(targets) == 0 {
+		return newStageResult(targets), nil
+	}
+
+	if err := s.checkCommittedSinceBase(ctx, targets); err != nil {
+		return nil, err
+	}
+
+	snapshot, err := s.SnapshotIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var check *stagedDiffCheck
+	if s.options.Verify {
+		check, err = s.newStagedDiffCheck(ctx, targets)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Build target ID list
-	targetIDs, err := buildTargetIDs(hunkSpecs, allHunks)
+	result := newStageResult(targets)
+	err = s.stageTargetsSequentially(ctx, targets, targetFiles, result)
+	if err == nil && check != nil {
+		err = s.verify(ctx, check, targets)
+	}
+	if err != nil {
+		if restoreErr := snapshot.Restore(); restoreErr != nil {
+			s.logger.Error("Failed to restore staging area: %v", restoreErr)
+			return result, errors.Join(err, restoreErr)
+		}
+		s.logger.Info("Restored staging area to its state before staging")
+		result.MarkRolledBack()
+		return result, err
+	}
+
+	return result, nil
+}
+
+// stageTargetsSequentially applies the targets and records the outcome in result.
+// The current diff is loaded once; targets are matched against it by patch ID and
+// applied file by file, so the work is linear in the number of hunks.
+func (s *Stager) stageTargetsSequentially(ctx context.Context, targets []hunkTarget, targetFiles map[string]bool, result *StageResult) error {
+	pending := make([]int, len(targets))
+	for i := range targets {
+		pending[i] = i
+	}
+
+	// Get current diff (reuse targetFiles from Phase 0)
+	diff, err := s.loadCurrentDiff(ctx, targetFiles)
 	if err != nil {
 		return err
 	}
 
+	if s.options.Fuzzy {
+		s.matchFuzzyTargets(diff, targets, result)
+	}
+
 	// Phase 2: Execution - Sequential staging loop
-	for len(targetIDs) > 0 {
-		// Get current diff (reuse targetFiles from Phase 0)
-		diffOutput, err := s.getCurrentDiff(ctx, targetFiles)
-		if err != nil {
+	for len(pending) > 0 {
+		if err := ctx.Err(); err != nil {
 			return err
 		}
 
-		// Parse current diff
-		currentHunks, err := ParsePatchFileWithGitDiff(string(diffOutput))
-		if err != nil {
-			s.logger.Error("Failed to parse current diff: %v", err)
-			return NewParsingError("current diff", err)
+		group := nextFileGroup(diff, targets, pending)
+		if len(group) == 0 {
+			return NewHunkNotFoundError(fmt.Sprintf("hunks with patch IDs: %v", pendingPatchIDs(targets, pending)), nil)
 		}
 
-		// Find and apply matching hunk
-		newTargetIDs, applied, err := s.findAndApplyMatchingHunk(ctx, currentHunks, targetIDs)
+		worktreeChanged, err := s.applyFileGroup(ctx, diff, targets, group, result)
 		if err != nil {
 			return err
 		}
 
-		if !applied {
-			return NewHunkNotFoundError(fmt.Sprintf("hunks with patch IDs: %v", targetIDs), nil)
+		applied := make(map[int]bool, len(group))
+		for _, m := range group {
+			applied[m.target] = true
+			diff.used[m.hunk] = true
+		}
+		remaining := pending[:0]
+		for _, idx := range pending {
+			if !applied[idx] {
+				remaining = append(remaining, idx)
+			}
 		}
+		pending = remaining
 
-		targetIDs = newTargetIDs
+		// A working-directory apply changed the diff of this file
+		if worktreeChanged && len(pending) > 0 {
+			if err := s.reloadFile(ctx, diff, diff.hunks[group[0].hunk].FilePath); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
 }
 
-// preparePatchData prepares patch data by reading and parsing the patch file
-func (s *Stager) preparePatchData(ctx context.Context, patchFile string) ([]HunkInfo, error) {
-	content, err := os.ReadFile(patchFile)
-	if err != nil {
-		return nil, NewFileNotFoundError(patchFile, err)
+// targetMatch is a pending target together with the current hunk it matches
+type targetMatch struct {
+	target int // Position in targets
+	hunk   int // Position in the current diff
+}
+
+// nextFileGroup matches the pending targets against the current diff and returns
+// the matches for the file of the first matched hunk, in diff order.
+// It returns nil if no pending target is found in the current diff.
+func nextFileGroup(diff *currentDiff, targets []hunkTarget, pending []int) []targetMatch {
+	claimed := make(map[int]bool)
+	var matches []targetMatch
+	for _, idx := range pending {
+		if h := diff.find(targets[idx].PatchID, claimed); h >= 0 {
+			claimed[h] = true
+			matches = append(matches, targetMatch{target: idx, hunk: h})
+		}
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].hunk < matches[j].hunk })
+	filePath := diff.hunks[matches[0].hunk].FilePath
+
+	var group []targetMatch
+	for _, m := range matches {
+		if diff.hunks[m.hunk].FilePath == filePath {
+			group = append(group, m)
+		}
+	}
+	return group
+}
+
+// applyFileGroup applies the matched hunks of a single file.
+// Text hunks are applied together with a single "git apply --cached"; if that fails,
+// or for binary and file-operation hunks, each hunk is applied on its own with the
+// fallback strategies. It reports whether the working tree was modified.
+func (s *Stager) applyFileGroup(ctx context.Context, diff *currentDiff, targets []hunkTarget, group []targetMatch, result *StageResult) (bool, error) {
+	if len(group) > 1 {
+		applied, err := s.tryApplyFileGroupAtOnce(ctx, diff, targets, group)
+		if err != nil {
+			return false, err
+		}
+		if applied {
+			for _, m := range group {
+				result.markApplied(m.target, ApplyStrategyCached)
+			}
+			return false, nil
+		}
+	}
+
+	worktreeChanged := false
+	for _, m := range group {
+		hunkContent := diff.contents[m.hunk]
+		if targets[m.target].Lines != nil {
+			// Only the selected lines of the hunk are staged
+			var err error
+			hunkContent, err = s.extractSelectedHunkContent(&diff.hunks[m.hunk], targets[m.target].Lines)
+			if err != nil {
+				return worktreeChanged, err
+			}
+		}
+
+		// Apply the hunk
+		targetID := targets[m.target].PatchID
+		s.logger.Info("Applying hunk with patch ID: %s", targetID)
+		strategy, err := s.applyHunkWithStrategy(ctx, hunkContent, targetID)
+		if err != nil {
+			return worktreeChanged, err
+		}
+		result.markApplied(m.target, strategy)
+		if strategy == ApplyStrategyWorkingDirectory {
+			worktreeChanged = true
+		}
+	}
+
+	return worktreeChanged, nil
+}
+
+// tryApplyFileGroupAtOnce applies all text hunks of the group with one "git apply --cached".
+// It returns false (and no error) if the group cannot be combined or the apply fails,
+// leaving the index unchanged so that the hunks can be applied one by one.
+func (s *Stager) tryApplyFileGroupAtOnce(ctx context.Context, diff *currentDiff, targets []hunkTarget, group []targetMatch) (bool, error) {
+	file := diff.hunks[group[0].hunk].File
+	fragments := make([]*gitdiff.TextFragment, 0, len(group))
+	for _, m := range group {
+		hunk := diff.hunks[m.hunk]
+		if hunk.IsBinary || hunk.Fragment == nil || hunk.File != file {
+			return false, nil
+		}
+
+		fragment := hunk.Fragment
+		if targets[m.target].Lines != nil {
+			reduced, err := reduceFragment(fragment, targets[m.target].Lines)
+			if err != nil {
+				return false, err
+			}
+			fragment = reduced
+		}
+		fragments = append(fragments, fragment)
+	}
+
+	ids := make([]string, len(group))
+	for i, m := range group {
+		ids[i] = targets[m.target].PatchID
+	}
+
+	s.logger.Info("Applying hunks with patch IDs %v to %s at once", ids, diff.hunks[group[0].hunk].FilePath)
+	if err := s.tryNormalApply(ctx, generateFragmentsPatch(file, fragments)); err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false, ctx.Err()
+		}
+		s.logger.Debug("Applying hunks %v at once failed, applying them one by one: %v", ids, err)
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// pendingPatchIDs returns the patch IDs of the pending targets
+func pendingPatchIDs(targets []hunkTarget, pending []int) []string {
+	ids := make([]string, len(pending))
+	for i, idx := range pending {
+		ids[i] = targets[idx].PatchID
 	}
-	patchContent := string(content)
+	return ids
+}
 
+// preparePatchData prepares patch data by parsing the patch content and calculating patch IDs
+func (s *Stager) preparePatchData(ctx context.Context, patchContent string) ([]HunkInfo, error) {
 	allHunks, err := ParsePatchFileWithGitDiff(patchContent)
 	if err != nil {
 		return nil, NewParsingError("patch file", err)
 	}
 
+	if s.recordsSession() {
+		session, err := s.LoadSession(ctx)
+		if err != nil {
+			s.logger.Debug("Ignoring session manifest: %v", err)
+		} else if session.assignPatchIDs(patchContent, allHunks) {
+			s.logger.Debug("Reusing %d patch IDs from the session manifest", len(allHunks))
+			return allHunks, nil
+		}
+	}
+
 	// Calculate patch IDs for all hunks
 	if err := s.calculatePatchIDsForHunks(ctx, allHunks); err != nil {
 		// Don't wrap context errors - let them propagate as-is
@@ -388,10 +881,13 @@ func (s *Stager) preparePatchData(ctx context.Context, patchFile string) ([]Hunk
 	return allHunks, nil
 }
 
-// getCurrentDiff gets the current diff for target files
+// getCurrentDiff gets the current diff of the target files against the base revision (the staged diff when unstaging)
 func (s *Stager) getCurrentDiff(ctx context.Context, targetFiles map[string]bool) ([]byte, error) {
 	// Build diff command with specific files
-	diffArgs := []string{"diff", "HEAD", "--"}
+	diffArgs := []string{"diff", "--end-of-options", s.base(), "--"}
+	if s.unstaging {
+		diffArgs = []string{"diff", "--cached", "--"}
+	}
 	for file := range targetFiles {
 		diffArgs = append(diffArgs, file)
 	}
@@ -404,45 +900,13 @@ func (s *Stager) getCurrentDiff(ctx context.Context, targetFiles map[string]bool
 	return diffOutput, nil
 }
 
-// findAndApplyMatchingHunk finds a matching hunk and applies it
-func (s *Stager) findAndApplyMatchingHunk(ctx context.Context, currentHunks []HunkInfo, targetIDs []string) ([]string, bool, error) {
-	for i := range currentHunks {
-		hunkContent, err := s.extractHunkContent(&currentHunks[i])
-		if err != nil || len(hunkContent) == 0 {
-			continue
-		}
-
-		currentPatchID, err := s.calculatePatchIDStable(ctx, hunkContent)
-		if err != nil {
-			// Check if context was canceled or timed out
-			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-				return nil, false, ctx.Err()
-			}
-			continue
-		}
-
-		// Check if this hunk matches any target
-		for i, targetID := range targetIDs {
-			if currentPatchID == targetID {
-				// Apply the hunk
-				s.logger.Info("Applying hunk with patch ID: %s", targetID)
-				if err := s.applyHunk(ctx, hunkContent, targetID); err != nil {
-					return nil, false, err
-				}
-
-				// Remove from target list
-				targetIDs = append(targetIDs[:i], targetIDs[i+1:]...)
-				return targetIDs, true, nil
-			}
-		}
-	}
-
-	return targetIDs, false, nil
-}
-
-// tryNormalApply attempts to apply the patch using the standard git apply --cached command
+// tryNormalApply attempts to apply the patch using the standard git apply --cached command (with -R when unstaging)
 func (s *Stager) tryNormalApply(ctx context.Context, hunkContent []byte) error {
-	_, err := s.executor.ExecuteWithStdin(ctx, "git", bytes.NewReader(hunkContent), "apply", "--cached")
+	applyArgs := []string{"apply", "--cached"}
+	if s.unstaging {
+		applyArgs = append(applyArgs, "-R")
+	}
+	_, err := s.executor.ExecuteWithStdin(ctx, "git", bytes.NewReader(hunkContent), applyArgs...)
 	return err
 }
 
@@ -494,6 +958,11 @@ func (s *Stager) tryResetApplyStrategy(ctx context.Context, hunkContent []byte,
 func (s *Stager) tryWorkingDirectoryApply(ctx context.Context, hunkContent []byte, targetID string) error {
 	s.logger.Debug("File already exists in index for %s (fallback check), trying alternative approach", targetID)
 
+	if err := s.saveWorktreeFiles(ctx, hunkContent); err != nil {
+		s.logger.Debug("Could not save the working tree files of %s for a rollback: %s", targetID, err.Error())
+		return err
+	}
+
 	_, applyErr := s.executor.ExecuteWithStdin(ctx, "git", bytes.NewReader(hunkContent), "apply")
 	if applyErr != nil {
 		s.logger.Debug("Working directory apply also failed for %s: %s", targetID, applyErr.Error())
@@ -505,13 +974,15 @@ func (s *Stager) tryWorkingDirectoryApply(ctx context.Context, hunkContent []byt
 }
 
 // handleApplyError handles errors from the initial patch application attempt
-func (s *Stager) handleApplyError(ctx context.Context, hunkContent []byte, targetID string, err error) error {
+// and returns the fallback strategy that succeeded
+func (s *Stager) handleApplyError(ctx context.Context, hunkContent []byte, targetID string, err error) (ApplyStrategy, error) {
 	s.logger.Debug("Initial apply failed for %s: %s", targetID, err.Error())
 
-	if !s.isAlreadyExistsError(err) {
+	// The fallbacks below add files to the index, so they don't apply to unstaging
+	if s.unstaging || !s.isAlreadyExistsError(err) {
 		// For non-"already exists" errors, return the original error
 		s.logger.Debug("Failed patch content for %s:\n%s", targetID, string(hunkContent))
-		return NewPatchApplicationError(targetID, err)
+		return ApplyStrategyNone, NewPatchApplicationError(targetID, err)
 	}
 
 	// Try reset-apply strategy first (for git mv scenarios)
@@ -520,26 +991,37 @@ func (s *Stager) handleApplyError(ctx context.Context, hunkContent []byte, targe
 		s.logger.Debug("File already exists in index for %s (stderr: %s), trying git mv compatible approach", targetID, stderrStr)
 
 		if resetErr := s.tryResetApplyStrategy(ctx, hunkContent, targetID); resetErr == nil {
-			return nil // Success
+			return ApplyStrategyResetApply, nil // Success
 		}
 	}
 
-	// Fallback to working directory apply
-	if workingErr := s.tryWorkingDirectoryApply(ctx, hunkContent, targetID); workingErr == nil {
-		return nil // Success
+	// Fallback to working directory apply, only when explicitly allowed
+	// (and never in dry-run mode, which must not touch the working tree)
+	if !s.options.AllowWorktreeWrite {
+		s.logger.Debug("Skipping working directory apply for %s: writing to the working tree is not allowed", targetID)
+	} else if s.options.DryRun {
+		s.logger.Debug("Skipping working directory apply for %s in dry-run mode", targetID)
+	} else if workingErr := s.tryWorkingDirectoryApply(ctx, hunkContent, targetID); workingErr == nil {
+		return ApplyStrategyWorkingDirectory, nil // Success
 	}
 
 	// All strategies failed
 	s.logger.Debug("Failed patch content for %s:\n%s", targetID, string(hunkContent))
-	return NewPatchApplicationError(targetID, err)
+	return ApplyStrategyNone, NewPatchApplicationError(targetID, err)
 }
 
 // applyHunk applies a single hunk to the staging area
 func (s *Stager) applyHunk(ctx context.Context, hunkContent []byte, targetID string) error {
+	_, err := s.applyHunkWithStrategy(ctx, hunkContent, targetID)
+	return err
+}
+
+// applyHunkWithStrategy applies a single hunk to the staging area and reports which strategy succeeded
+func (s *Stager) applyHunkWithStrategy(ctx context.Context, hunkContent []byte, targetID string) (ApplyStrategy, error) {
 	if err := s.tryNormalApply(ctx, hunkContent); err != nil {
 		return s.handleApplyError(ctx, hunkContent, targetID, err)
 	}
-	return nil
+	return ApplyStrategyCached, nil
 }
 
 // extractFilenameFromPatch extracts the filename from a patch header
@@ -555,23 +1037,3 @@ func (s *Stager) extractFilenameFromPatch(patchContent []byte) string {
 	}
 	return ""
 }
-
-// calculatePatchIDStable calculates patch ID using git patch-id --stable
-func (s *Stager) calculatePatchIDStable(ctx context.Context, hunkPatch []byte) (string, error) {
-	output, err := s.executor.ExecuteWithStdin(ctx, "git", bytes.NewReader(hunkPatch), "patch-id", "--stable")
-	if err != nil {
-		return "", err
-	}
-
-	// git patch-id output format: "patch-id commit-id"
-	parts := strings.Fields(string(output))
-	if len(parts) > 0 {
-		// Return first 8 chars for brevity
-		if len(parts[0]) >= 8 {
-			return parts[0][:8], nil
-		}
-		return parts[0], nil
-	}
-
-	return "", NewGitCommandError("git patch-id", fmt.Errorf("unexpected output"))
-}
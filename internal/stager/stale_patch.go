@@ -0,0 +1,206 @@
+package stager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+// FindStaleFiles returns the files of the patch whose working tree content changed since
+// the patch was generated, in patch order. A file is stale if the blob of its working tree
+// content ("git hash-object", or the link text for symbolic links) does not match the new
+// object ID of its index line, if it was removed, or if a file the patch deletes exists
+// again. Files without an index line (e.g. patches generated with --no-index) cannot be
+// checked and are never stale.
+func (s *Stager) FindStaleFiles(ctx context.Context, patchContent string) ([]string, error) {
+	files, _, err := gitdiff.Parse(strings.NewReader(patchContent))
+	if err != nil {
+		return nil, NewParsingError("patch file", err)
+	}
+
+	stale := make([]bool, len(files))
+	var hashed []int // Indexes of the files whose working tree blob is compared
+	for i, file := range files {
+		switch {
+		case file.IsDelete:
+			_, err := os.Lstat(file.OldName)
+			stale[i] = err == nil
+		case file.NewOIDPrefix == "":
+		default:
+			info, err := os.Lstat(file.NewName)
+			switch {
+			case errors.Is(err, os.ErrNotExist):
+				stale[i] = true
+			case err == nil && info.Mode()&os.ModeSymlink != 0:
+				// git hash-object would follow the link; git stores the link text instead
+				oid, err := s.hashSymlink(ctx, file.NewName)
+				if err != nil {
+					return nil, err
+				}
+				if !strings.HasPrefix(oid, file.NewOIDPrefix) {
+					s.logger.Debug("Patch expects %s at %s, working tree has %s", file.NewOIDPrefix, file.NewName, oid)
+					stale[i] = true
+				}
+			default:
+				hashed = append(hashed, i)
+			}
+		}
+	}
+
+	if len(hashed) > 0 {
+		args := []string{"hash-object", "--"}
+		for _, i := range hashed {
+			args = append(args, files[i].NewName)
+		}
+		output, err := s.executor.Execute(ctx, "git", args...)
+		if err != nil {
+			return nil, NewGitCommandError("git hash-object", err)
+		}
+
+		oids := strings.Fields(string(output))
+		if len(oids) != len(hashed) {
+			return nil, NewGitCommandError("git hash-object", errors.New("unexpected number of object IDs"))
+		}
+		for j, i := range hashed {
+			if !strings.HasPrefix(oids[j], files[i].NewOIDPrefix) {
+				s.logger.Debug("Patch expects %s at %s, working tree has %s", files[i].NewOIDPrefix, files[i].NewName, oids[j])
+				stale[i] = true
+			}
+		}
+	}
+
+	var paths []string
+	for i, file := range files {
+		if !stale[i] {
+			continue
+		}
+		if file.IsDelete {
+			paths = append(paths, file.OldName)
+		} else {
+			paths = append(paths, file.NewName)
+		}
+	}
+	return paths, nil
+}
+
+// hashSymlink returns the blob ID git records for a symbolic link, which is the hash of its target path
+func (s *Stager) hashSymlink(ctx context.Context, path string) (string, error) {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return "", NewIOError("read symbolic link "+path, err)
+	}
+	output, err := s.executor.ExecuteWithStdin(ctx, "git", strings.NewReader(target), "hash-object", "--stdin")
+	if err != nil {
+		return "", NewGitCommandError("git hash-object --stdin", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// RemapHunkSpecs translates hunk specifications numbered against oldPatch into the numbering
+// of newPatch, a patch regenerated from the working tree after oldPatch went stale. Hunks are
+// matched by patch ID within their file, so a hunk keeps being selected when edits elsewhere
+// shift the numbering; a hunk that is not in newPatch changed since oldPatch was generated and
+// is reported with a HunkNotFound error. The specifications must not contain file patterns or
+// negations (see ExpandFilePatterns and ApplyExclusions). "id:" and "file:*" specifications
+// are kept as they are; line ranges of a file ("L40-L55") cannot be remapped and are rejected.
+func (s *Stager) RemapHunkSpecs(ctx context.Context, hunkSpecs []string, oldPatch, newPatch string) ([]string, error) {
+	oldHunks, err := s.parseWithPatchIDs(ctx, oldPatch)
+	if err != nil {
+		return nil, err
+	}
+	newHunks, err := s.parseWithPatchIDs(ctx, newPatch)
+	if err != nil {
+		return nil, err
+	}
+
+	// Match the hunks of each file in patch order, so hunks with equal patch IDs keep their order
+	hunkCounts := make(map[string]int)
+	renumbered := make(map[string]int) // "file:N" in oldPatch -> number in newPatch
+	claimed := make(map[int]bool)
+	for _, old := range oldHunks {
+		hunkCounts[old.FilePath] = max(hunkCounts[old.FilePath], old.IndexInFile)
+		for i, hunk := range newHunks {
+			if claimed[i] || hunk.FilePath != old.FilePath {
+				continue
+			}
+			// Fallback IDs of hunks without content are not real patch IDs; match those by position
+			sameHunk := hunk.PatchID == old.PatchID
+			if isFallbackPatchID(old.PatchID) {
+				sameHunk = isFallbackPatchID(hunk.PatchID) && hunk.IndexInFile == old.IndexInFile
+			}
+			if sameHunk {
+				claimed[i] = true
+				renumbered[hunkKey(old.FilePath, old.IndexInFile)] = hunk.IndexInFile
+				break
+			}
+		}
+	}
+
+	remapped := make([]string, 0, len(hunkSpecs))
+	for _, spec := range hunkSpecs {
+		filePath, hunksSpec, found := strings.Cut(spec, ":")
+		if IsIDSelector(spec) || !found || hunksSpec == "*" {
+			remapped = append(remapped, spec)
+			continue
+		}
+		if _, exists := hunkCounts[filePath]; !exists {
+			return nil, NewHunkNotFoundError(fmt.Sprintf("file %s not found in patch", filePath), nil)
+		}
+
+		_, selectors, err := ParseHunkSelectors(spec)
+		if err != nil {
+			return nil, err
+		}
+		selectors, err = ResolveHunkSelectors(filePath, selectors, hunkCounts[filePath])
+		if err != nil {
+			return nil, err
+		}
+
+		items := make([]string, 0, len(selectors))
+		for _, selector := range selectors {
+			if len(selector.FileLines) > 0 {
+				return nil, NewInvalidArgumentError(fmt.Sprintf("line ranges of a file cannot be remapped to the regenerated patch: %s", spec), nil)
+			}
+			number, exists := renumbered[hunkKey(filePath, selector.Hunk)]
+			if !exists {
+				return nil, NewHunkNotFoundError(fmt.Sprintf("hunk %s:%d in the regenerated patch (it changed since the patch was generated)", filePath, selector.Hunk), nil)
+			}
+			items = append(items, formatHunkItem(number, selector.Lines))
+		}
+		remapped = append(remapped, filePath+":"+strings.Join(items, ","))
+	}
+	return remapped, nil
+}
+
+// parseWithPatchIDs parses a patch and calculates the patch IDs of its hunks
+func (s *Stager) parseWithPatchIDs(ctx context.Context, patchContent string) ([]HunkInfo, error) {
+	hunks, err := ParsePatchFileWithGitDiff(patchContent)
+	if err != nil {
+		return nil, NewParsingError("patch file", err)
+	}
+	if err := s.calculatePatchIDsForHunks(ctx, hunks); err != nil {
+		return nil, err
+	}
+	return hunks, nil
+}
+
+// formatHunkItem formats a hunk number with its selected body lines ("3" or "3[2-4,7]")
+func formatHunkItem(number int, lines []LineRange) string {
+	if len(lines) == 0 {
+		return strconv.Itoa(number)
+	}
+	ranges := make([]string, len(lines))
+	for i, r := range lines {
+		if r.Start == r.End {
+			ranges[i] = strconv.Itoa(r.Start)
+		} else {
+			ranges[i] = fmt.Sprintf("%d-%d", r.Start, r.End)
+		}
+	}
+	return fmt.Sprintf("%d[%s]", number, strings.Join(ranges, ","))
+}
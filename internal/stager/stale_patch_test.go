@@ -0,0 +1,187 @@
+package stager
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/internal/executor"
+	"github.com/syou6162/git-sequential-stage/internal/logger"
+)
+
+const stalePatchA = `diff --git a/a.txt b/a.txt
+index 1111111..aaaaaaa 100644
+--- a/a.txt
++++ b/a.txt
+@@ -1 +1 @@
+-a
++A
+`
+
+const stalePatchContent = stalePatchA + `diff --git a/b.txt b/b.txt
+index 2222222..bbbbbbb 100644
+--- a/b.txt
++++ b/b.txt
+@@ -1 +1 @@
+-b
++B
+diff --git a/gone.txt b/gone.txt
+deleted file mode 100644
+index 3333333..0000000
+--- a/gone.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-gone
+diff --git a/missing.txt b/missing.txt
+index 4444444..ccccccc 100644
+--- a/missing.txt
++++ b/missing.txt
+@@ -1 +1 @@
+-m
++M
+`
+
+func TestFindStaleFiles(t *testing.T) {
+	t.Chdir(t.TempDir())
+	for _, name := range []string{"a.txt", "b.txt", "gone.txt"} {
+		if err := os.WriteFile(name, []byte(name+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a.txt is unchanged, b.txt was edited, gone.txt was deleted by the patch
+	// but exists again and missing.txt was removed
+	mock := executor.NewMockCommandExecutor()
+	mock.Commands["git [hash-object -- a.txt b.txt]"] = executor.MockResponse{
+		Output: []byte("aaaaaaa0000000000000000000000000000000000\nddddddd1000000000000000000000000000000000\n"),
+	}
+	s := &Stager{executor: mock, logger: logger.NewFromEnv()}
+
+	stale, err := s.FindStaleFiles(context.Background(), stalePatchContent)
+	if err != nil {
+		t.Fatalf("FindStaleFiles failed: %v", err)
+	}
+	expected := []string{"b.txt", "gone.txt", "missing.txt"}
+	if !reflect.DeepEqual(stale, expected) {
+		t.Errorf("Expected stale files %v, got %v", expected, stale)
+	}
+}
+
+func TestFindStaleFiles_UpToDate(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("a.txt", []byte("A\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	mock := executor.NewMockCommandExecutor()
+	mock.Commands["git [hash-object -- a.txt]"] = executor.MockResponse{
+		Output: []byte("aaaaaaa0000000000000000000000000000000000\n"),
+	}
+	s := &Stager{executor: mock, logger: logger.NewFromEnv()}
+
+	stale, err := s.FindStaleFiles(context.Background(), stalePatchA)
+	if err != nil {
+		t.Fatalf("FindStaleFiles failed: %v", err)
+	}
+	if len(stale) != 0 {
+		t.Errorf("Expected no stale files, got %v", stale)
+	}
+}
+
+func TestFindStaleFiles_HashObjectFails(t *testing.T) {
+	t.Chdir(t.TempDir())
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(name, []byte(name+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mock := executor.NewMockCommandExecutor()
+	mock.Commands["git [hash-object -- a.txt b.txt]"] = executor.MockResponse{Error: errors.New("fatal: could not open b.txt")}
+	s := &Stager{executor: mock, logger: logger.NewFromEnv()}
+
+	_, err := s.FindStaleFiles(context.Background(), stalePatchContent)
+	if !errors.Is(err, &StagerError{Type: ErrorTypeGitCommand}) {
+		t.Errorf("Expected a git command error, got %v", err)
+	}
+}
+
+func TestNewStalePatchError(t *testing.T) {
+	err := NewStalePatchError([]string{"a.txt", "b.txt"})
+	if err.Type != ErrorTypeStalePatch {
+		t.Errorf("Expected ErrorTypeStalePatch, got %v", err.Type)
+	}
+	expected := "patch file is stale: the working tree changed since it was generated: a.txt, b.txt"
+	if err.Error() != expected {
+		t.Errorf("Expected message %q, got %q", expected, err.Error())
+	}
+}
+
+const remapOldPatch = `diff --git a/a.txt b/a.txt
+index 1111111..2222222 100644
+--- a/a.txt
++++ b/a.txt
+@@ -8,3 +8,3 @@
+ h
+-i
++I
+ j
+`
+
+// remapNewPatch adds a hunk ahead of the one in remapOldPatch
+const remapNewPatch = `diff --git a/a.txt b/a.txt
+index 1111111..3333333 100644
+--- a/a.txt
++++ b/a.txt
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+@@ -8,3 +8,3 @@
+ h
+-i
++I
+ j
+`
+
+func TestRemapHunkSpecs(t *testing.T) {
+	s := &Stager{executor: executor.NewMockCommandExecutor(), logger: logger.NewFromEnv()}
+
+	remapped, err := s.RemapHunkSpecs(context.Background(), []string{"a.txt:1", "a.txt:1[2]", "a.txt:*", "id:abcd"}, remapOldPatch, remapNewPatch)
+	if err != nil {
+		t.Fatalf("RemapHunkSpecs failed: %v", err)
+	}
+	expected := []string{"a.txt:2", "a.txt:2[2]", "a.txt:*", "id:abcd"}
+	if !reflect.DeepEqual(remapped, expected) {
+		t.Errorf("Expected %v, got %v", expected, remapped)
+	}
+}
+
+func TestRemapHunkSpecs_Errors(t *testing.T) {
+	s := &Stager{executor: executor.NewMockCommandExecutor(), logger: logger.NewFromEnv()}
+
+	tests := []struct {
+		name     string
+		spec     string
+		newPatch string
+		errType  ErrorType
+	}{
+		{"changed hunk", "a.txt:1", strings.Replace(remapOldPatch, "+I", "+X", 1), ErrorTypeHunkNotFound},
+		{"hunk beyond the file", "a.txt:2", remapNewPatch, ErrorTypeHunkCountExceeded},
+		{"file not in patch", "b.txt:1", remapNewPatch, ErrorTypeHunkNotFound},
+		{"file lines", "a.txt:L9", remapNewPatch, ErrorTypeInvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.RemapHunkSpecs(context.Background(), []string{tt.spec}, remapOldPatch, tt.newPatch)
+			if !errors.Is(err, &StagerError{Type: tt.errType}) {
+				t.Errorf("Expected a %v error, got %v", tt.errType, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package stager
+
+import (
+	"context"
+	"fmt"
+)
+
+// UnstageHunksWithResult removes the specified hunks from the staging area; the working tree is left untouched.
+// Hunks are numbered as in "git diff --cached" and accept the same specifications as staging,
+// except for line selections. Like staging, every hunk is tracked by its patch ID and applied
+// in reverse with "git apply --cached -R", so numbering drift is handled the same way.
+// If unstaging fails partway, the staging area is restored.
+func (s *Stager) UnstageHunksWithResult(ctx context.Context, hunkSpecs []string) (*StageResult, error) {
+	stagedDiff, err := s.executor.Execute(ctx, "git", "diff", "--cached")
+	if err != nil {
+		return nil, NewGitCommandError("git diff --cached", err)
+	}
+
+	unstager := *s
+	unstager.unstaging = true
+
+	allHunks, err := unstager.preparePatchData(ctx, string(stagedDiff))
+	if err != nil {
+		return nil, err
+	}
+
+	hunkSpecs, err = resolveIDSelectors(hunkSpecs, allHunks)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, spec := range hunkSpecs {
+		_, selectors, err := ParseHunkSelectors(spec)
+		if err != nil {
+			return nil, err
+		}
+		for _, selector := range selectors {
+			if selector.IsPartial() {
+				return nil, NewInvalidArgumentError(fmt.Sprintf("line selection not supported when unstaging: %s", spec), nil)
+			}
+		}
+	}
+
+	targetFiles, err := collectTargetFiles(hunkSpecs)
+	if err != nil {
+		return nil, NewInvalidArgumentError("failed to collect target files", err)
+	}
+
+	return unstager.stageTargets(ctx, hunkSpecs, allHunks, targetFiles)
+}
+
+// UnstageFiles removes all staged changes of the given files from the staging area
+func (s *Stager) UnstageFiles(ctx context.Context, files []string) error {
+	for _, file := range files {
+		if _, err := s.executor.Execute(ctx, "git", "reset", "-q", "--", file); err != nil {
+			return NewGitCommandError(fmt.Sprintf("git reset %s", file), err)
+		}
+
+		s.logger.Info("Unstaged entire file: %s", file)
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package stager
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/internal/executor"
+	"github.com/syou6162/git-sequential-stage/internal/logger"
+)
+
+func TestUnstageHunksWithResult_AppliesInReverse(t *testing.T) {
+	mock := executor.NewMockCommandExecutor()
+	mock.Commands["git [diff --cached]"] = executor.MockResponse{Output: []byte(threeHunkDiff)}
+	mock.Commands["git [diff --cached -- file.txt]"] = executor.MockResponse{Output: []byte(threeHunkDiff)}
+	mock.Commands["git [rev-parse --git-path index]"] = executor.MockResponse{Output: []byte(filepath.Join(t.TempDir(), "index") + "\n")}
+	mock.Commands["git [apply --cached -R]"] = executor.MockResponse{}
+	s := &Stager{executor: mock, logger: logger.NewFromEnv()}
+
+	result, err := s.UnstageHunksWithResult(context.Background(), []string{"file.txt:2"})
+	if err != nil {
+		t.Fatalf("UnstageHunksWithResult failed: %v", err)
+	}
+	if len(result.Hunks) != 1 || result.Hunks[0].Status != HunkStatusApplied {
+		t.Errorf("Expected one applied hunk, got %+v", result.Hunks)
+	}
+
+	var applies []executor.ExecutedCommand
+	for _, cmd := range mock.ExecutedCommands {
+		if len(cmd.Args) > 0 && cmd.Args[0] == "apply" {
+			applies = append(applies, cmd)
+		}
+	}
+	if len(applies) != 1 {
+		t.Fatalf("Expected a single git apply --cached -R, got %d", len(applies))
+	}
+	patch := string(applies[0].Stdin)
+	if !strings.Contains(patch, "+line11 changed") || strings.Contains(patch, "+line2 changed") {
+		t.Errorf("Expected the reverse patch to contain only hunk 2:\n%s", patch)
+	}
+}
+
+func TestUnstageHunksWithResult_RejectsLineSelection(t *testing.T) {
+	mock := executor.NewMockCommandExecutor()
+	mock.Commands["git [diff --cached]"] = executor.MockResponse{Output: []byte(threeHunkDiff)}
+	s := &Stager{executor: mock, logger: logger.NewFromEnv()}
+
+	_, err := s.UnstageHunksWithResult(context.Background(), []string{"file.txt:1[1]"})
+	if err == nil || !strings.Contains(err.Error(), "line selection not supported when unstaging") {
+		t.Errorf("Expected a line selection error, got %v", err)
+	}
+}
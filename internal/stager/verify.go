@@ -0,0 +1,115 @@
+package stager
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// stagedDiffCheck records the staged hunks of the target files before staging,
+// so that the staging area can be compared with the expected result afterwards
+type stagedDiffCheck struct {
+	paths   []string        // Pathspecs for "git diff --cached" (old names included so renames are detected)
+	skipped map[string]bool // Files with line selections, whose staged hunks cannot be predicted
+	before  map[string]int  // Patch ID -> number of staged hunks before staging
+}
+
+// newStagedDiffCheck records the staged hunks of the files touched by the targets
+func (s *Stager) newStagedDiffCheck(ctx context.Context, targets []hunkTarget) (*stagedDiffCheck, error) {
+	check := &stagedDiffCheck{skipped: make(map[string]bool)}
+
+	seen := make(map[string]bool)
+	for _, target := range targets {
+		for _, path := range []string{target.Hunk.FilePath, target.Hunk.OldFilePath} {
+			if path != "" && !seen[path] {
+				seen[path] = true
+				check.paths = append(check.paths, path)
+			}
+		}
+		// A partially staged hunk is merged with its surroundings differently
+		// in the staged diff, so its patch ID cannot be predicted
+		if target.Lines != nil {
+			check.skipped[target.Hunk.FilePath] = true
+		}
+	}
+	sort.Strings(check.paths)
+
+	before, err := s.stagedPatchIDs(ctx, check)
+	if err != nil {
+		return nil, err
+	}
+	check.before = before
+	return check, nil
+}
+
+// stagedPatchIDs counts the patch IDs of the staged hunks of the checked files
+func (s *Stager) stagedPatchIDs(ctx context.Context, check *stagedDiffCheck) (map[string]int, error) {
+	args := append([]string{"diff", "--cached", "--"}, check.paths...)
+	stagedDiff, err := s.executor.Execute(ctx, "git", args...)
+	if err != nil {
+		return nil, NewGitCommandError("git diff --cached", err)
+	}
+
+	hunks, err := ParsePatchFileWithGitDiff(string(stagedDiff))
+	if err != nil {
+		return nil, NewParsingError("staged diff", err)
+	}
+	if err := s.calculatePatchIDsForHunks(ctx, hunks); err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, NewGitCommandError("patch-id calculation", err)
+	}
+
+	ids := make(map[string]int)
+	for _, hunk := range hunks {
+		if !check.skipped[hunk.FilePath] {
+			ids[hunk.PatchID]++
+		}
+	}
+	return ids, nil
+}
+
+// verify compares the staged hunks with the hunks recorded before staging plus the
+// targets (minus the targets when unstaging), and reports any difference as a VerificationError
+func (s *Stager) verify(ctx context.Context, check *stagedDiffCheck, targets []hunkTarget) error {
+	expected := make(map[string]int, len(check.before)+len(targets))
+	for id, count := range check.before {
+		expected[id] = count
+	}
+	for _, target := range targets {
+		if check.skipped[target.Hunk.FilePath] {
+			continue
+		}
+		if s.unstaging {
+			expected[target.PatchID]--
+		} else {
+			expected[target.PatchID]++
+		}
+	}
+
+	actual, err := s.stagedPatchIDs(ctx, check)
+	if err != nil {
+		return err
+	}
+
+	var missing, extra []string
+	for id, count := range expected {
+		for i := actual[id]; i < count; i++ {
+			missing = append(missing, id)
+		}
+	}
+	for id, count := range actual {
+		for i := expected[id]; i < count; i++ {
+			extra = append(extra, id)
+		}
+	}
+	if len(missing) == 0 && len(extra) == 0 {
+		s.logger.Debug("Verified staged diff of %d files", len(check.paths))
+		return nil
+	}
+
+	sort.Strings(missing)
+	sort.Strings(extra)
+	return NewVerificationError(missing, extra)
+}
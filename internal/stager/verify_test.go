@@ -0,0 +1,75 @@
+package stager
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/internal/executor"
+	"github.com/syou6162/git-sequential-stage/internal/logger"
+)
+
+func TestStageTargets_VerifyReportsMissingHunk(t *testing.T) {
+	mock := executor.NewMockCommandExecutor()
+	mock.Commands["git [rev-parse --git-path index]"] = executor.MockResponse{Output: []byte(filepath.Join(t.TempDir(), "index") + "\n")}
+	mock.Commands["git [diff --end-of-options HEAD -- file.txt]"] = executor.MockResponse{Output: []byte(threeHunkDiff)}
+	// The staged diff stays empty, as if "git apply --cached" had not changed the index
+	mock.Commands["git [diff --cached -- file.txt]"] = executor.MockResponse{}
+	mock.Commands["git [apply --cached]"] = executor.MockResponse{}
+	s := &Stager{executor: mock, logger: logger.NewFromEnv(), options: Options{Verify: true}}
+	ctx := context.Background()
+
+	allHunks, err := s.preparePatchData(ctx, threeHunkDiff)
+	if err != nil {
+		t.Fatalf("Failed to prepare patch data: %v", err)
+	}
+
+	result, err := s.stageTargets(ctx, []string{"file.txt:2"}, allHunks, map[string]bool{"file.txt": true})
+	var verificationErr *VerificationError
+	if !errors.As(err, &verificationErr) {
+		t.Fatalf("Expected a VerificationError, got %v", err)
+	}
+	if len(verificationErr.Missing) != 1 || verificationErr.Missing[0] != allHunks[1].PatchID || len(verificationErr.Extra) != 0 {
+		t.Errorf("Expected hunk 2 to be reported missing, got missing=%v extra=%v", verificationErr.Missing, verificationErr.Extra)
+	}
+	if !result.RolledBack {
+		t.Error("Expected the staging area to be restored after a failed verification")
+	}
+}
+
+func TestStagedDiffCheck_SkipsLineSelections(t *testing.T) {
+	mock := executor.NewMockCommandExecutor()
+	mock.Commands["git [diff --cached -- file.txt]"] = executor.MockResponse{Output: []byte(threeHunkDiff)}
+	s := &Stager{executor: mock, logger: logger.NewFromEnv()}
+	ctx := context.Background()
+
+	allHunks, err := s.preparePatchData(ctx, threeHunkDiff)
+	if err != nil {
+		t.Fatalf("Failed to prepare patch data: %v", err)
+	}
+	targets := []hunkTarget{{PatchID: allHunks[0].PatchID, Hunk: allHunks[0], Lines: []int{2}}}
+
+	check, err := s.newStagedDiffCheck(ctx, targets)
+	if err != nil {
+		t.Fatalf("newStagedDiffCheck failed: %v", err)
+	}
+	if len(check.before) != 0 {
+		t.Errorf("Expected staged hunks of files with line selections to be ignored, got %v", check.before)
+	}
+	if err := s.verify(ctx, check, targets); err != nil {
+		t.Errorf("Expected verification to pass, got %v", err)
+	}
+}
+
+func TestVerificationError_Error(t *testing.T) {
+	err := NewVerificationError([]string{"aaaa1111"}, []string{"bbbb2222", "cccc3333"})
+	want := "staged diff verification failed: missing hunks with patch IDs [aaaa1111]; unexpected hunks with patch IDs [bbbb2222, cccc3333]"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+	if strings.Contains(NewVerificationError(nil, []string{"bbbb2222"}).Error(), "missing") {
+		t.Error("Expected no missing part without missing hunks")
+	}
+}
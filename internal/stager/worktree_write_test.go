@@ -0,0 +1,140 @@
+package stager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/syou6162/git-sequential-stage/internal/executor"
+	"github.com/syou6162/git-sequential-stage/internal/logger"
+)
+
+const alreadyExistsHunk = `diff --git a/file.go b/file.go
+new file mode 100644
+index 0000000..def456
+--- /dev/null
++++ b/file.go
+@@ -0,0 +1 @@
++package main
+`
+
+func newAlreadyExistsMock() *executor.MockCommandExecutor {
+	mock := executor.NewMockCommandExecutor()
+	mock.Commands["git [apply --cached]"] = executor.MockResponse{Error: fmt.Errorf("error: file.go: already exists in index")}
+	mock.Commands["git [apply]"] = executor.MockResponse{}
+	return mock
+}
+
+func countWorktreeApplies(mock *executor.MockCommandExecutor) int {
+	count := 0
+	for _, cmd := range mock.ExecutedCommands {
+		if len(cmd.Args) == 1 && cmd.Args[0] == "apply" {
+			count++
+		}
+	}
+	return count
+}
+
+func TestApplyHunkWithStrategy_WorktreeWriteNotAllowedByDefault(t *testing.T) {
+	mock := newAlreadyExistsMock()
+	s := &Stager{executor: mock, logger: logger.NewFromEnv()}
+
+	strategy, err := s.applyHunkWithStrategy(context.Background(), []byte(alreadyExistsHunk), "abc12345")
+	if !errors.Is(err, &StagerError{Type: ErrorTypePatchApplication}) {
+		t.Fatalf("Expected a patch application error, got %v", err)
+	}
+	if strategy != ApplyStrategyNone {
+		t.Errorf("Expected no strategy, got %v", strategy)
+	}
+	if n := countWorktreeApplies(mock); n != 0 {
+		t.Errorf("Expected the working tree not to be written, got %d git apply calls", n)
+	}
+}
+
+func TestApplyHunkWithStrategy_AllowWorktreeWrite(t *testing.T) {
+	mock := newAlreadyExistsMock()
+	s := &Stager{executor: mock, logger: logger.NewFromEnv(), options: Options{AllowWorktreeWrite: true}}
+
+	strategy, err := s.applyHunkWithStrategy(context.Background(), []byte(alreadyExistsHunk), "abc12345")
+	if err != nil {
+		t.Fatalf("applyHunkWithStrategy failed: %v", err)
+	}
+	if strategy != ApplyStrategyWorkingDirectory {
+		t.Errorf("Expected %v, got %v", ApplyStrategyWorkingDirectory, strategy)
+	}
+	if n := countWorktreeApplies(mock); n != 1 {
+		t.Errorf("Expected one git apply to the working tree, got %d", n)
+	}
+}
+
+func TestApplyHunkWithStrategy_DryRunOverridesAllowWorktreeWrite(t *testing.T) {
+	mock := newAlreadyExistsMock()
+	s := &Stager{executor: mock, logger: logger.NewFromEnv(), options: Options{AllowWorktreeWrite: true, DryRun: true}}
+
+	if _, err := s.applyHunkWithStrategy(context.Background(), []byte(alreadyExistsHunk), "abc12345"); err == nil {
+		t.Fatal("Expected an error in dry-run mode")
+	}
+	if n := countWorktreeApplies(mock); n != 0 {
+		t.Errorf("Expected the working tree not to be written in dry-run mode, got %d git apply calls", n)
+	}
+}
+
+func TestIndexSnapshot_RestoresWorktreeWrites(t *testing.T) {
+	tests := []struct {
+		name    string
+		existed bool   // Whether file.go existed before staging
+		content string // Content of file.go before staging
+	}{
+		{name: "new file"},
+		{name: "existing file", existed: true, content: "package old\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			path := filepath.Join(root, "file.go")
+			if tt.existed {
+				if err := os.WriteFile(path, []byte(tt.content), 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			mock := newAlreadyExistsMock()
+			mock.Commands["git [rev-parse --git-path index]"] = executor.MockResponse{Output: []byte(filepath.Join(root, "index") + "\n")}
+			mock.Commands["git [rev-parse --show-toplevel]"] = executor.MockResponse{Output: []byte(root + "\n")}
+			s := &Stager{executor: mock, logger: logger.NewFromEnv(), options: Options{AllowWorktreeWrite: true}}
+
+			snapshot, err := s.SnapshotIndex(context.Background())
+			if err != nil {
+				t.Fatalf("SnapshotIndex failed: %v", err)
+			}
+			if _, err := s.applyHunkWithStrategy(context.Background(), []byte(alreadyExistsHunk), "abc12345"); err != nil {
+				t.Fatalf("applyHunkWithStrategy failed: %v", err)
+			}
+			// What "git apply" writes to the working tree
+			if err := os.WriteFile(path, []byte("package main\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := snapshot.Restore(); err != nil {
+				t.Fatalf("Restore failed: %v", err)
+			}
+			content, err := os.ReadFile(path)
+			if !tt.existed {
+				if !os.IsNotExist(err) {
+					t.Errorf("Expected file.go to be removed again, got %q (err: %v)", content, err)
+				}
+				return
+			}
+			if err != nil || string(content) != tt.content {
+				t.Errorf("Expected file.go to be restored to %q, got %q (err: %v)", tt.content, content, err)
+			}
+			if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o755 {
+				t.Errorf("Expected the file mode to be restored, got %v (err: %v)", info, err)
+			}
+		})
+	}
+}
@@ -67,7 +67,9 @@ func (v *Validator) ValidateArgs(hunks, patchFile string) error {
 
 // ValidateArgsNew validates command line arguments with the file:hunks format.
 // Each hunk specification should be in the format "file:hunk_numbers" where
-// hunk_numbers is a comma-separated list of positive integers.
+// hunk_numbers is a comma-separated list of positive integers, optionally
+// restricted to some lines (e.g. "file.go:2[3-7]" or "file.go:L40-L55").
+// Hunks can also be selected by patch ID (e.g. "id:3fa9c1d2").
 func (v *Validator) ValidateArgsNew(hunkSpecs []string, patchFile string) error {
 	if len(hunkSpecs) == 0 {
 		return errors.New("at least one hunk specification is required")
@@ -77,9 +79,12 @@ func (v *Validator) ValidateArgsNew(hunkSpecs []string, patchFile string) error
 		return errors.New("patch file cannot be empty")
 	}
 
-	// Validate each hunk specification using parseHunkSpec
+	// Validate each hunk specification using ParseHunkSelectors
 	for _, spec := range hunkSpecs {
-		_, _, err := stager.ParseHunkSpec(spec)
+		if stager.IsIDSelector(spec) {
+			continue
+		}
+		_, _, err := stager.ParseHunkSelectors(spec)
 		if err != nil {
 			return err
 		}
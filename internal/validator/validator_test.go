@@ -177,6 +177,18 @@ func TestValidator_ValidateArgsNew(t *testing.T) {
 			patchFile: "changes.patch",
 			wantErr:   false,
 		},
+		{
+			name:      "valid patch ID selectors",
+			hunkSpecs: []string{"id:3fa9c1d2,88AA01BC", "main.go:1"},
+			patchFile: "test.patch",
+			wantErr:   false,
+		},
+		{
+			name:      "short id is a hunk number of a file named id",
+			hunkSpecs: []string{"id:1,3"},
+			patchFile: "test.patch",
+			wantErr:   false,
+		},
 		{
 			name:      "path with multiple colons causes error",
 			hunkSpecs: []string{"path:to:file.go:1,2"},
@@ -271,10 +283,23 @@ func TestValidator_ValidateArgsNew(t *testing.T) {
 		},
 		{
 			name:      "multiple files with negative numbers",
-			hunkSpecs: []string{"file1.go:1,2", "file2.go:3,-1"},
+			hunkSpecs: []string{"file1.go:1,2", "file2.go:3,-3"},
+			patchFile: "test.patch",
+			wantErr:   true,
+			errMsg:    "hunk number must be positive: -3",
+		},
+		{
+			name:      "ranges and hunks counted from the end",
+			hunkSpecs: []string{"file1.go:2-5,7-", "file2.go:last,last-1,-1"},
+			patchFile: "test.patch",
+			wantErr:   false,
+		},
+		{
+			name:      "range with end before start",
+			hunkSpecs: []string{"file1.go:5-2"},
 			patchFile: "test.patch",
 			wantErr:   true,
-			errMsg:    "hunk number must be positive: -1",
+			errMsg:    "invalid hunk range: 5-2 (end before start)",
 		},
 
 		// 境界値テスト
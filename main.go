@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
-	"sort"
 	"strings"
 	"syscall"
 	"time"
@@ -41,28 +40,215 @@ func (e *usageShownError) Error() string {
 // runGitSequentialStage は git-sequential-stage の主要なロジックを実行します
 // テストから直接呼び出せるように分離されています
 func runGitSequentialStage(ctx context.Context, hunks []string, patchFile string) error {
+	_, err := runGitSequentialStageWithResult(ctx, hunks, patchFile)
+	return err
+}
+
+// runGitSequentialStageWithResult は runGitSequentialStage と同じ処理を行い、
+// ハンクごとのステージング結果も返します（--format=json 用）
+func runGitSequentialStageWithResult(ctx context.Context, hunks []string, patchFile string) (*stager.StageResult, error) {
+	return runStageSelection(ctx, stager.Selection{HunkSpecs: hunks}, patchFile)
+}
+
+// runStageSelection stages the hunks selected by -hunk and -match flags
+func runStageSelection(ctx context.Context, selection stager.Selection, patchFile string) (*stager.StageResult, error) {
+	opts := stagerOptionsFromEnv()
+	policy, err := resolveSafetyPolicy(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	opts.SafetyPolicy = policy
+	return runStageSelectionWithOptions(ctx, opts, selection, patchFile)
+}
+
+// runStageSelectionWithOptions stages the selected hunks with the given stager options
+func runStageSelectionWithOptions(ctx context.Context, opts stager.Options, selection stager.Selection, patchFile string) (*stager.StageResult, error) {
 	// Validate required arguments
-	if len(hunks) == 0 {
-		return fmt.Errorf("at least one -hunk flag is required")
+	if len(selection.HunkSpecs) == 0 && len(selection.Matches) == 0 && len(selection.Excludes) == 0 {
+		return nil, stager.NewInvalidArgumentError("at least one -hunk, -match or -exclude flag is required", nil)
 	}
 	if patchFile == "" {
-		return fmt.Errorf("-patch flag is required")
+		return nil, stager.NewInvalidArgumentError("-patch flag is required", nil)
 	}
 
 	// Create real command executor
-	exec := executor.NewRealCommandExecutor()
-	s := stager.NewStager(exec)
+	return stageWithExecutor(ctx, executor.NewRealCommandExecutor(), opts, selection, patchFile)
+}
+
+// safetyPolicyConfigKey is the git config key that sets the default safety policy
+const safetyPolicyConfigKey = "sequential-stage.safety"
+
+// stagerOptionsFromEnv returns the stager options configured through environment variables.
+// GIT_SEQUENTIAL_STAGE_GIT_PATCH_ID=1 calculates patch IDs with "git patch-id" instead of the built-in implementation.
+// GIT_SEQUENTIAL_STAGE_VERIFY=1 verifies the staged diff after staging.
+func stagerOptionsFromEnv() stager.Options {
+	return stager.Options{
+		GitPatchID: os.Getenv("GIT_SEQUENTIAL_STAGE_GIT_PATCH_ID") != "",
+		Verify:     os.Getenv("GIT_SEQUENTIAL_STAGE_VERIFY") != "",
+	}
+}
+
+// resolveSafetyPolicy returns the safety policy of a command that stages hunks.
+// The policy flag wins if it is set; otherwise GIT_SEQUENTIAL_STAGE_SAFETY is used,
+// and if that is unset too, "git config sequential-stage.safety".
+func resolveSafetyPolicy(ctx context.Context, flagValue string) (stager.SafetyPolicy, error) {
+	policyName := flagValue
+	if policyName == "" {
+		policyName = os.Getenv("GIT_SEQUENTIAL_STAGE_SAFETY")
+	}
+	if policyName == "" {
+		// --default prints nothing instead of failing if the key is not set, which leaves the default policy
+		if output, err := executor.NewRealCommandExecutor().Execute(ctx, "git", "config", "--default", "", "--get", safetyPolicyConfigKey); err == nil {
+			policyName = strings.TrimSpace(string(output))
+		}
+	}
+	if policyName == "" {
+		return stager.SafetyPolicyTargetsOnly, nil
+	}
+	return stager.ParseSafetyPolicy(policyName)
+}
+
+// stageWithExecutor stages the selected hunks using the given executor and stager options
+func stageWithExecutor(ctx context.Context, exec executor.CommandExecutor, opts stager.Options, selection stager.Selection, patchFile string) (*stager.StageResult, error) {
+	s := stager.NewStagerWithOptions(exec, opts)
 	v := validator.NewValidator(exec)
 
+	// Expand glob and directory specifications against the files in the patch and apply exclusions
+	hunkSpecs, excludes, err := expandHunkSpecs(selection.HunkSpecs, selection.Excludes, len(selection.Matches) == 0, patchFile)
+	if err != nil {
+		return nil, err
+	}
+
 	// Separate wildcard files from normal hunk specifications
-	wildcardFiles := []string{}
-	normalHunks := []string{}
+	wildcardFiles, normalHunks, err := splitHunkSpecs(hunkSpecs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reject malformed match specifications before touching the index
+	for _, spec := range selection.Matches {
+		if _, err := stager.ParseMatchSelector(spec); err != nil {
+			return nil, fmt.Errorf("argument validation failed: %w", err)
+		}
+	}
+
+	result := &stager.StageResult{}
+
+	// Staging hunks rolls back on its own; whole files staged after them need a snapshot
+	// covering both, so a wildcard file that fails leaves nothing of this call staged
+	var snapshot *stager.IndexSnapshot
+	if len(wildcardFiles) > 0 {
+		snapshot, err = s.SnapshotIndex(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Stage specific hunks first if any
+	// (Need to process hunks before wildcard to maintain patch consistency)
+	if len(normalHunks) > 0 || len(selection.Matches) > 0 {
+		// Validate arguments for normal hunks
+		if len(normalHunks) > 0 {
+			if err := v.ValidateArgsNew(normalHunks, patchFile); err != nil {
+				return nil, fmt.Errorf("argument validation failed: %w", err)
+			}
+		}
+
+		// Stage hunks
+		hunkResult, err := s.StageSelectionWithResult(ctx, stager.Selection{HunkSpecs: normalHunks, Matches: selection.Matches, Excludes: excludes}, patchFile)
+		if hunkResult != nil {
+			result.Hunks = hunkResult.Hunks
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to stage hunks: %w", err)
+		}
+	}
+
+	// Stage wildcard files directly with git add (after hunks)
+	if len(wildcardFiles) > 0 {
+		if err := s.StageFiles(ctx, wildcardFiles); err != nil {
+			err = fmt.Errorf("failed to stage wildcard files: %w", err)
+			if restoreErr := snapshot.Restore(); restoreErr != nil {
+				return result, errors.Join(err, restoreErr)
+			}
+			result.MarkRolledBack()
+			return result, err
+		}
+		result.Files = wildcardFiles
+	}
+
+	// The staging already succeeded, so a session that cannot be recorded is only reported
+	if opts.Session && !opts.DryRun {
+		if err := s.RecordSession(ctx, patchFile, result); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to record the staging session: %v\n", err)
+		}
+	}
+
+	return result, nil
+}
+
+// expandHunkSpecs expands glob and directory hunk specifications ("src/*.go:*", "docs/**:*", "src/:1")
+// against the files in the patch and removes the hunks excluded by excludes or by negated
+// specifications ("main.go:!4"). If selectAll is set and no hunk is specified, every file of the
+// patch is selected. The expanded exclusions are returned as well.
+// Specifications with literal paths and nothing to exclude are returned unchanged.
+func expandHunkSpecs(hunks, excludes []string, selectAll bool, patchFile string) ([]string, []string, error) {
+	return expandHunkSpecsWith(hunks, excludes, selectAll, func() ([]byte, error) {
+		content, err := os.ReadFile(patchFile)
+		if err != nil {
+			return nil, stager.NewFileNotFoundError(patchFile, err)
+		}
+		return content, nil
+	})
+}
+
+// expandHunkSpecsWith works like expandHunkSpecs for the patch returned by loadPatch,
+// which is only called if some specification needs to be expanded
+func expandHunkSpecsWith(hunks, excludes []string, selectAll bool, loadPatch func() ([]byte, error)) ([]string, []string, error) {
+	selectAll = selectAll && len(hunks) == 0 && len(excludes) > 0
+	if !stager.HasFilePatterns(hunks) && !stager.HasFilePatterns(excludes) && !stager.HasNegatedSpecs(hunks) && len(excludes) == 0 {
+		return hunks, excludes, nil
+	}
+
+	content, err := loadPatch()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	allHunks, err := stager.ParsePatchFileWithGitDiff(string(content))
+	if err != nil {
+		return nil, nil, stager.NewParsingError("patch file", err)
+	}
+
+	hunks, err = stager.ExpandFilePatterns(hunks, allHunks)
+	if err != nil {
+		return nil, nil, err
+	}
+	excludes, err = stager.ExpandFilePatterns(excludes, allHunks)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(excludes) == 0 && !stager.HasNegatedSpecs(hunks) {
+		return hunks, excludes, nil
+	}
+	hunks, err = stager.ApplyExclusions(hunks, excludes, selectAll, allHunks)
+	if err != nil {
+		return nil, nil, err
+	}
+	return hunks, excludes, nil
+}
+
+// splitHunkSpecs separates wildcard files (file:*) from normal hunk specifications (file:numbers)
+func splitHunkSpecs(hunks []string) (wildcardFiles []string, normalHunks []string, err error) {
+	wildcardFiles = []string{}
+	normalHunks = []string{}
 	fileSpecTypes := make(map[string]string) // Track specification type per file
 
 	for _, spec := range hunks {
 		parts := strings.Split(spec, ":")
 		if len(parts) != 2 {
-			return fmt.Errorf("invalid hunk specification: %s (expected format: file:hunks)", spec)
+			return nil, nil, stager.NewInvalidArgumentError(fmt.Sprintf("invalid hunk specification: %s (expected format: file:hunks)", spec), nil)
 		}
 
 		file := parts[0]
@@ -72,10 +258,10 @@ func runGitSequentialStage(ctx context.Context, hunks []string, patchFile string
 		if existingType, exists := fileSpecTypes[file]; exists {
 			// Check for conflicting specifications
 			if hunksSpec == "*" && existingType == "numbers" {
-				return fmt.Errorf("mixed wildcard and hunk numbers not allowed for file %s", file)
+				return nil, nil, stager.NewInvalidArgumentError(fmt.Sprintf("mixed wildcard and hunk numbers not allowed for file %s", file), nil)
 			}
 			if hunksSpec != "*" && existingType == "wildcard" {
-				return fmt.Errorf("mixed wildcard and hunk numbers not allowed for file %s", file)
+				return nil, nil, stager.NewInvalidArgumentError(fmt.Sprintf("mixed wildcard and hunk numbers not allowed for file %s", file), nil)
 			}
 		}
 
@@ -86,35 +272,14 @@ func runGitSequentialStage(ctx context.Context, hunks []string, patchFile string
 		} else {
 			// Check for mixed wildcard and numbers (not allowed)
 			if strings.Contains(hunksSpec, "*") {
-				return fmt.Errorf("mixed wildcard and hunk numbers not allowed in %s", spec)
+				return nil, nil, stager.NewInvalidArgumentError(fmt.Sprintf("mixed wildcard and hunk numbers not allowed in %s", spec), nil)
 			}
 			normalHunks = append(normalHunks, spec)
 			fileSpecTypes[file] = "numbers"
 		}
 	}
 
-	// Stage specific hunks first if any
-	// (Need to process hunks before wildcard to maintain patch consistency)
-	if len(normalHunks) > 0 {
-		// Validate arguments for normal hunks
-		if err := v.ValidateArgsNew(normalHunks, patchFile); err != nil {
-			return fmt.Errorf("argument validation failed: %v", err)
-		}
-
-		// Stage hunks
-		if err := s.StageHunks(ctx, normalHunks, patchFile); err != nil {
-			return fmt.Errorf("failed to stage hunks: %w", err)
-		}
-	}
-
-	// Stage wildcard files directly with git add (after hunks)
-	if len(wildcardFiles) > 0 {
-		if err := s.StageFiles(ctx, wildcardFiles); err != nil {
-			return fmt.Errorf("failed to stage wildcard files: %w", err)
-		}
-	}
-
-	return nil
+	return wildcardFiles, normalHunks, nil
 }
 
 // showUsage displays the top-level usage information
@@ -122,7 +287,13 @@ func showUsage() {
 	fmt.Fprintf(os.Stderr, "Usage: %s <subcommand> [options]\n\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "Subcommands:\n")
 	fmt.Fprintf(os.Stderr, "  stage         Stage specified hunks from a patch file\n")
+	fmt.Fprintf(os.Stderr, "  unstage       Remove specified hunks from the staging area\n")
+	fmt.Fprintf(os.Stderr, "  split         Write selected hunks to standalone patch files\n")
 	fmt.Fprintf(os.Stderr, "  count-hunks   Count hunks per file in the current repository\n")
+	fmt.Fprintf(os.Stderr, "  list-hunks    List every hunk with its number, line ranges and body\n")
+	fmt.Fprintf(os.Stderr, "  remaining     List the hunks of a patch that are not committed yet, with their current numbers\n")
+	fmt.Fprintf(os.Stderr, "  status        Show which hunks of the recorded staging session are staged, committed or left\n")
+	fmt.Fprintf(os.Stderr, "  apply-plan    Stage and commit groups of hunks described in a plan file\n")
 	fmt.Fprintf(os.Stderr, "\nRun '%s <subcommand> --help' for subcommand-specific options.\n", os.Args[0])
 }
 
@@ -131,39 +302,169 @@ func runStageCommand(ctx context.Context, args []string) error {
 	// Create a new FlagSet for the stage subcommand
 	stageFlags := flag.NewFlagSet("stage", flag.ExitOnError)
 	var hunks hunkList
-	patchFile := stageFlags.String("patch", "", "Path to the patch file")
+	patchFile := stageFlags.String("patch", "", "Path to the patch file, or - to read it from stdin")
+	fromWorktree := stageFlags.Bool("from-worktree", false, "Use the output of 'git diff HEAD' (or of 'git diff <base>' with --base) as the patch instead of a patch file")
+	base := stageFlags.String("base", "", "Revision the patch was generated against and hunks are matched with ('git diff <base>'); default HEAD. The patch must match it; hunks changed between it and HEAD cannot be staged")
 	stageFlags.Var(&hunks, "hunk", "File:hunk_numbers to stage (e.g., path/to/file.py:1,3) or file:* for entire file")
+	var matches hunkList
+	stageFlags.Var(&matches, "match", "File-glob:regex selecting every hunk whose changed lines or function context match (e.g., '*.go:func NewLogger')")
+	var excludes hunkList
+	stageFlags.Var(&excludes, "exclude", "File:hunk_numbers not to stage (e.g., main.go:4); without -hunk or -match, everything else in the patch is staged")
+	format := stageFlags.String("format", formatText, "Output format: text or json")
+	dryRun := stageFlags.Bool("dry-run", false, "Show what would be staged without touching the index")
+	fuzzy := stageFlags.Bool("fuzzy", false, "Match hunks that changed since the patch was generated to their current version by similarity, and stage that")
+	allowWorktreeWrite := stageFlags.Bool("allow-worktree-write", false, "Fall back to applying a hunk to the working tree with 'git apply' when it cannot be applied to the index")
+	safety := stageFlags.String("safety", "", "Safety policy for changes staged beforehand: strict, targets-only (default), allow-preexisting or off (overrides GIT_SEQUENTIAL_STAGE_SAFETY and git config sequential-stage.safety)")
+	verify := stageFlags.Bool("verify", false, "Check that the staged diff contains exactly the requested hunks afterwards (or set GIT_SEQUENTIAL_STAGE_VERIFY=1)")
+	session := stageFlags.Bool("session", false, "Record staged hunks in .git/sequential-stage/ (see the status subcommand) and reuse the recorded patch IDs of the same patch")
+	stalePatch := stageFlags.String("stale-patch", stalePatchWarn, "What to do if files changed since the patch was generated: warn, refuse or regenerate (from 'git diff HEAD', renumbering -hunk and -exclude by patch ID)")
 
 	stageFlags.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s stage -patch=<patch_file> -hunk=<file:numbers|*> [-hunk=<file:numbers|*>...]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s stage (-patch=<patch_file|-> | --from-worktree) (-hunk=<file:numbers|*|!numbers> | -match=<file-glob:regex>)... [-exclude=<file:numbers>...]\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "\nStages specified hunks from a patch file sequentially.\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		stageFlags.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
 		fmt.Fprintf(os.Stderr, "  # Stage specific hunks\n")
 		fmt.Fprintf(os.Stderr, "  %s stage -patch=changes.patch -hunk=\"src/main.go:1,3\" -hunk=\"src/test.go:2\"\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Stage a range of hunks, everything from hunk 3 on, or the last hunk\n")
+		fmt.Fprintf(os.Stderr, "  %s stage -patch=changes.patch -hunk=\"src/main.go:2-5\" -hunk=\"src/api.go:3-\" -hunk=\"src/test.go:last\"\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  # Stage entire files using wildcard\n")
-		fmt.Fprintf(os.Stderr, "  %s stage -patch=changes.patch -hunk=\"src/logger.go:*\" -hunk=\"src/test.go:1,2\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s stage -patch=changes.patch -hunk=\"src/logger.go:*\" -hunk=\"src/test.go:1,2\"\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Stage every matching file in the patch, or the first hunk of every file below a directory\n")
+		fmt.Fprintf(os.Stderr, "  %s stage -patch=changes.patch -hunk=\"internal/stager/*.go:*\" -hunk=\"docs/**:*\" -hunk=\"src/:1\"\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Stage only body lines 3-7 of hunk 2, or changed lines 40-55 of a file\n")
+		fmt.Fprintf(os.Stderr, "  %s stage -patch=changes.patch -hunk=\"src/main.go:2[3-7]\" -hunk=\"src/api.go:L40-L55\"\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Stage hunks by the patch IDs shown by list-hunks\n")
+		fmt.Fprintf(os.Stderr, "  %s stage -patch=changes.patch -hunk=\"id:3fa9c1d2,88aa01bc\"\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Stage every hunk of main.go except hunk 4, or everything in the patch except it\n")
+		fmt.Fprintf(os.Stderr, "  %s stage -patch=changes.patch -hunk=\"main.go:!4\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s stage -patch=changes.patch -exclude=\"main.go:4\"\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Stage every hunk whose changed lines match a regular expression\n")
+		fmt.Fprintf(os.Stderr, "  %s stage -patch=changes.patch -match=\"*.go:func NewLogger\"\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Read the patch from stdin, or take it from the working tree directly\n")
+		fmt.Fprintf(os.Stderr, "  git diff HEAD | %s stage -patch=- -hunk=\"src/main.go:1\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s stage --from-worktree -hunk=\"src/main.go:1\"\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Preview the resulting staged diff without changing the index\n")
+		fmt.Fprintf(os.Stderr, "  %s stage -patch=changes.patch -hunk=\"src/main.go:1,3\" --dry-run\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Fail (and restore the index) unless exactly the requested hunks end up staged\n")
+		fmt.Fprintf(os.Stderr, "  %s stage -patch=changes.patch -hunk=\"src/main.go:1,3\" --verify\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Stage hunks that were edited after the patch was generated in their current version\n")
+		fmt.Fprintf(os.Stderr, "  %s stage -patch=changes.patch -hunk=\"src/main.go:1\" --fuzzy\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Allow the working tree to be written when a hunk cannot be applied to the index\n")
+		fmt.Fprintf(os.Stderr, "  %s stage -patch=changes.patch -hunk=\"src/main.go:1\" --allow-worktree-write\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Stage hunks on top of changes that were staged with git add beforehand\n")
+		fmt.Fprintf(os.Stderr, "  %s stage -patch=changes.patch -hunk=\"src/main.go:1\" --safety=allow-preexisting\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Record the staged hunks, then show what is left of the patch\n")
+		fmt.Fprintf(os.Stderr, "  %s stage -patch=changes.patch -hunk=\"src/main.go:1\" --session\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s status\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Refuse to stage from a patch file that no longer matches the working tree\n")
+		fmt.Fprintf(os.Stderr, "  %s stage -patch=changes.patch -hunk=\"src/main.go:1\" --stale-patch=refuse\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Stage hunks of a patch generated against the merge base with main\n")
+		fmt.Fprintf(os.Stderr, "  %s stage -patch=changes.patch -hunk=\"src/main.go:1\" --base=$(git merge-base HEAD main)\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Report per-hunk results as JSON\n")
+		fmt.Fprintf(os.Stderr, "  %s stage -patch=changes.patch -hunk=\"src/main.go:1\" --format=json\n", os.Args[0])
 	}
 
 	if err := stageFlags.Parse(args); err != nil {
 		return err
 	}
 
+	if err := validateFormat(*format); err != nil {
+		stageFlags.Usage()
+		fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+		return &usageShownError{message: err.Error()}
+	}
+	if err := validateStalePatchMode(*stalePatch); err != nil {
+		stageFlags.Usage()
+		fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+		return &usageShownError{message: err.Error()}
+	}
+
 	// Validate required flags
-	if *patchFile == "" {
+	if *patchFile == "" && !*fromWorktree {
 		stageFlags.Usage()
 		fmt.Fprintf(os.Stderr, "\nError: patch file required\n")
 		return &usageShownError{message: "patch file required"}
 	}
-	if len(hunks) == 0 {
+	if *patchFile != "" && *fromWorktree {
 		stageFlags.Usage()
-		fmt.Fprintf(os.Stderr, "\nError: at least one -hunk flag is required\n")
-		return &usageShownError{message: "at least one -hunk flag is required"}
+		fmt.Fprintf(os.Stderr, "\nError: -patch and --from-worktree cannot be used together\n")
+		return &usageShownError{message: "-patch and --from-worktree cannot be used together"}
+	}
+	if len(hunks) == 0 && len(matches) == 0 && len(excludes) == 0 {
+		stageFlags.Usage()
+		fmt.Fprintf(os.Stderr, "\nError: at least one -hunk, -match or -exclude flag is required\n")
+		return &usageShownError{message: "at least one -hunk, -match or -exclude flag is required"}
+	}
+
+	// Errors before staging are reported in the requested format as well
+	fail := func(err error) error {
+		if *format == formatJSON {
+			return writeStageJSON(nil, "", *dryRun, err)
+		}
+		return err
+	}
+
+	// The base goes onto git command lines from here on, so make sure it is a commit first
+	if *base != "" {
+		if err := stager.VerifyBaseRevision(ctx, executor.NewRealCommandExecutor(), *base); err != nil {
+			return fail(err)
+		}
+	}
+
+	// Capture the patch from stdin or the working tree before anything is staged
+	resolvedPatch, cleanup, err := resolvePatchFile(ctx, executor.NewRealCommandExecutor(), *patchFile, *fromWorktree, *base, os.Stdin)
+	if err != nil {
+		return fail(err)
 	}
 
 	// Call the existing implementation
-	if err := runGitSequentialStage(ctx, hunks, *patchFile); err != nil {
+	var result *stager.StageResult
+	var stagedDiff string
+	selection := stager.Selection{HunkSpecs: hunks, Matches: matches, Excludes: excludes}
+	opts := stagerOptionsFromEnv()
+	if opts.SafetyPolicy, err = resolveSafetyPolicy(ctx, *safety); err != nil {
+		cleanup()
+		return fail(err)
+	}
+	opts.Verify = opts.Verify || *verify
+	opts.AllowWorktreeWrite = *allowWorktreeWrite
+	opts.Fuzzy = *fuzzy
+	opts.Session = *session
+	opts.Base = *base
+
+	// A patch captured from the working tree just now cannot be stale
+	if !*fromWorktree {
+		var checkedPatch string
+		var removeRegenerated func()
+		checkedPatch, removeRegenerated, err = checkStalePatch(ctx, executor.NewRealCommandExecutor(), *stalePatch, resolvedPatch, *base)
+		removeResolved := cleanup
+		cleanup = func() {
+			removeRegenerated()
+			removeResolved()
+		}
+		// Hunk numbers were taken from the stale patch
+		if err == nil && checkedPatch != resolvedPatch {
+			selection, err = remapSelection(ctx, executor.NewRealCommandExecutor(), opts, selection, resolvedPatch, checkedPatch)
+		}
+		resolvedPatch = checkedPatch
+	}
+	if err == nil {
+		if *dryRun {
+			result, stagedDiff, err = runDryRunStage(ctx, opts, selection, resolvedPatch)
+		} else {
+			result, err = runStageSelectionWithOptions(ctx, opts, selection, resolvedPatch)
+		}
+	}
+	// The temporary patch is not needed anymore (error handling below may exit)
+	cleanup()
+
+	if *format == formatJSON {
+		return writeStageJSON(result, stagedDiff, *dryRun, err)
+	}
+
+	if err != nil {
 		// Check if user cancelled or timeout occurred
 		if errors.Is(err, context.Canceled) {
 			fmt.Fprintf(os.Stderr, "Operation cancelled by user\n")
@@ -178,56 +479,441 @@ func runStageCommand(ctx context.Context, args []string) error {
 		// handleStageError calls os.Exit(1) and never returns
 	}
 
+	if *dryRun {
+		printDryRunResult(stagedDiff)
+		return nil
+	}
+
 	// Success: display success message
 	fmt.Printf("Successfully staged specified hunks\n")
+	printFuzzyMatches(result)
+	printWorktreeWrites(result)
+	return nil
+}
+
+// writeStageJSON writes the outcome of the stage subcommand as JSON.
+// It exits with a non-zero status if staging failed.
+func writeStageJSON(result *stager.StageResult, stagedDiff string, dryRun bool, err error) error {
+	output := newStageOutput(result, err)
+	if dryRun {
+		output.DryRun = true
+		output.StagedDiff = stagedDiff
+	}
+	if writeErr := writeJSON(output); writeErr != nil {
+		return writeErr
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(130) // Standard exit code for SIGINT
+		}
+		os.Exit(1)
+	}
+	return nil
+}
+
+// printFuzzyMatches reports the hunks that --fuzzy matched to their current version by similarity
+func printFuzzyMatches(result *stager.StageResult) {
+	if result == nil {
+		return
+	}
+	for _, hunk := range result.Hunks {
+		if hunk.MatchedPatchID != "" {
+			fmt.Printf("Fuzzy match: %s:%d (patch ID %s) staged as its current version id:%s (confidence %.0f%%)\n",
+				hunk.FilePath, hunk.IndexInFile, hunk.PatchID, hunk.MatchedPatchID, hunk.Confidence*100)
+		}
+	}
+}
+
+// printWorktreeWrites reports the hunks that --allow-worktree-write applied to the
+// working tree instead of the index, since they are not staged
+func printWorktreeWrites(result *stager.StageResult) {
+	if result == nil {
+		return
+	}
+	for _, hunk := range result.Hunks {
+		if hunk.Strategy == stager.ApplyStrategyWorkingDirectory {
+			fmt.Fprintf(os.Stderr, "Warning: %s:%d (patch ID %s) was applied to the working tree, not the index\n", hunk.FilePath, hunk.IndexInFile, hunk.PatchID)
+		}
+	}
+}
+
+// runUnstageCommand handles the 'unstage' subcommand
+func runUnstageCommand(ctx context.Context, args []string) error {
+	// Create a new FlagSet for the unstage subcommand
+	unstageFlags := flag.NewFlagSet("unstage", flag.ExitOnError)
+	var hunks hunkList
+	unstageFlags.Var(&hunks, "hunk", "File:hunk_numbers to unstage, numbered as in 'git diff --cached' (e.g., path/to/file.py:1,3) or file:* for entire file")
+	format := unstageFlags.String("format", formatText, "Output format: text or json")
+	verify := unstageFlags.Bool("verify", false, "Check that exactly the requested hunks were removed from the staged diff (or set GIT_SEQUENTIAL_STAGE_VERIFY=1)")
+
+	unstageFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s unstage -hunk=<file:numbers|*> [-hunk=<file:numbers|*>...]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nRemoves specified hunks from the staging area, leaving the working tree untouched.\n")
+		fmt.Fprintf(os.Stderr, "Hunks are numbered as in 'git diff --cached'.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		unstageFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  # Unstage the second staged hunk of main.go, keeping the other staged hunks\n")
+		fmt.Fprintf(os.Stderr, "  %s unstage -hunk=\"src/main.go:2\"\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Unstage a whole file\n")
+		fmt.Fprintf(os.Stderr, "  %s unstage -hunk=\"src/logger.go:*\"\n", os.Args[0])
+	}
+
+	if err := unstageFlags.Parse(args); err != nil {
+		return err
+	}
+
+	if err := validateFormat(*format); err != nil {
+		unstageFlags.Usage()
+		fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+		return &usageShownError{message: err.Error()}
+	}
+	if len(hunks) == 0 {
+		unstageFlags.Usage()
+		fmt.Fprintf(os.Stderr, "\nError: at least one -hunk flag is required\n")
+		return &usageShownError{message: "at least one -hunk flag is required"}
+	}
+
+	opts := stagerOptionsFromEnv()
+	opts.Verify = opts.Verify || *verify
+	result, err := runUnstage(ctx, opts, hunks)
+
+	if *format == formatJSON {
+		if writeErr := writeJSON(newStageOutput(result, err)); writeErr != nil {
+			return writeErr
+		}
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				os.Exit(130) // Standard exit code for SIGINT
+			}
+			os.Exit(1)
+		}
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Successfully unstaged specified hunks\n")
 	return nil
 }
 
+// runUnstage removes the specified hunks from the staging area.
+// Specifications are resolved against "git diff --cached" instead of a patch file.
+func runUnstage(ctx context.Context, opts stager.Options, hunks []string) (*stager.StageResult, error) {
+	exec := executor.NewRealCommandExecutor()
+	s := stager.NewStagerWithOptions(exec, opts)
+
+	// Expand glob, directory and negated specifications against the staged changes
+	hunks, _, err := expandHunkSpecsWith(hunks, nil, false, func() ([]byte, error) {
+		stagedDiff, err := exec.Execute(ctx, "git", "diff", "--cached")
+		if err != nil {
+			return nil, stager.NewGitCommandError("git diff --cached", err)
+		}
+		return stagedDiff, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	wildcardFiles, normalHunks, err := splitHunkSpecs(hunks)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &stager.StageResult{}
+
+	if len(normalHunks) > 0 {
+		hunkResult, err := s.UnstageHunksWithResult(ctx, normalHunks)
+		if hunkResult != nil {
+			result.Hunks = hunkResult.Hunks
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to unstage hunks: %w", err)
+		}
+	}
+
+	if len(wildcardFiles) > 0 {
+		if err := s.UnstageFiles(ctx, wildcardFiles); err != nil {
+			return result, fmt.Errorf("failed to unstage wildcard files: %w", err)
+		}
+		result.Files = wildcardFiles
+	}
+
+	return result, nil
+}
+
 // runCountHunksCommand handles the 'count-hunks' subcommand
 func runCountHunksCommand(ctx context.Context, args []string) error {
 	// Create a new FlagSet for the count-hunks subcommand
 	countFlags := flag.NewFlagSet("count-hunks", flag.ExitOnError)
+	format := countFlags.String("format", formatText, "Output format: text or json")
+	base := countFlags.String("base", "HEAD", "Revision to count the hunks of the working tree against ('git diff <base>')")
 
 	countFlags.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s count-hunks\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s count-hunks [--format=text|json] [--base=<rev>]\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "\nCount hunks per file in the current repository.\n\n")
-		fmt.Fprintf(os.Stderr, "This command runs 'git diff HEAD' and counts the number of hunks for each modified file.\n")
+		fmt.Fprintf(os.Stderr, "This command runs 'git diff HEAD' (or 'git diff <base>') and counts the number of hunks for each modified file.\n")
 		fmt.Fprintf(os.Stderr, "Output format: <filepath>: <count>\n")
 		fmt.Fprintf(os.Stderr, "Files are sorted alphabetically.\n\n")
-		fmt.Fprintf(os.Stderr, "Examples:\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		countFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
 		fmt.Fprintf(os.Stderr, "  %s count-hunks\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s count-hunks --format=json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s count-hunks --base=HEAD~1\n", os.Args[0])
 	}
 
 	if err := countFlags.Parse(args); err != nil {
 		return err
 	}
 
+	if err := validateFormat(*format); err != nil {
+		countFlags.Usage()
+		fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+		return &usageShownError{message: err.Error()}
+	}
+
 	// Create command executor
 	exec := executor.NewRealCommandExecutor()
 
-	// Execute git diff HEAD
-	output, err := exec.Execute(ctx, "git", "diff", "HEAD")
+	// The base goes onto the git command line, so make sure it is a commit first
+	if err := stager.VerifyBaseRevision(ctx, exec, *base); err != nil {
+		return err
+	}
+
+	// Execute git diff <base>
+	output, err := exec.Execute(ctx, "git", "diff", "--end-of-options", *base)
 	if err != nil {
 		return executor.WrapGitError(err, "git diff")
 	}
 
-	// Count hunks in diff output
-	hunkCounts, err := stager.CountHunksInDiff(string(output))
+	// Count hunks in diff output (sorted alphabetically by file path)
+	hunkCounts, err := stager.CountHunksPerFile(string(output))
 	if err != nil {
 		return fmt.Errorf("failed to count hunks: %w", err)
 	}
 
-	// Sort filenames alphabetically
-	var filenames []string
-	for filename := range hunkCounts {
-		filenames = append(filenames, filename)
+	if *format == formatJSON {
+		return writeJSON(newCountHunksOutput(hunkCounts))
 	}
-	sort.Strings(filenames)
 
 	// Output in "filename: count" format
 	// For binary files, this will show "*" instead of a number
-	for _, filename := range filenames {
-		fmt.Printf("%s: %s\n", filename, hunkCounts[filename])
+	for _, count := range hunkCounts {
+		if count.IsBinary {
+			fmt.Printf("%s: *\n", count.FilePath)
+		} else {
+			fmt.Printf("%s: %d\n", count.FilePath, count.Hunks)
+		}
+	}
+
+	return nil
+}
+
+// runListHunksCommand handles the 'list-hunks' subcommand
+func runListHunksCommand(ctx context.Context, args []string) error {
+	// Create a new FlagSet for the list-hunks subcommand
+	listFlags := flag.NewFlagSet("list-hunks", flag.ExitOnError)
+	patchFile := listFlags.String("patch", "", "Path to a patch file to list (default: output of 'git diff HEAD')")
+	showBody := listFlags.Bool("body", false, "Print the body of each hunk below its summary line")
+
+	listFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s list-hunks [-patch=<patch_file>] [-body]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nList every hunk in the current repository or in a patch file.\n\n")
+		fmt.Fprintf(os.Stderr, "Output format: <filepath>:<number> (#<global>) +<added> -<removed> @@ -a,b +c,d @@ id:<patch ID> <context>\n")
+		fmt.Fprintf(os.Stderr, "<filepath>:<number> and id:<patch ID> are specifications accepted by 'stage -hunk'.\n")
+		fmt.Fprintf(os.Stderr, "Unlike numbers, patch IDs do not change when other hunks are staged or edited.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		listFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s list-hunks\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s list-hunks -patch=changes.patch -body\n", os.Args[0])
+	}
+
+	if err := listFlags.Parse(args); err != nil {
+		return err
+	}
+
+	var diffOutput []byte
+	if *patchFile != "" {
+		content, err := os.ReadFile(*patchFile)
+		if err != nil {
+			return fmt.Errorf("failed to read patch file: %w", err)
+		}
+		diffOutput = content
+	} else {
+		exec := executor.NewRealCommandExecutor()
+		output, err := exec.Execute(ctx, "git", "diff", "HEAD")
+		if err != nil {
+			return executor.WrapGitError(err, "git diff")
+		}
+		diffOutput = output
+	}
+
+	opts := stagerOptionsFromEnv()
+	s := stager.NewStagerWithOptions(executor.NewRealCommandExecutor(), opts)
+	summaries, err := s.ListHunks(ctx, string(diffOutput))
+	if err != nil {
+		return fmt.Errorf("failed to list hunks: %w", err)
+	}
+
+	for _, summary := range summaries {
+		fmt.Println(formatHunkSummary(summary))
+		if *showBody && summary.Body != "" {
+			for _, line := range strings.Split(strings.TrimSuffix(summary.Body, "\n"), "\n") {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+	}
+
+	return nil
+}
+
+// formatHunkSummary formats a single line of list-hunks output
+func formatHunkSummary(summary stager.HunkSummary) string {
+	prefix := fmt.Sprintf("%s:%d (#%d)", summary.FilePath, summary.IndexInFile, summary.GlobalIndex)
+
+	switch {
+	case summary.IsBinary:
+		// Binary files can only be staged as a whole
+		return fmt.Sprintf("%s binary (stage with %s:*)", prefix, summary.FilePath)
+	case !summary.HasContent:
+		return fmt.Sprintf("%s no content changes", prefix)
+	}
+
+	line := fmt.Sprintf("%s +%d -%d %s", prefix, summary.LinesAdded, summary.LinesDeleted, summary.Range())
+	if summary.PatchID != "" {
+		line += " id:" + summary.PatchID
+	}
+	if summary.Context != "" {
+		line += " " + summary.Context
+	}
+	return line
+}
+
+// runApplyPlanCommand handles the 'apply-plan' subcommand
+func runApplyPlanCommand(ctx context.Context, args []string) error {
+	// Create a new FlagSet for the apply-plan subcommand
+	planFlags := flag.NewFlagSet("apply-plan", flag.ExitOnError)
+	planFile := planFlags.String("plan", "", "Path to the plan file (YAML or JSON)")
+	patchFile := planFlags.String("patch", "", "Path to the patch file (overrides 'patch' in the plan)")
+
+	planFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s apply-plan -plan=<plan_file> [-patch=<patch_file>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nStages and commits each group of hunks listed in a plan file, in order.\n")
+		fmt.Fprintf(os.Stderr, "Patch IDs are computed once and reused for every commit.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		planFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nPlan format (YAML):\n")
+		fmt.Fprintf(os.Stderr, "  patch: changes.patch\n")
+		fmt.Fprintf(os.Stderr, "  commits:\n")
+		fmt.Fprintf(os.Stderr, "    - message: \"feat: Add structured logging\"\n")
+		fmt.Fprintf(os.Stderr, "      hunks: [\"src/logger.go:1,2\", \"src/config.go:*\"]\n")
+		fmt.Fprintf(os.Stderr, "    - message: \"test: Add logger tests\"\n")
+		fmt.Fprintf(os.Stderr, "      hunks: [\"src/logger_test.go:1\"]\n")
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s apply-plan -plan=plan.yaml\n", os.Args[0])
+	}
+
+	if err := planFlags.Parse(args); err != nil {
+		return err
+	}
+
+	if *planFile == "" {
+		planFlags.Usage()
+		fmt.Fprintf(os.Stderr, "\nError: plan file required\n")
+		return &usageShownError{message: "plan file required"}
+	}
+
+	plan, err := loadCommitPlan(*planFile)
+	if err != nil {
+		return err
+	}
+	if *patchFile != "" {
+		plan.Patch = *patchFile
+	}
+
+	if err := runApplyPlan(ctx, plan); err != nil {
+		if errors.Is(err, context.Canceled) {
+			fmt.Fprintf(os.Stderr, "Operation cancelled by user\n")
+			os.Exit(130) // Standard exit code for SIGINT
+		}
+		handleStageError(err)
+		// handleStageError calls os.Exit(1) and never returns
+	}
+
+	fmt.Printf("Successfully applied plan with %d commits\n", len(plan.Commits))
+	return nil
+}
+
+// runApplyPlan stages and commits each group of the plan in order.
+// The patch file is parsed and its patch IDs are computed only once.
+func runApplyPlan(ctx context.Context, plan *commitPlan) error {
+	if err := plan.validate(); err != nil {
+		return err
+	}
+
+	opts := stagerOptionsFromEnv()
+	policy, err := resolveSafetyPolicy(ctx, "")
+	if err != nil {
+		return err
+	}
+	opts.SafetyPolicy = policy
+	exec := executor.NewRealCommandExecutor()
+	s := stager.NewStagerWithOptions(exec, opts)
+	v := validator.NewValidator(exec)
+
+	// Validate every group up front so that a typo in a later group
+	// doesn't leave the history with only some of the commits
+	type commitGroup struct {
+		wildcardFiles []string
+		normalHunks   []string
+	}
+	groups := make([]commitGroup, len(plan.Commits))
+	for i, commit := range plan.Commits {
+		hunks, _, err := expandHunkSpecs(commit.Hunks, nil, false, plan.Patch)
+		if err != nil {
+			return fmt.Errorf("commit %d: %w", i+1, err)
+		}
+		wildcardFiles, normalHunks, err := splitHunkSpecs(hunks)
+		if err != nil {
+			return fmt.Errorf("commit %d: %w", i+1, err)
+		}
+		if len(normalHunks) > 0 {
+			if err := v.ValidateArgsNew(normalHunks, plan.Patch); err != nil {
+				return fmt.Errorf("commit %d: argument validation failed: %w", i+1, err)
+			}
+		}
+		groups[i] = commitGroup{wildcardFiles: wildcardFiles, normalHunks: normalHunks}
+	}
+
+	patch, err := s.PreparePatch(ctx, plan.Patch)
+	if err != nil {
+		return err
+	}
+
+	for i, commit := range plan.Commits {
+		group := groups[i]
+
+		if len(group.normalHunks) > 0 {
+			if _, err := s.StagePreparedHunks(ctx, patch, group.normalHunks); err != nil {
+				return fmt.Errorf("commit %d (%s): failed to stage hunks: %w", i+1, commit.Message, err)
+			}
+		}
+
+		if len(group.wildcardFiles) > 0 {
+			if err := s.StageFiles(ctx, group.wildcardFiles); err != nil {
+				return fmt.Errorf("commit %d (%s): failed to stage wildcard files: %w", i+1, commit.Message, err)
+			}
+		}
+
+		if _, err := exec.Execute(ctx, "git", "commit", "-m", commit.Message); err != nil {
+			return fmt.Errorf("commit %d (%s): %w", i+1, commit.Message, executor.WrapGitError(err, "git commit"))
+		}
+
+		fmt.Printf("[%d/%d] Committed: %s\n", i+1, len(plan.Commits), commit.Message)
 	}
 
 	return nil
@@ -245,8 +931,20 @@ func routeSubcommand(ctx context.Context, args []string) error {
 	switch subcommand {
 	case "stage":
 		return runStageCommand(ctx, subcommandArgs)
+	case "unstage":
+		return runUnstageCommand(ctx, subcommandArgs)
+	case "split":
+		return runSplitCommand(ctx, subcommandArgs)
 	case "count-hunks":
 		return runCountHunksCommand(ctx, subcommandArgs)
+	case "remaining":
+		return runRemainingCommand(ctx, subcommandArgs)
+	case "status":
+		return runStatusCommand(ctx, subcommandArgs)
+	case "list-hunks":
+		return runListHunksCommand(ctx, subcommandArgs)
+	case "apply-plan":
+		return runApplyPlanCommand(ctx, subcommandArgs)
 	default:
 		return fmt.Errorf("unknown subcommand: %s", subcommand)
 	}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/syou6162/git-sequential-stage/internal/stager"
+)
+
+// Output formats accepted by the --format flag
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// validateFormat checks that the given output format is supported
+func validateFormat(format string) error {
+	if format != formatText && format != formatJSON {
+		return fmt.Errorf("unsupported format: %s (expected %s or %s)", format, formatText, formatJSON)
+	}
+	return nil
+}
+
+// countHunksOutput is the JSON representation of count-hunks output
+type countHunksOutput struct {
+	Files []fileHunkCountOutput `json:"files"`
+}
+
+// fileHunkCountOutput is the JSON representation of a single file in count-hunks output
+type fileHunkCountOutput struct {
+	Path   string `json:"path"`
+	Hunks  int    `json:"hunks"`
+	Binary bool   `json:"binary"`
+}
+
+// stageOutput is the JSON representation of a stage run
+type stageOutput struct {
+	Success    bool               `json:"success"`
+	Hunks      []hunkResultOutput `json:"hunks"`
+	Files      []string           `json:"files"`
+	RolledBack bool               `json:"rolled_back"`
+	DryRun     bool               `json:"dry_run,omitempty"`
+	StagedDiff string             `json:"staged_diff,omitempty"` // Resulting staged diff in dry-run mode
+	Error      *errorOutput       `json:"error,omitempty"`
+}
+
+// hunkResultOutput is the JSON representation of a single hunk in stage output
+type hunkResultOutput struct {
+	File     string `json:"file"`
+	Hunk     int    `json:"hunk"`
+	PatchID  string `json:"patch_id"`
+	Status   string `json:"status"`
+	Strategy string `json:"strategy"`
+
+	// Set for hunks matched by similarity with --fuzzy
+	MatchedPatchID  string  `json:"matched_patch_id,omitempty"`
+	FuzzyConfidence float64 `json:"fuzzy_confidence,omitempty"`
+}
+
+// statusOutput is the JSON representation of status output
+type statusOutput struct {
+	PatchHash string              `json:"patch_hash"`
+	Unstaged  int                 `json:"unstaged"`
+	Hunks     []sessionHunkOutput `json:"hunks"`
+}
+
+// sessionHunkOutput is the JSON representation of a single hunk in status output
+type sessionHunkOutput struct {
+	File    string `json:"file"`
+	Hunk    int    `json:"hunk"`
+	PatchID string `json:"patch_id"`
+	State   string `json:"state"`
+}
+
+// remainingOutput is the JSON representation of remaining output
+type remainingOutput struct {
+	Total int                   `json:"total"` // Number of hunks in the patch
+	Hunks []remainingHunkOutput `json:"hunks"`
+}
+
+// remainingHunkOutput is the JSON representation of a single hunk in remaining output
+type remainingHunkOutput struct {
+	File         string `json:"file"`
+	Hunk         int    `json:"hunk"`
+	PatchID      string `json:"patch_id"`
+	OriginalFile string `json:"original_file"`
+	OriginalHunk int    `json:"original_hunk"`
+}
+
+// errorOutput is the JSON representation of an error.
+// Category is "stager" or "safety" for typed errors, and Type holds
+// the StagerError.Type or SafetyError.Type name respectively.
+// A failed --verify check has category "verification" and lists the patch IDs
+// of the missing and unexpected hunks.
+type errorOutput struct {
+	Category string   `json:"category"`
+	Type     string   `json:"type"`
+	Message  string   `json:"message"`
+	Advice   string   `json:"advice,omitempty"`
+	Missing  []string `json:"missing,omitempty"`
+	Extra    []string `json:"extra,omitempty"`
+}
+
+// newCountHunksOutput converts hunk counts into their JSON representation
+func newCountHunksOutput(counts []stager.FileHunkCount) countHunksOutput {
+	output := countHunksOutput{Files: make([]fileHunkCountOutput, 0, len(counts))}
+	for _, count := range counts {
+		output.Files = append(output.Files, fileHunkCountOutput{
+			Path:   count.FilePath,
+			Hunks:  count.Hunks,
+			Binary: count.IsBinary,
+		})
+	}
+	return output
+}
+
+// newStageOutput converts a stage result and error into their JSON representation
+func newStageOutput(result *stager.StageResult, err error) stageOutput {
+	output := stageOutput{
+		Success: err == nil,
+		Hunks:   []hunkResultOutput{},
+		Files:   []string{},
+	}
+
+	if result != nil {
+		for _, hunk := range result.Hunks {
+			output.Hunks = append(output.Hunks, hunkResultOutput{
+				File:     hunk.FilePath,
+				Hunk:     hunk.IndexInFile,
+				PatchID:  hunk.PatchID,
+				Status:   hunk.Status.String(),
+				Strategy: hunk.Strategy.String(),
+
+				MatchedPatchID:  hunk.MatchedPatchID,
+				FuzzyConfidence: hunk.Confidence,
+			})
+		}
+		output.Files = append(output.Files, result.Files...)
+		output.RolledBack = result.RolledBack
+	}
+
+	if err != nil {
+		output.Error = newErrorOutput(err)
+	}
+
+	return output
+}
+
+// newStatusOutput converts a session and the state of its hunks into their JSON representation
+func newStatusOutput(session *stager.Session, statuses []stager.SessionHunkStatus) statusOutput {
+	output := statusOutput{
+		PatchHash: session.PatchHash,
+		Unstaged:  countUnstaged(statuses),
+		Hunks:     make([]sessionHunkOutput, 0, len(statuses)),
+	}
+	for _, status := range statuses {
+		output.Hunks = append(output.Hunks, sessionHunkOutput{
+			File:    status.FilePath,
+			Hunk:    status.IndexInFile,
+			PatchID: status.PatchID,
+			State:   status.State.String(),
+		})
+	}
+	return output
+}
+
+// newRemainingOutput converts the remaining hunks of a patch into their JSON representation
+func newRemainingOutput(remaining []stager.RemainingHunk, total int) remainingOutput {
+	output := remainingOutput{Total: total, Hunks: make([]remainingHunkOutput, 0, len(remaining))}
+	for _, hunk := range remaining {
+		output.Hunks = append(output.Hunks, remainingHunkOutput{
+			File:         hunk.CurrentFilePath,
+			Hunk:         hunk.CurrentIndexInFile,
+			PatchID:      hunk.PatchID,
+			OriginalFile: hunk.FilePath,
+			OriginalHunk: hunk.IndexInFile,
+		})
+	}
+	return output
+}
+
+// newErrorOutput classifies an error by its stager or safety error type
+func newErrorOutput(err error) *errorOutput {
+	var safetyErr *stager.SafetyError
+	if errors.As(err, &safetyErr) {
+		return &errorOutput{
+			Category: "safety",
+			Type:     safetyErr.Type.String(),
+			Message:  safetyErr.Message,
+			Advice:   safetyErr.Advice,
+		}
+	}
+
+	var verificationErr *stager.VerificationError
+	if errors.As(err, &verificationErr) {
+		return &errorOutput{
+			Category: "verification",
+			Type:     "StagedDiffMismatch",
+			Message:  err.Error(),
+			Missing:  verificationErr.Missing,
+			Extra:    verificationErr.Extra,
+		}
+	}
+
+	var stagerErr *stager.StagerError
+	if errors.As(err, &stagerErr) {
+		return &errorOutput{
+			Category: "stager",
+			Type:     stagerErr.Type.String(),
+			Message:  err.Error(),
+		}
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return &errorOutput{Category: "context", Type: "Canceled", Message: err.Error()}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &errorOutput{Category: "context", Type: "DeadlineExceeded", Message: err.Error()}
+	}
+
+	return &errorOutput{Category: "unknown", Type: "Unknown", Message: err.Error()}
+}
+
+// writeJSON writes v to stdout as indented JSON
+func writeJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/syou6162/git-sequential-stage/internal/executor"
+	"github.com/syou6162/git-sequential-stage/internal/stager"
+)
+
+// stdinPatch is the -patch value that reads the patch from standard input
+const stdinPatch = "-"
+
+// resolvePatchFile returns the path of the patch file to stage from.
+// With -patch=- the patch is read from stdin, and with --from-worktree it is captured
+// from "git diff <base>" (HEAD if base is empty); in both cases it is written to a temporary
+// file that the returned cleanup function removes. Any other -patch value is returned as is.
+func resolvePatchFile(ctx context.Context, exec executor.CommandExecutor, patchFile string, fromWorktree bool, base string, stdin io.Reader) (string, func(), error) {
+	noop := func() {}
+
+	var content []byte
+	switch {
+	case fromWorktree && patchFile != "":
+		return "", noop, stager.NewInvalidArgumentError("-patch and --from-worktree cannot be used together", nil)
+	case fromWorktree:
+		if base == "" {
+			base = "HEAD"
+		}
+		output, err := exec.Execute(ctx, "git", "diff", "--end-of-options", base)
+		if err != nil {
+			return "", noop, stager.NewGitCommandError("git diff "+base, err)
+		}
+		content = output
+	case patchFile == stdinPatch:
+		input, err := io.ReadAll(stdin)
+		if err != nil {
+			return "", noop, stager.NewIOError("read patch from stdin", err)
+		}
+		content = input
+	default:
+		return patchFile, noop, nil
+	}
+
+	tmpFile, err := os.CreateTemp("", "git-sequential-stage-*.patch")
+	if err != nil {
+		return "", noop, stager.NewIOError("create temporary patch file", err)
+	}
+	cleanup := func() { _ = os.Remove(tmpFile.Name()) }
+
+	if _, err := tmpFile.Write(content); err != nil {
+		_ = tmpFile.Close()
+		cleanup()
+		return "", noop, stager.NewIOError("write temporary patch file", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		cleanup()
+		return "", noop, stager.NewIOError("write temporary patch file", err)
+	}
+
+	return tmpFile.Name(), cleanup, nil
+}
+
+// Stale patch modes of the stage subcommand (--stale-patch)
+const (
+	stalePatchWarn       = "warn"
+	stalePatchRefuse     = "refuse"
+	stalePatchRegenerate = "regenerate"
+)
+
+// validateStalePatchMode checks the value of --stale-patch
+func validateStalePatchMode(mode string) error {
+	switch mode {
+	case stalePatchWarn, stalePatchRefuse, stalePatchRegenerate:
+		return nil
+	default:
+		return fmt.Errorf("invalid --stale-patch mode: %s (expected %s, %s or %s)", mode, stalePatchWarn, stalePatchRefuse, stalePatchRegenerate)
+	}
+}
+
+// checkStalePatch compares the patch file with the working tree before anything is staged.
+// If files changed since the patch was generated, the "warn" mode prints them to stderr and
+// keeps the patch, "refuse" fails with a StalePatch error, and "regenerate" replaces the patch
+// with a fresh "git diff <base>" (see remapSelection for the hunk numbers).
+// The returned cleanup function removes a regenerated patch.
+func checkStalePatch(ctx context.Context, exec executor.CommandExecutor, mode, patchFile, base string) (string, func(), error) {
+	noop := func() {}
+
+	content, err := os.ReadFile(patchFile)
+	if err != nil {
+		return "", noop, stager.NewFileNotFoundError(patchFile, err)
+	}
+
+	stale, err := stager.NewStager(exec).FindStaleFiles(ctx, string(content))
+	if err != nil || len(stale) == 0 {
+		return patchFile, noop, err
+	}
+
+	switch mode {
+	case stalePatchRefuse:
+		return "", noop, stager.NewStalePatchError(stale)
+	case stalePatchRegenerate:
+		if base == "" {
+			base = "HEAD"
+		}
+		fmt.Fprintf(os.Stderr, "Patch file is stale (%s changed); regenerating it from 'git diff %s'\n", strings.Join(stale, ", "), base)
+		return resolvePatchFile(ctx, exec, "", true, base, nil)
+	default:
+		fmt.Fprintf(os.Stderr, "Warning: patch file is stale (%s changed since it was generated); hunk numbers may not match the working tree\n", strings.Join(stale, ", "))
+		return patchFile, noop, nil
+	}
+}
+
+// remapSelection renumbers the hunk specifications and exclusions of selection, which were
+// taken from oldPatch, for newPatch regenerated from the working tree. File patterns and
+// negations are expanded against oldPatch first; hunks are then matched by patch ID, so
+// "file:N" keeps selecting the same change after edits elsewhere shift the numbering.
+func remapSelection(ctx context.Context, exec executor.CommandExecutor, opts stager.Options, selection stager.Selection, oldPatch, newPatch string) (stager.Selection, error) {
+	hunks, excludes, err := expandHunkSpecs(selection.HunkSpecs, selection.Excludes, len(selection.Matches) == 0, oldPatch)
+	if err != nil {
+		return selection, err
+	}
+
+	oldContent, err := os.ReadFile(oldPatch)
+	if err != nil {
+		return selection, stager.NewFileNotFoundError(oldPatch, err)
+	}
+	newContent, err := os.ReadFile(newPatch)
+	if err != nil {
+		return selection, stager.NewFileNotFoundError(newPatch, err)
+	}
+
+	s := stager.NewStagerWithOptions(exec, opts)
+	if selection.HunkSpecs, err = s.RemapHunkSpecs(ctx, hunks, string(oldContent), string(newContent)); err != nil {
+		return selection, err
+	}
+	if selection.Excludes, err = s.RemapHunkSpecs(ctx, excludes, string(oldContent), string(newContent)); err != nil {
+		return selection, err
+	}
+	return selection, nil
+}
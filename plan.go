@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// commitPlan describes a sequence of commits to create from a single patch file
+type commitPlan struct {
+	Patch   string          `yaml:"patch" json:"patch"`
+	Commits []plannedCommit `yaml:"commits" json:"commits"`
+}
+
+// plannedCommit is a single commit in a plan: a message and the hunks it contains
+type plannedCommit struct {
+	Message string   `yaml:"message" json:"message"`
+	Hunks   []string `yaml:"hunks" json:"hunks"`
+}
+
+// loadCommitPlan reads a plan file. Files with a .json extension are parsed as JSON,
+// everything else as YAML.
+func loadCommitPlan(path string) (*commitPlan, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan file: %w", err)
+	}
+
+	var plan commitPlan
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(content, &plan)
+	} else {
+		err = yaml.Unmarshal(content, &plan)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse plan file %s: %w", path, err)
+	}
+
+	return &plan, nil
+}
+
+// validate checks that the plan has a patch file and that every commit has a message and hunks
+func (p *commitPlan) validate() error {
+	if p.Patch == "" {
+		return fmt.Errorf("plan has no patch file (set 'patch' in the plan or use -patch)")
+	}
+	if len(p.Commits) == 0 {
+		return fmt.Errorf("plan has no commits")
+	}
+	for i, commit := range p.Commits {
+		if strings.TrimSpace(commit.Message) == "" {
+			return fmt.Errorf("commit %d has no message", i+1)
+		}
+		if len(commit.Hunks) == 0 {
+			return fmt.Errorf("commit %d (%s) has no hunks", i+1, commit.Message)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/syou6162/git-sequential-stage/internal/executor"
+	"github.com/syou6162/git-sequential-stage/internal/stager"
+)
+
+// runRemainingCommand handles the 'remaining' subcommand
+func runRemainingCommand(ctx context.Context, args []string) error {
+	// Create a new FlagSet for the remaining subcommand
+	remainingFlags := flag.NewFlagSet("remaining", flag.ExitOnError)
+	patchFile := remainingFlags.String("patch", "", "Path to the original patch file, or - to read it from stdin")
+	format := remainingFlags.String("format", formatText, "Output format: text or json")
+
+	remainingFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s remaining -patch=<patch_file|-> [--format=text|json]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nLists the hunks of the patch that are not committed yet, matched by patch ID against\n")
+		fmt.Fprintf(os.Stderr, "'git diff HEAD', with their current numbers.\n\n")
+		fmt.Fprintf(os.Stderr, "Output format: <filepath>:<current number> id:<patch ID> (patch <filepath>:<number>)\n")
+		fmt.Fprintf(os.Stderr, "<filepath>:<current number> refers to a fresh 'git diff HEAD'; id:<patch ID> works with either patch.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		remainingFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s remaining -patch=changes.patch\n", os.Args[0])
+	}
+
+	if err := remainingFlags.Parse(args); err != nil {
+		return err
+	}
+
+	if err := validateFormat(*format); err != nil {
+		remainingFlags.Usage()
+		fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+		return &usageShownError{message: err.Error()}
+	}
+	if *patchFile == "" {
+		remainingFlags.Usage()
+		fmt.Fprintf(os.Stderr, "\nError: patch file required\n")
+		return &usageShownError{message: "patch file required"}
+	}
+
+	resolvedPatch, cleanup, err := resolvePatchFile(ctx, executor.NewRealCommandExecutor(), *patchFile, false, "", os.Stdin)
+	if err != nil {
+		return err
+	}
+	remaining, total, err := runRemaining(ctx, resolvedPatch)
+	cleanup()
+	if err != nil {
+		return err
+	}
+
+	if *format == formatJSON {
+		return writeJSON(newRemainingOutput(remaining, total))
+	}
+
+	for _, hunk := range remaining {
+		fmt.Printf("%s:%d id:%s (patch %s:%d)\n", hunk.CurrentFilePath, hunk.CurrentIndexInFile, hunk.PatchID, hunk.FilePath, hunk.IndexInFile)
+	}
+	return nil
+}
+
+// runRemaining returns the hunks of the patch that are still in "git diff HEAD"
+// and the number of hunks in the patch
+func runRemaining(ctx context.Context, patchFile string) ([]stager.RemainingHunk, int, error) {
+	content, err := os.ReadFile(patchFile)
+	if err != nil {
+		return nil, 0, stager.NewFileNotFoundError(patchFile, err)
+	}
+
+	opts := stagerOptionsFromEnv()
+	s := stager.NewStagerWithOptions(executor.NewRealCommandExecutor(), opts)
+	remaining, err := s.RemainingHunks(ctx, string(content))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hunks, err := stager.ParsePatchFileWithGitDiff(string(content))
+	if err != nil {
+		return nil, 0, stager.NewParsingError("patch file", err)
+	}
+	return remaining, len(hunks), nil
+}
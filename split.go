@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/syou6162/git-sequential-stage/internal/executor"
+	"github.com/syou6162/git-sequential-stage/internal/stager"
+)
+
+// runSplitCommand handles the 'split' subcommand
+func runSplitCommand(ctx context.Context, args []string) error {
+	// Create a new FlagSet for the split subcommand
+	splitFlags := flag.NewFlagSet("split", flag.ExitOnError)
+	var hunks hunkList
+	patchFile := splitFlags.String("patch", "", "Path to the patch file, or - to read it from stdin")
+	splitFlags.Var(&hunks, "hunk", "File:hunk_numbers written to one patch file (e.g., path/to/file.py:1,3) or file:* for entire file")
+	outDir := splitFlags.String("out", "", "Directory to write the patch files to (created if missing)")
+	perFile := splitFlags.Bool("per-file", false, "Write every file of a -hunk group to a patch file of its own")
+
+	splitFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s split -patch=<patch_file|-> -hunk=<file:numbers|*> [-hunk=<file:numbers|*>...] -out=<dir> [-per-file]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nWrites one standalone patch file per -hunk group without touching the index or the working tree.\n")
+		fmt.Fprintf(os.Stderr, "Each patch file applies to the original tree on its own ('git apply --check').\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		splitFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  # Write hunks 1 and 3 of main.go to one patch and logger.go to another\n")
+		fmt.Fprintf(os.Stderr, "  %s split -patch=changes.patch -hunk=\"src/main.go:1,3\" -hunk=\"src/logger.go:*\" -out=patches/\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Write one patch per file below src/\n")
+		fmt.Fprintf(os.Stderr, "  %s split -patch=changes.patch -hunk=\"src/:*\" -out=patches/ -per-file\n", os.Args[0])
+	}
+
+	if err := splitFlags.Parse(args); err != nil {
+		return err
+	}
+
+	// Validate required flags
+	if *patchFile == "" {
+		splitFlags.Usage()
+		fmt.Fprintf(os.Stderr, "\nError: patch file required\n")
+		return &usageShownError{message: "patch file required"}
+	}
+	if len(hunks) == 0 {
+		splitFlags.Usage()
+		fmt.Fprintf(os.Stderr, "\nError: at least one -hunk flag is required\n")
+		return &usageShownError{message: "at least one -hunk flag is required"}
+	}
+	if *outDir == "" {
+		splitFlags.Usage()
+		fmt.Fprintf(os.Stderr, "\nError: output directory required\n")
+		return &usageShownError{message: "output directory required"}
+	}
+
+	resolvedPatch, cleanup, err := resolvePatchFile(ctx, executor.NewRealCommandExecutor(), *patchFile, false, "", os.Stdin)
+	if err != nil {
+		return err
+	}
+	written, err := runSplit(ctx, hunks, resolvedPatch, *outDir, *perFile)
+	cleanup()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range written {
+		fmt.Println(path)
+	}
+	return nil
+}
+
+// runSplit writes one patch file per -hunk group to outDir and returns the written paths.
+// Files are named after their position and first file, e.g. "001-src-main.go.patch".
+func runSplit(ctx context.Context, hunks []string, patchFile, outDir string, perFile bool) ([]string, error) {
+	content, err := os.ReadFile(patchFile)
+	if err != nil {
+		return nil, stager.NewFileNotFoundError(patchFile, err)
+	}
+
+	// Every -hunk flag is a group; glob and negated specifications expand within their group
+	groups := make([][]string, 0, len(hunks))
+	for _, hunk := range hunks {
+		group, _, err := expandHunkSpecs([]string{hunk}, nil, false, patchFile)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+
+	opts := stagerOptionsFromEnv()
+	s := stager.NewStagerWithOptions(executor.NewRealCommandExecutor(), opts)
+	parts, err := s.SplitPatch(ctx, string(content), groups, perFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return nil, stager.NewIOError("create output directory", err)
+	}
+
+	written := make([]string, 0, len(parts))
+	for i, part := range parts {
+		name := fmt.Sprintf("%03d-%s.patch", i+1, strings.ReplaceAll(part.Files[0], "/", "-"))
+		path := filepath.Join(outDir, name)
+		if err := os.WriteFile(path, part.Content, 0o644); err != nil {
+			return written, stager.NewIOError(fmt.Sprintf("write %s", path), err)
+		}
+		written = append(written, path)
+	}
+
+	return written, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/syou6162/git-sequential-stage/internal/executor"
+	"github.com/syou6162/git-sequential-stage/internal/stager"
+)
+
+// errNoSession is returned by status when no stage run recorded a session
+var errNoSession = errors.New("no staging session recorded (run 'stage --session' first)")
+
+// runStatusCommand handles the 'status' subcommand
+func runStatusCommand(ctx context.Context, args []string) error {
+	// Create a new FlagSet for the status subcommand
+	statusFlags := flag.NewFlagSet("status", flag.ExitOnError)
+	format := statusFlags.String("format", formatText, "Output format: text or json")
+
+	statusFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s status [--format=text|json]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nShows every hunk of the patch recorded by 'stage --session' and whether it is\n")
+		fmt.Fprintf(os.Stderr, "staged, partially staged, committed or still unstaged.\n\n")
+		fmt.Fprintf(os.Stderr, "Output format: <state> <filepath>:<number> id:<patch ID>\n")
+		fmt.Fprintf(os.Stderr, "Numbers refer to the recorded patch; patch IDs are accepted by 'stage -hunk=id:<patch ID>'.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		statusFlags.PrintDefaults()
+	}
+
+	if err := statusFlags.Parse(args); err != nil {
+		return err
+	}
+
+	if err := validateFormat(*format); err != nil {
+		statusFlags.Usage()
+		fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+		return &usageShownError{message: err.Error()}
+	}
+
+	session, statuses, err := runStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	if *format == formatJSON {
+		return writeJSON(newStatusOutput(session, statuses))
+	}
+
+	fmt.Printf("Session for patch %s: %d of %d hunks unstaged\n", shortHash(session.PatchHash), countUnstaged(statuses), len(statuses))
+	for _, status := range statuses {
+		fmt.Printf("%-9s %s:%d id:%s\n", status.State, status.FilePath, status.IndexInFile, status.PatchID)
+	}
+	return nil
+}
+
+// runStatus loads the recorded session and the current state of its hunks
+func runStatus(ctx context.Context) (*stager.Session, []stager.SessionHunkStatus, error) {
+	opts := stagerOptionsFromEnv()
+	s := stager.NewStagerWithOptions(executor.NewRealCommandExecutor(), opts)
+	session, statuses, err := s.SessionStatus(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if session == nil {
+		return nil, nil, errNoSession
+	}
+	return session, statuses, nil
+}
+
+// countUnstaged counts the hunks that are neither staged nor committed
+func countUnstaged(statuses []stager.SessionHunkStatus) int {
+	count := 0
+	for _, status := range statuses {
+		if status.State == stager.SessionHunkUnstaged {
+			count++
+		}
+	}
+	return count
+}
+
+// shortHash abbreviates a patch hash for display
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
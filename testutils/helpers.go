@@ -2,6 +2,7 @@ package testutils
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -96,6 +97,46 @@ func NewTestRepo(t *testing.T, prefix string) *TestRepo {
 	return testRepo
 }
 
+// TextFile is the content of a text file, one newline-terminated line per element.
+// Tests change single lines and write the result with String.
+type TextFile []string
+
+// NewTextFile returns a file of n lines, "linea" to "linez" repeated, so that edits a few
+// lines apart end up in separate hunks with distinct context
+func NewTextFile(n int) TextFile {
+	lines := make(TextFile, n)
+	for i := range lines {
+		lines[i] = "line" + string(rune('a'+i%26)) + "\n"
+	}
+	return lines
+}
+
+// String returns the file content
+func (f TextFile) String() string {
+	return strings.Join(f, "")
+}
+
+// NewMultiHunkRepo creates a test repository whose initial commit has file.txt with n lines
+// (see NewTextFile) and the given other files, then replaces the lines of file.txt in edits
+// in the working tree. It returns the edited lines for later changes to file.txt.
+func NewMultiHunkRepo(t *testing.T, prefix string, n int, edits map[int]string, files map[string]string) (*TestRepo, TextFile) {
+	t.Helper()
+
+	testRepo := NewTestRepo(t, prefix)
+	lines := NewTextFile(n)
+	testRepo.CreateFile("file.txt", lines.String())
+	for name, content := range files {
+		testRepo.CreateFile(name, content)
+	}
+	testRepo.CommitChanges("Initial commit")
+
+	for i, line := range edits {
+		lines[i] = line
+	}
+	testRepo.ModifyFile("file.txt", lines.String())
+	return testRepo, lines
+}
+
 // Cleanup removes the test repository
 func (tr *TestRepo) Cleanup() {
 	if tr.cleanup != nil {
@@ -490,3 +531,34 @@ func GenerateFunction(index int, version string) string {
 
 `, "{INDEX}", string(rune('0'+index))), "{VERSION}", version)
 }
+
+// CaptureStdout runs fn while redirecting os.Stdout and returns everything written to it
+func CaptureStdout(t *testing.T, fn func() error) (string, error) {
+	t.Helper()
+
+	oldStdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	os.Stdout = w
+
+	outCh := make(chan string, 1)
+	go func() {
+		var buf strings.Builder
+		if _, err := io.Copy(&buf, r); err != nil {
+			t.Errorf("Failed to copy output: %v", err)
+		}
+		outCh <- buf.String()
+	}()
+
+	runErr := fn()
+
+	// Close write end and restore stdout
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close pipe: %v", err)
+	}
+	os.Stdout = oldStdout
+
+	return <-outCh, runErr
+}